@@ -0,0 +1,104 @@
+package analytics
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go-elastic-api/auth"
+	"go-elastic-api/es"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+)
+
+// Endpoints of the search events
+const (
+	EndpointFullTextSearch = "full_text_search"
+	EndpointSemantic       = "semantic"
+	EndpointHybrid         = "hybrid"
+	EndpointFilter         = "filter"
+)
+
+// recordedClient records the successful searches made through it with a Recorder.
+// The methods it does not override are passed to the embedded Client.
+type recordedClient struct {
+	es.Client
+
+	recorder *Recorder
+	now      func() time.Time
+}
+
+// Recorded wraps a Client so that every successful FullTextSearch, SemanticSearch, HybridSearch
+// and FilterBooks call is recorded as an es.SearchEvent, with the subject of the authenticated principal as user.
+// Wrap the cache with it so that searches answered from the cache are recorded as well.
+func Recorded(next es.Client, recorder *Recorder) es.Client {
+	return &recordedClient{Client: next, recorder: recorder, now: time.Now}
+}
+
+func (c *recordedClient) FullTextSearch(ctx context.Context, query string, page es.Page) (*search.Response, error) {
+	start := c.now()
+	res, err := c.Client.FullTextSearch(ctx, query, page)
+	if err == nil {
+		event := c.searchEvent(ctx, EndpointFullTextSearch, start, page, res)
+		event.Query = NormalizeQuery(query)
+		c.recorder.Record(event)
+	}
+	return res, err
+}
+
+func (c *recordedClient) SemanticSearch(ctx context.Context, query string, page es.Page) (*search.Response, error) {
+	start := c.now()
+	res, err := c.Client.SemanticSearch(ctx, query, page)
+	if err == nil {
+		event := c.searchEvent(ctx, EndpointSemantic, start, page, res)
+		event.Query = NormalizeQuery(query)
+		c.recorder.Record(event)
+	}
+	return res, err
+}
+
+func (c *recordedClient) HybridSearch(ctx context.Context, query string, page es.Page) (*search.Response, error) {
+	start := c.now()
+	res, err := c.Client.HybridSearch(ctx, query, page)
+	if err == nil {
+		event := c.searchEvent(ctx, EndpointHybrid, start, page, res)
+		event.Query = NormalizeQuery(query)
+		c.recorder.Record(event)
+	}
+	return res, err
+}
+
+func (c *recordedClient) FilterBooks(ctx context.Context, filter map[string]any, page es.Page) (*search.Response, error) {
+	start := c.now()
+	res, err := c.Client.FilterBooks(ctx, filter, page)
+	if err == nil {
+		event := c.searchEvent(ctx, EndpointFilter, start, page, res)
+		event.Filters = es.NormalizeFilter(filter)
+		c.recorder.Record(event)
+	}
+	return res, err
+}
+
+func (c *recordedClient) searchEvent(ctx context.Context, endpoint string, start time.Time, page es.Page, res *search.Response) es.SearchEvent {
+	event := es.SearchEvent{
+		Timestamp: start.UTC(),
+		Type:      es.EventSearch,
+		Endpoint:  endpoint,
+		From:      page.From,
+		Hits:      int64(len(res.Hits.Hits)),
+		LatencyMS: float64(c.now().Sub(start).Microseconds()) / 1000,
+	}
+	if res.Hits.Total != nil {
+		event.Hits = res.Hits.Total.Value
+	}
+	if principal, ok := auth.FromContext(ctx); ok {
+		event.User = principal.Subject
+	}
+	return event
+}
+
+// NormalizeQuery lowercases a query and collapses its spaces, so that the same query typed
+// differently is counted once.
+func NormalizeQuery(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
@@ -0,0 +1,68 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-elastic-api/auth"
+	"go-elastic-api/es"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+// searchClient answers searches with res, or fails with err.
+// Calling any other method panics on the nil embedded interface.
+type searchClient struct {
+	es.Client
+	res *search.Response
+	err error
+}
+
+func (c *searchClient) FullTextSearch(ctx context.Context, query string, page es.Page) (*search.Response, error) {
+	return c.res, c.err
+}
+
+func (c *searchClient) FilterBooks(ctx context.Context, filter map[string]any, page es.Page) (*search.Response, error) {
+	return c.res, c.err
+}
+
+func TestRecordedClient(t *testing.T) {
+	writer := &fakeWriter{}
+	recorder := NewRecorder(writer, "book-events", 10, time.Hour, prometheus.NewRegistry())
+	next := &searchClient{res: &search.Response{Hits: types.HitsMetadata{Total: &types.TotalHits{Value: 0}}}}
+	client := Recorded(next, recorder).(*recordedClient)
+	start := time.Date(2026, time.October, 19, 8, 0, 0, 0, time.UTC)
+	calls := 0
+	client.now = func() time.Time {
+		calls++
+		return start.Add(time.Duration(calls-1) * 1500 * time.Microsecond)
+	}
+
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "reader-1", Role: auth.RoleReader})
+	_, err := client.FullTextSearch(ctx, "  Snow   CRASH ", es.Page{From: 10, Size: 10})
+	require.NoError(t, err)
+
+	next.res = &search.Response{Hits: types.HitsMetadata{Total: &types.TotalHits{Value: 4}}}
+	_, err = client.FilterBooks(context.Background(), map[string]any{"publisher": "Ace ", "unknown": 1}, es.Page{Size: 10})
+	require.NoError(t, err)
+
+	// Failed searches are not recorded
+	next.err = errors.New("timeout")
+	_, err = client.FullTextSearch(ctx, "dune", es.Page{})
+	require.Error(t, err)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	recorder.Run(cancelled)
+	require.Equal(t, []any{
+		es.SearchEvent{Timestamp: start, Type: es.EventSearch, Endpoint: EndpointFullTextSearch, Query: "snow crash",
+			From: 10, Hits: 0, LatencyMS: 1.5, User: "reader-1"},
+		es.SearchEvent{Timestamp: start.Add(3 * time.Millisecond), Type: es.EventSearch, Endpoint: EndpointFilter,
+			Filters: map[string]any{"publisher": "Ace"}, Hits: 4, LatencyMS: 1.5},
+	}, writer.events())
+}
@@ -0,0 +1,110 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/bulk"
+)
+
+// PopularityStore counts the clicks of books and writes their popularity, es.Client is one.
+type PopularityStore interface {
+	BookClicks(ctx context.Context, alias string, since time.Time) (map[string]int64, error)
+	UpdatePopularity(ctx context.Context, popularity map[string]float64) (*bulk.Response, error)
+}
+
+// PopularityUpdate sums up one update of the popularity of books.
+type PopularityUpdate struct {
+	// Books is the number of books given a popularity
+	Books int
+	// Missing is the number of clicked books that no longer exist
+	Missing int
+	// Failed is the number of books whose popularity could not be written
+	Failed int
+}
+
+// PopularityUpdater writes the number of clicks of every book in a time window into its popularity,
+// which full text search blends into its ranking.
+type PopularityUpdater struct {
+	store    PopularityStore
+	alias    string
+	window   time.Duration
+	interval time.Duration
+	logger   *slog.Logger
+	now      func() time.Time
+}
+
+// NewPopularityUpdater creates an updater counting the clicks recorded to alias in the last window,
+// every interval once Run is called.
+func NewPopularityUpdater(store PopularityStore, alias string, window, interval time.Duration) *PopularityUpdater {
+	return &PopularityUpdater{
+		store:    store,
+		alias:    alias,
+		window:   window,
+		interval: interval,
+		logger:   slog.Default(),
+		now:      time.Now,
+	}
+}
+
+// Update counts the clicks of the window ending now and writes them as the popularity of the books.
+// Books without clicks in the window lose their popularity.
+func (u *PopularityUpdater) Update(ctx context.Context) (PopularityUpdate, error) {
+	clicks, err := u.store.BookClicks(ctx, u.alias, u.now().Add(-u.window))
+	if err != nil {
+		return PopularityUpdate{}, fmt.Errorf("cannot count clicks: %w", err)
+	}
+
+	popularity := make(map[string]float64, len(clicks))
+	for id, n := range clicks {
+		popularity[id] = float64(n)
+	}
+	res, err := u.store.UpdatePopularity(ctx, popularity)
+	if err != nil {
+		return PopularityUpdate{}, fmt.Errorf("cannot update popularity: %w", err)
+	}
+
+	update := PopularityUpdate{Books: len(popularity)}
+	for _, item := range res.Items {
+		for _, result := range item {
+			switch {
+			case result.Status == http.StatusNotFound:
+				update.Missing++
+			case result.Error != nil:
+				update.Failed++
+				if update.Failed == 1 {
+					u.logger.WarnContext(ctx, "cannot update popularity of book", slog.String("book_id", str(result.Id_)),
+						slog.String("error", str(result.Error.Reason)))
+				}
+			}
+		}
+	}
+	update.Books -= update.Missing + update.Failed
+	return update, nil
+}
+
+// Run updates the popularity right away, then every interval until ctx is done.
+// Failed updates are logged and tried again at the next interval.
+func (u *PopularityUpdater) Run(ctx context.Context) {
+	ticker := time.NewTicker(u.interval)
+	defer ticker.Stop()
+
+	for {
+		update, err := u.Update(ctx)
+		if err != nil {
+			u.logger.WarnContext(ctx, "cannot update popularity of books", slog.String("error", err.Error()))
+		} else {
+			u.logger.InfoContext(ctx, "updated popularity of books", slog.Int("books", update.Books),
+				slog.Int("missing", update.Missing), slog.Int("failed", update.Failed))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/bulk"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/operationtype"
+	"github.com/stretchr/testify/require"
+)
+
+// popularityStore counts clicks from clicks and keeps the popularity it is given.
+// Books in missing do not exist.
+type popularityStore struct {
+	clicks     map[string]int64
+	clicksErr  error
+	since      time.Time
+	missing    map[string]bool
+	popularity map[string]float64
+}
+
+func (s *popularityStore) BookClicks(ctx context.Context, alias string, since time.Time) (map[string]int64, error) {
+	s.since = since
+	return s.clicks, s.clicksErr
+}
+
+func (s *popularityStore) UpdatePopularity(ctx context.Context, popularity map[string]float64) (*bulk.Response, error) {
+	s.popularity = popularity
+	res := &bulk.Response{}
+	for id := range popularity {
+		item := types.ResponseItem{Id_: &id, Status: 200}
+		if s.missing[id] {
+			reason := "document missing"
+			item = types.ResponseItem{Id_: &id, Status: 404, Error: &types.ErrorCause{Reason: &reason}}
+		}
+		res.Items = append(res.Items, map[operationtype.OperationType]types.ResponseItem{operationtype.Update: item})
+	}
+	return res, nil
+}
+
+func TestPopularityUpdater(t *testing.T) {
+	store := &popularityStore{clicks: map[string]int64{"1": 12, "2": 3, "3": 1}, missing: map[string]bool{"3": true}}
+	updater := NewPopularityUpdater(store, "book-events", 24*time.Hour, time.Hour)
+	now := time.Date(2026, time.October, 19, 8, 0, 0, 0, time.UTC)
+	updater.now = func() time.Time { return now }
+
+	update, err := updater.Update(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, PopularityUpdate{Books: 2, Missing: 1}, update)
+	require.Equal(t, now.Add(-24*time.Hour), store.since)
+	require.Equal(t, map[string]float64{"1": 12, "2": 3, "3": 1}, store.popularity)
+
+	// Popularity is left alone when clicks cannot be counted
+	store.popularity = nil
+	store.clicksErr = errors.New("no such index [book-events]")
+	_, err = updater.Update(context.Background())
+	require.ErrorContains(t, err, "cannot count clicks")
+	require.Nil(t, store.popularity)
+}
@@ -0,0 +1,135 @@
+// Package analytics records searches and clicks to the events indices in the background,
+// so that the product can learn what users look for and what they do not find.
+package analytics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/bulk"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxBatchSize is the most events written in one bulk request.
+const maxBatchSize = 500
+
+// closeTimeout bounds the writing of the events still queued when the recorder stops.
+const closeTimeout = 10 * time.Second
+
+// EventWriter writes events to an alias, es.Client is one.
+type EventWriter interface {
+	IndexEvents(ctx context.Context, alias string, events []any) (*bulk.Response, error)
+}
+
+// Recorder queues events in a bounded buffer and writes them in batches from a single goroutine.
+// Recording never blocks: when the buffer is full, because Elasticsearch is slow or down,
+// events are dropped and counted rather than slowing down the request that made them.
+type Recorder struct {
+	writer        EventWriter
+	alias         string
+	events        chan any
+	flushInterval time.Duration
+	logger        *slog.Logger
+	results       *prometheus.CounterVec
+}
+
+// NewRecorder creates a recorder writing to alias, buffering up to bufferSize events and writing them
+// at least every flushInterval. Events are counted by result, written, failed or dropped,
+// in an analytics_events_total metric registered with reg. Events are only written once Run is called.
+func NewRecorder(writer EventWriter, alias string, bufferSize int, flushInterval time.Duration, reg prometheus.Registerer) *Recorder {
+	r := &Recorder{
+		writer:        writer,
+		alias:         alias,
+		events:        make(chan any, bufferSize),
+		flushInterval: flushInterval,
+		logger:        slog.Default(),
+		results: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "analytics_events_total",
+			Help: "Number of analytics events by result: written, failed or dropped because the buffer was full.",
+		}, []string{"result"}),
+	}
+	reg.MustRegister(r.results)
+	return r
+}
+
+// Record queues event to be written, unless the buffer is full. It is safe for concurrent use.
+func (r *Recorder) Record(event any) {
+	select {
+	case r.events <- event:
+	default:
+		r.results.WithLabelValues("dropped").Inc()
+	}
+}
+
+// Run writes the queued events until ctx is done, then writes the events still queued and returns.
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]any, 0, maxBatchSize)
+	for {
+		select {
+		case event := <-r.events:
+			batch = append(batch, event)
+			if len(batch) == maxBatchSize {
+				batch = r.flush(ctx, batch)
+			}
+		case <-ticker.C:
+			batch = r.flush(ctx, batch)
+		case <-ctx.Done():
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), closeTimeout)
+			defer cancel()
+			for {
+				select {
+				case event := <-r.events:
+					batch = append(batch, event)
+					if len(batch) == maxBatchSize {
+						batch = r.flush(ctx, batch)
+					}
+				default:
+					r.flush(ctx, batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush writes batch and returns it emptied for reuse. Failed events are logged and not retried,
+// analytics are not worth holding searches back for.
+func (r *Recorder) flush(ctx context.Context, batch []any) []any {
+	if len(batch) == 0 {
+		return batch
+	}
+	res, err := r.writer.IndexEvents(ctx, r.alias, batch)
+	if err != nil {
+		r.results.WithLabelValues("failed").Add(float64(len(batch)))
+		r.logger.WarnContext(ctx, "cannot write analytics events", slog.String("alias", r.alias),
+			slog.Int("events", len(batch)), slog.String("error", err.Error()))
+		return batch[:0]
+	}
+
+	failed := 0
+	for _, item := range res.Items {
+		for _, result := range item {
+			if result.Error != nil {
+				if failed == 0 {
+					r.logger.WarnContext(ctx, "cannot write analytics event", slog.String("alias", r.alias),
+						slog.String("error", str(result.Error.Reason)))
+				}
+				failed++
+			}
+		}
+	}
+	r.results.WithLabelValues("failed").Add(float64(failed))
+	r.results.WithLabelValues("written").Add(float64(len(batch) - failed))
+	return batch[:0]
+}
+
+func str(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,114 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/bulk"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/operationtype"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWriter keeps the batches it is given. Events equal to failEvent are rejected
+// as bulk items, and every batch fails with err when set.
+type fakeWriter struct {
+	mu        sync.Mutex
+	batches   [][]any
+	failEvent any
+	err       error
+}
+
+func (w *fakeWriter) IndexEvents(ctx context.Context, alias string, events []any) (*bulk.Response, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.batches = append(w.batches, slices.Clone(events))
+	if w.err != nil {
+		return nil, w.err
+	}
+
+	res := &bulk.Response{}
+	for _, event := range events {
+		item := types.ResponseItem{Status: 201}
+		if w.failEvent != nil && event == w.failEvent {
+			reason := "mapper_parsing_exception"
+			item = types.ResponseItem{Status: 400, Error: &types.ErrorCause{Reason: &reason}}
+		}
+		res.Items = append(res.Items, map[operationtype.OperationType]types.ResponseItem{operationtype.Create: item})
+	}
+	return res, nil
+}
+
+func (w *fakeWriter) events() []any {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Concat(w.batches...)
+}
+
+func TestRecorderDropsWhenFull(t *testing.T) {
+	writer := &fakeWriter{}
+	recorder := NewRecorder(writer, "book-events", 2, time.Hour, prometheus.NewRegistry())
+
+	// Nothing is written before Run, the third event does not fit the buffer
+	for i := range 3 {
+		recorder.Record(i)
+	}
+	require.Equal(t, 1.0, testutil.ToFloat64(recorder.results.WithLabelValues("dropped")))
+
+	// Stopping writes what was queued
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	recorder.Run(ctx)
+	require.Equal(t, []any{0, 1}, writer.events())
+	require.Equal(t, 2.0, testutil.ToFloat64(recorder.results.WithLabelValues("written")))
+}
+
+func TestRecorderRun(t *testing.T) {
+	writer := &fakeWriter{failEvent: "bad"}
+	recorder := NewRecorder(writer, "book-events", 100, 10*time.Millisecond, prometheus.NewRegistry())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		recorder.Run(ctx)
+		close(done)
+	}()
+
+	recorder.Record("a")
+	recorder.Record("bad")
+	require.Eventually(t, func() bool { return len(writer.events()) == 2 }, time.Second, 5*time.Millisecond)
+	require.Equal(t, 1.0, testutil.ToFloat64(recorder.results.WithLabelValues("written")))
+	require.Equal(t, 1.0, testutil.ToFloat64(recorder.results.WithLabelValues("failed")))
+
+	cancel()
+	<-done
+}
+
+func TestRecorderBatches(t *testing.T) {
+	writer := &fakeWriter{}
+	recorder := NewRecorder(writer, "book-events", 2*maxBatchSize, time.Hour, prometheus.NewRegistry())
+	for i := range maxBatchSize + 1 {
+		recorder.Record(i)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	recorder.Run(ctx)
+	require.Len(t, writer.batches, 2)
+	require.Len(t, writer.batches[0], maxBatchSize)
+	require.Len(t, writer.batches[1], 1)
+
+	// A failed request counts all its events
+	writer = &fakeWriter{err: errors.New("no such index [book-events] and [require_alias] request flag is [true]")}
+	recorder = NewRecorder(writer, "book-events", 10, time.Hour, prometheus.NewRegistry())
+	recorder.Record("a")
+	recorder.Record("b")
+	recorder.Run(ctx)
+	require.Equal(t, 2.0, testutil.ToFloat64(recorder.results.WithLabelValues("failed")))
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go-elastic-api/es"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createIndex creates an index with the books mappings.
+// Example request: PUT /api/v1/admin/indices/books-v2
+// Example response: {"acknowledged": true, "index": "books-v2", "shards_acknowledged": true}
+func (server *Server) createIndex(c *gin.Context) {
+	res, err := server.esStore.CreateIndex(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		respondESError(c, fmt.Errorf("error creating index: %w", err))
+		return
+	}
+	c.JSON(http.StatusCreated, res)
+}
+
+// deleteIndex deletes an index.
+// Example request: DELETE /api/v1/admin/indices/books-v1
+// Example response: {"acknowledged": true}
+func (server *Server) deleteIndex(c *gin.Context) {
+	res, err := server.esStore.DeleteIndex(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		respondESError(c, fmt.Errorf("error deleting index: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+type reindexRequest struct {
+	Source string `json:"source" binding:"required"`
+	Dest   string `json:"dest" binding:"required"`
+}
+
+// reindex starts copying the documents of one index into another.
+// It returns 202 Accepted with the ID of the Elasticsearch task doing the copy.
+// Example request: POST /api/v1/admin/reindex {"source": "books-v1", "dest": "books-v2"}
+// Example response: {"task": "oTUltX4IQMOUUVeiohTt8A:12345"}
+func (server *Server) reindex(c *gin.Context) {
+	var req reindexRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("invalid reindex request: %s", err)))
+		return
+	}
+
+	res, err := server.esStore.Reindex(c.Request.Context(), req.Source, req.Dest)
+	if err != nil {
+		respondESError(c, fmt.Errorf("error starting reindex: %w", err))
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"task": res.Task})
+}
+
+// deleteByQuery deletes every book matching a filter with the format of /filter/books.
+// An empty filter is rejected.
+// Example request: POST /api/v1/admin/delete_by_query {"publisher": "Some Publisher"}
+// Example response: {"deleted": 12, "total": 12, ...}
+func (server *Server) deleteByQuery(c *gin.Context) {
+	filter := make(map[string]any)
+	if err := c.ShouldBindJSON(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("invalid filter format: %s", err)))
+		return
+	}
+	if len(filter) == 0 {
+		c.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("filter must not be empty")))
+		return
+	}
+
+	res, err := server.esStore.DeleteBooksByQuery(c.Request.Context(), filter)
+	if err != nil {
+		respondESError(c, fmt.Errorf("error deleting books by query: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// lifecycle reports the lifecycle phase and health of every index behind the events alias.
+// Example request: GET /api/v1/admin/lifecycle
+// Example response: {"alias": "book-events", "policy": "book-events-policy", "indices": [{"index": "book-events-000001", "phase": "hot", ...}]}
+func (server *Server) lifecycle(c *gin.Context) {
+	alias := server.config.EventsAlias
+	indices, err := server.esStore.ManagedIndices(c.Request.Context(), alias)
+	if err != nil {
+		respondESError(c, fmt.Errorf("error getting lifecycle of %s: %w", alias, err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"alias": alias, "policy": es.LifecyclePolicyName(alias), "indices": indices})
+}
+
+// synonyms lists the rules of the synonyms set used by the search analyzer of the books indices.
+// Example request: GET /api/v1/admin/synonyms
+// Example response: {"set": "books-synonyms", "rules": [{"id": "Xk3...", "synonyms": "sci-fi, science fiction"}]}
+func (server *Server) synonyms(c *gin.Context) {
+	rules, err := server.esStore.Synonyms(c.Request.Context(), es.BooksSynonymsSet)
+	if err != nil {
+		respondESError(c, fmt.Errorf("error getting synonyms: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"set": es.BooksSynonymsSet, "rules": rules})
+}
+
+type synonymsRequest struct {
+	Rules []string `json:"rules" binding:"required"`
+}
+
+// putSynonyms replaces the rules of the synonyms set. Elasticsearch reloads the search analyzers
+// using it, so searches match with the new rules right away, without a reindex.
+// It returns 200 OK with the indices whose analyzers were reloaded, and 400 Bad Request for an invalid rule.
+// Example request: PUT /api/v1/admin/synonyms {"rules": ["sci-fi, science fiction", "tolkien, j.r.r. tolkien"]}
+// Example response: {"set": "books-synonyms", "result": "updated", "reloaded": ["books-v2"]}
+func (server *Server) putSynonyms(c *gin.Context) {
+	var req synonymsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("invalid synonyms request: %s", err)))
+		return
+	}
+	for i, rule := range req.Rules {
+		if strings.TrimSpace(rule) == "" || strings.ContainsAny(rule, "\r\n") {
+			c.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("rule %d must be a single non-empty line, got %q", i, rule)))
+			return
+		}
+	}
+
+	res, err := server.esStore.PutSynonyms(c.Request.Context(), es.BooksSynonymsSet, req.Rules)
+	if err != nil {
+		respondESError(c, fmt.Errorf("error updating synonyms: %w", err))
+		return
+	}
+	reloaded := make([]string, 0, len(res.ReloadAnalyzersDetails.ReloadDetails))
+	for _, details := range res.ReloadAnalyzersDetails.ReloadDetails {
+		reloaded = append(reloaded, details.Index)
+	}
+	c.JSON(http.StatusOK, gin.H{"set": es.BooksSynonymsSet, "result": res.Result, "reloaded": reloaded})
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-elastic-api/es"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/synonyms/putsynonym"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/result"
+	"github.com/stretchr/testify/require"
+)
+
+// lifecycleStore serves ManagedIndices from indices, or fails with err.
+type lifecycleStore struct {
+	fakeStore
+	indices   []es.ManagedIndex
+	err       error
+	lastAlias string
+}
+
+func (s *lifecycleStore) ManagedIndices(ctx context.Context, alias string) ([]es.ManagedIndex, error) {
+	s.lastAlias = alias
+	return s.indices, s.err
+}
+
+func TestLifecycle(t *testing.T) {
+	store := &lifecycleStore{indices: []es.ManagedIndex{
+		{Index: "book-events-000001", Managed: true, Policy: "book-events-policy", Phase: "warm", Action: "shrink", Step: "ERROR",
+			Error: "index has 1 shard", Health: "green"},
+		{Index: "book-events-000002", Managed: true, Policy: "book-events-policy", Phase: "hot", Action: "rollover", Step: "check-rollover-ready",
+			Age: "5.2h", WriteIndex: true, Health: "yellow", DocsCount: "1200", StoreSize: "1.1mb"},
+	}}
+	server := newTestServer(t, store)
+
+	recorder := doRequest(server, http.MethodGet, "/api/v1/admin/lifecycle")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "book-events", store.lastAlias)
+	require.JSONEq(t, `{"alias": "book-events", "policy": "book-events-policy", "indices": [
+		{"index": "book-events-000001", "managed": true, "policy": "book-events-policy", "phase": "warm", "action": "shrink", "step": "ERROR",
+			"error": "index has 1 shard", "write_index": false, "health": "green"},
+		{"index": "book-events-000002", "managed": true, "policy": "book-events-policy", "phase": "hot", "action": "rollover", "step": "check-rollover-ready",
+			"age": "5.2h", "write_index": true, "health": "yellow", "docs_count": "1200", "store_size": "1.1mb"}
+	]}`, recorder.Body.String())
+
+	// The alias is not bootstrapped yet
+	server = newTestServer(t, &lifecycleStore{err: &types.ElasticsearchError{Status: http.StatusNotFound}})
+	recorder = doRequest(server, http.MethodGet, "/api/v1/admin/lifecycle")
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+// synonymsStore keeps the synonyms set it is given.
+type synonymsStore struct {
+	fakeStore
+	rules []string
+}
+
+func (s *synonymsStore) Synonyms(ctx context.Context, set string) ([]types.SynonymRuleRead, error) {
+	rules := make([]types.SynonymRuleRead, 0, len(s.rules))
+	for i, rule := range s.rules {
+		rules = append(rules, types.SynonymRuleRead{Id: fmt.Sprint(i), Synonyms: rule})
+	}
+	return rules, nil
+}
+
+func (s *synonymsStore) PutSynonyms(ctx context.Context, set string, rules []string) (*putsynonym.Response, error) {
+	s.rules = rules
+	return &putsynonym.Response{
+		Result:                 result.Updated,
+		ReloadAnalyzersDetails: types.ReloadResult{ReloadDetails: []types.ReloadDetails{{Index: "books-v2"}}},
+	}, nil
+}
+
+func TestSynonyms(t *testing.T) {
+	store := &synonymsStore{}
+	server := newTestServer(t, store)
+	putSynonyms := func(body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/admin/synonyms", strings.NewReader(body))
+		server.router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := putSynonyms(`{"rules": ["sci-fi, science fiction", "tolkien, j.r.r. tolkien"]}`)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"set": "books-synonyms", "result": "updated", "reloaded": ["books-v2"]}`, recorder.Body.String())
+
+	recorder = doRequest(server, http.MethodGet, "/api/v1/admin/synonyms")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"set": "books-synonyms", "rules": [
+		{"id": "0", "synonyms": "sci-fi, science fiction"},
+		{"id": "1", "synonyms": "tolkien, j.r.r. tolkien"}
+	]}`, recorder.Body.String())
+
+	// Rules are one line each
+	require.Equal(t, http.StatusBadRequest, putSynonyms(`{"rules": ["sci-fi, science fiction\ntolkien, j.r.r. tolkien"]}`).Code)
+	require.Equal(t, http.StatusBadRequest, putSynonyms(`{"rules": [" "]}`).Code)
+	require.Equal(t, http.StatusBadRequest, putSynonyms(`{}`).Code)
+	require.Len(t, store.rules, 2)
+}
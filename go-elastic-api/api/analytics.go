@@ -0,0 +1,136 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultAnalyticsWindow is how far back reports look without a window parameter
+	defaultAnalyticsWindow = 24 * time.Hour
+	// defaultAnalyticsInterval is the length of the click-through rate buckets without an interval parameter
+	defaultAnalyticsInterval = time.Hour
+	// maxAnalyticsBuckets bounds the buckets of a click-through rate report
+	maxAnalyticsBuckets = 1000
+	// maxAnalyticsSize bounds the queries of a report
+	maxAnalyticsSize = 100
+)
+
+// topQueries reports the queries searched the most in a time window, with their zero result searches,
+// clicks and click-through rate. The window is a duration such as 24h or 168h, 24h by default.
+// Example request: GET /api/v1/admin/analytics/top_queries?window=168h&size=10
+// Example response: {"window": "168h0m0s", "since": "2026-10-12T08:00:00Z", "queries": [{"query": "dune", "searches": 42, ...}]}
+func (server *Server) topQueries(c *gin.Context) {
+	window, size, err := parseAnalyticsWindow(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	since := windowStart(window)
+
+	queries, err := server.esStore.TopQueries(c.Request.Context(), server.config.EventsAlias, since, size)
+	if err != nil {
+		respondESError(c, fmt.Errorf("error getting top queries: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"window": window.String(), "since": since, "queries": queries})
+}
+
+// zeroResultQueries reports the queries most often searched without results in a time window.
+// Example request: GET /api/v1/admin/analytics/zero_results?window=24h
+// Example response: {"window": "24h0m0s", "since": "2026-10-18T08:00:00Z", "queries": [{"query": "snow crush", "searches": 7, "zero_results": 7, ...}]}
+func (server *Server) zeroResultQueries(c *gin.Context) {
+	window, size, err := parseAnalyticsWindow(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	since := windowStart(window)
+
+	queries, err := server.esStore.ZeroResultQueries(c.Request.Context(), server.config.EventsAlias, since, size)
+	if err != nil {
+		respondESError(c, fmt.Errorf("error getting zero result queries: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"window": window.String(), "since": since, "queries": queries})
+}
+
+// clickThroughRate reports the searches, clicks and click-through rate of a time window, in total
+// and by interval, 1h by default.
+// Example request: GET /api/v1/admin/analytics/ctr?window=168h&interval=24h
+// Example response: {"window": "168h0m0s", "interval": "24h0m0s", "searches": 1200, "clicks": 300, "ctr": 0.25, "buckets": [...]}
+func (server *Server) clickThroughRate(c *gin.Context) {
+	window, _, err := parseAnalyticsWindow(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	interval := defaultAnalyticsInterval
+	if v := c.Query("interval"); v != "" {
+		interval, err = time.ParseDuration(v)
+		if err != nil || interval < time.Minute {
+			c.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("interval must be a duration of at least 1m, got %q", v)))
+			return
+		}
+	}
+	if buckets := window / interval; buckets > maxAnalyticsBuckets {
+		c.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("window / interval must not exceed %d buckets, got %d", maxAnalyticsBuckets, buckets)))
+		return
+	}
+
+	since := windowStart(window)
+	buckets, err := server.esStore.ClickThroughRate(c.Request.Context(), server.config.EventsAlias, since, interval)
+	if err != nil {
+		respondESError(c, fmt.Errorf("error getting click-through rate: %w", err))
+		return
+	}
+	var searches, clicks int64
+	for _, b := range buckets {
+		searches += b.Searches
+		clicks += b.Clicks
+	}
+	ctr := 0.0
+	if searches > 0 {
+		ctr = float64(clicks) / float64(searches)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"window":   window.String(),
+		"interval": interval.String(),
+		"since":    since,
+		"searches": searches,
+		"clicks":   clicks,
+		"ctr":      ctr,
+		"buckets":  buckets,
+	})
+}
+
+// parseAnalyticsWindow reads the "window" and "size" query parameters of the analytics reports.
+func parseAnalyticsWindow(c *gin.Context) (time.Duration, int, error) {
+	window := defaultAnalyticsWindow
+	if v := c.Query("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return 0, 0, fmt.Errorf("window must be a positive duration such as 24h, got %q", v)
+		}
+		window = d
+	}
+
+	size := 10
+	if v := c.Query("size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAnalyticsSize {
+			return 0, 0, fmt.Errorf("size must be between 1 and %d, got %q", maxAnalyticsSize, v)
+		}
+		size = n
+	}
+	return window, size, nil
+}
+
+// windowStart returns when a window ending now starts, to the second.
+func windowStart(window time.Duration) time.Time {
+	return time.Now().Add(-window).UTC().Truncate(time.Second)
+}
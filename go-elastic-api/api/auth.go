@@ -0,0 +1,72 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"go-elastic-api/auth"
+	"go-elastic-api/es"
+
+	"github.com/gin-gonic/gin"
+)
+
+// principalContextKey is the gin context key of the authenticated auth.Principal.
+const principalContextKey = "principal"
+
+// authenticate rejects requests without valid credentials with 401 Unauthorized.
+// The principal is stored in the gin context and in the request context, so handlers,
+// the access log and the es.Client calls of the request can tell who made it.
+// Principals bound to a publisher get the matching es.WithPublisherScope.
+func authenticate(authenticator *auth.Authenticator, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := authenticator.Authenticate(c.Request)
+		if err != nil {
+			logger.WarnContext(c.Request.Context(), "authentication failed",
+				slog.String("path", c.Request.URL.Path),
+				slog.String("client_ip", c.ClientIP()),
+				slog.String("reason", err.Error()),
+			)
+			c.Header("WWW-Authenticate", `Bearer realm="go-elastic-api"`)
+			if errors.Is(err, auth.ErrMissingCredentials) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(auth.ErrInvalidCredentials))
+			return
+		}
+
+		ctx := auth.WithPrincipal(c.Request.Context(), principal)
+		if principal.Publisher != "" {
+			ctx = es.WithPublisherScope(ctx, principal.Publisher)
+		}
+		c.Set(principalContextKey, principal)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// requireRole rejects requests of principals below role with 403 Forbidden.
+// Without authentication there is no principal and every request is let through.
+func requireRole(role auth.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := principal(c)
+		if ok && !p.Can(role) {
+			c.AbortWithStatusJSON(http.StatusForbidden,
+				errorResponse(fmt.Errorf("role %s is required, %s has role %s", role, p.Subject, p.Role)))
+			return
+		}
+		c.Next()
+	}
+}
+
+// principal returns the authenticated caller of the request, if any.
+func principal(c *gin.Context) (auth.Principal, bool) {
+	p, ok := c.Get(principalContextKey)
+	if !ok {
+		return auth.Principal{}, false
+	}
+	principal, ok := p.(auth.Principal)
+	return principal, ok
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-elastic-api/auth"
+	"go-elastic-api/es"
+	"go-elastic-api/util"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/healthstatus"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := util.DefaultConfig()
+	cfg.AuthEnabled = true
+	cfg.AuthAPIKeys = []string{"ci:" + auth.HashAPIKey("ci-key")}
+
+	store := &fakeStore{status: healthstatus.Green, indexExists: true, searchRes: &search.Response{}}
+	server, err := NewServer(cfg, store, prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		url        string
+		apiKey     string
+		wantStatus int
+	}{
+		{name: "NoCredentials", url: "/search/full_text_search?query_str=dune", wantStatus: http.StatusUnauthorized},
+		{name: "InvalidKey", url: "/search/full_text_search?query_str=dune", apiKey: "guess", wantStatus: http.StatusUnauthorized},
+		{name: "ValidKey", url: "/search/full_text_search?query_str=dune", apiKey: "ci-key", wantStatus: http.StatusOK},
+		{name: "ProbesStayAnonymous", url: "/readyz", wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			if tc.apiKey != "" {
+				req.Header.Set(auth.APIKeyHeader, tc.apiKey)
+			}
+			server.router.ServeHTTP(recorder, req)
+
+			require.Equal(t, tc.wantStatus, recorder.Code)
+			if tc.wantStatus == http.StatusUnauthorized {
+				require.NotEmpty(t, recorder.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := util.DefaultConfig()
+	cfg.AuthEnabled = true
+	cfg.AuthAPIKeys = []string{
+		"reader:" + auth.HashAPIKey("reader-key"),
+		"editor:" + auth.HashAPIKey("editor-key") + ":editor",
+		"acme:" + auth.HashAPIKey("acme-key") + ":editor:Acme",
+		"admin:" + auth.HashAPIKey("admin-key") + ":admin",
+	}
+
+	store := &fakeStore{searchRes: &search.Response{}}
+	server, err := NewServer(cfg, store, prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		method     string
+		url        string
+		body       string
+		apiKey     string
+		wantStatus int
+		wantScope  string
+	}{
+		{name: "ReaderCanSearch", method: http.MethodGet, url: "/search/full_text_search?query_str=dune", apiKey: "reader-key", wantStatus: http.StatusOK},
+		{name: "ReaderCannotWrite", method: http.MethodDelete, url: "/books/42", apiKey: "reader-key", wantStatus: http.StatusForbidden},
+		{name: "EditorCanSearch", method: http.MethodGet, url: "/search/full_text_search?query_str=dune", apiKey: "editor-key", wantStatus: http.StatusOK},
+		{name: "EditorCanCreate", method: http.MethodPost, url: "/books", body: `{"id": "42", "name": "Dune"}`, apiKey: "editor-key", wantStatus: http.StatusCreated},
+		{name: "EditorCannotAdminister", method: http.MethodDelete, url: "/admin/indices/books", apiKey: "editor-key", wantStatus: http.StatusForbidden},
+		{name: "ScopedEditorDelete", method: http.MethodDelete, url: "/books/42", apiKey: "acme-key", wantStatus: http.StatusNoContent, wantScope: "Acme"},
+		{name: "AdminCanWrite", method: http.MethodDelete, url: "/books/42", apiKey: "admin-key", wantStatus: http.StatusNoContent},
+		{name: "AdminRejectsEmptyFilter", method: http.MethodPost, url: "/admin/delete_by_query", body: `{}`, apiKey: "admin-key", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store.lastScope = ""
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+			req.Header.Set(auth.APIKeyHeader, tc.apiKey)
+			server.router.ServeHTTP(recorder, req)
+
+			require.Equal(t, tc.wantStatus, recorder.Code, recorder.Body.String())
+			require.Equal(t, tc.wantScope, store.lastScope)
+		})
+	}
+}
+
+func TestCreateBookOutOfScope(t *testing.T) {
+	store := &fakeStore{writeErr: es.ErrOutOfScope}
+	server := newTestServer(t, store)
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/books", strings.NewReader(`{"id": "42", "name": "Dune", "publisher": "Other"}`))
+	server.router.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+}
+
+func TestUpdateUnknownBook(t *testing.T) {
+	store := &fakeStore{writeErr: es.ErrBookNotFound}
+	server := newTestServer(t, store)
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/books/missing", strings.NewReader(`{"name": "Dune"}`))
+	server.router.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusNotFound, recorder.Code, recorder.Body.String())
+}
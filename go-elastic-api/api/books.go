@@ -0,0 +1,102 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go-elastic-api/embedding"
+	"go-elastic-api/es"
+	"go-elastic-api/val"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/result"
+	"github.com/gin-gonic/gin"
+)
+
+// bindBook reads a book from the JSON body and checks the required fields.
+// A scoped editor may leave the publisher out, it defaults to theirs.
+func bindBook(c *gin.Context) (es.Book, error) {
+	var book es.Book
+	if err := c.ShouldBindJSON(&book); err != nil {
+		return book, fmt.Errorf("invalid book format: %s", err)
+	}
+	if publisher, ok := es.PublisherScope(c.Request.Context()); ok && book.Publisher == "" {
+		book.Publisher = publisher
+	}
+	if book.Name == "" {
+		return book, errors.New("name is required")
+	}
+	if book.ReleaseDate != "" && !val.IsDateValid(book.ReleaseDate) {
+		return book, fmt.Errorf("release_date must be a date as YYYY-MM-DD, got %q", book.ReleaseDate)
+	}
+	if book.Language != "" && !val.IsLanguageValid(book.Language) {
+		return book, fmt.Errorf("language must be a lowercase ISO 639-1 code like en or vi, got %q", book.Language)
+	}
+	if len(book.Embedding) > 0 && len(book.Embedding) != embedding.Dims {
+		return book, fmt.Errorf("embedding must have %d dimensions, got %d", embedding.Dims, len(book.Embedding))
+	}
+	return book, nil
+}
+
+// createBook adds a new book.
+// It returns 201 Created with the book, 400 Bad Request if the book is invalid,
+// 403 Forbidden if the book belongs to another publisher than the caller's,
+// and 409 Conflict if a book with the same ID exists.
+// Example request: POST /api/v1/books {"id": "42", "name": "Some Book", "author": "Some Author", ...}
+// Example response: {"id": "42", "name": "Some Book", "author": "Some Author", ...}
+func (server *Server) createBook(c *gin.Context) {
+	book, err := bindBook(c)
+	if err == nil && book.ID == "" {
+		err = errors.New("id is required")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, err := server.esStore.CreateBook(c.Request.Context(), book); err != nil {
+		respondESError(c, fmt.Errorf("error creating book %s: %w", book.ID, err))
+		return
+	}
+	c.JSON(http.StatusCreated, book)
+}
+
+// updateBook replaces the book with the ID of the path.
+// It returns 200 OK with the book, 400 Bad Request if the book is invalid,
+// and 404 Not Found if the book does not exist or belongs to another publisher than the caller's.
+// Example request: PUT /api/v1/books/42 {"name": "Some Book", "author": "Some Author", ...}
+// Example response: {"id": "42", "name": "Some Book", "author": "Some Author", ...}
+func (server *Server) updateBook(c *gin.Context) {
+	book, err := bindBook(c)
+	if err == nil && book.ID != "" && book.ID != c.Param("id") {
+		err = fmt.Errorf("id %q in the body does not match the path", book.ID)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	book.ID = c.Param("id")
+
+	if _, err := server.esStore.UpdateBook(c.Request.Context(), book); err != nil {
+		respondESError(c, fmt.Errorf("error updating book %s: %w", book.ID, err))
+		return
+	}
+	c.JSON(http.StatusOK, book)
+}
+
+// deleteBook deletes the book with the ID of the path.
+// It returns 204 No Content, or 404 Not Found if the book does not exist
+// or belongs to another publisher than the caller's.
+// Example request: DELETE /api/v1/books/42
+func (server *Server) deleteBook(c *gin.Context) {
+	bookID := c.Param("id")
+	res, err := server.esStore.DeleteBook(c.Request.Context(), bookID)
+	if err == nil && res.Result == result.Notfound {
+		err = es.ErrBookNotFound
+	}
+	if err != nil {
+		respondESError(c, fmt.Errorf("error deleting book %s: %w", bookID, err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
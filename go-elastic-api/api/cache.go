@@ -0,0 +1,55 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondCacheable writes v as a 200 OK JSON response with an ETag computed from the body,
+// or answers 304 Not Modified without a body when the If-None-Match header of the request matches it.
+// The ETag of a compressed body ends with its encoding, e.g. "…-br", since its bytes differ.
+// Responses may be cached by clients for CACHE_TTL; without the cache they must be revalidated.
+func (server *Server) respondCacheable(c *gin.Context, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(fmt.Errorf("cannot encode response: %w", err)))
+		return
+	}
+	digest := sha256.Sum256(body)
+	etag := hex.EncodeToString(digest[:16])
+	if encoding := c.GetString(encodingContextKey); encoding != "" && len(body) >= server.config.CompressionMinSize {
+		etag += "-" + encoding
+	}
+	etag = `"` + etag + `"`
+
+	c.Header("ETag", etag)
+	if server.config.CacheEnabled {
+		c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", int(server.config.CacheTTL.Seconds())))
+	} else {
+		c.Header("Cache-Control", "no-cache")
+	}
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// etagMatches reports whether an If-None-Match header lists etag. The comparison is weak,
+// as required for If-None-Match: W/"x" matches "x".
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/stretchr/testify/require"
+)
+
+func TestETag(t *testing.T) {
+	server := newTestServer(t, &fakeStore{searchRes: &search.Response{}})
+
+	recorder := doRequest(server, http.MethodGet, "/search/full_text_search?query_str=dune")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	etag := recorder.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	require.Equal(t, "no-cache", recorder.Header().Get("Cache-Control"))
+
+	for header, wantStatus := range map[string]int{
+		etag:               http.StatusNotModified,
+		"W/" + etag:        http.StatusNotModified,
+		`"other", ` + etag: http.StatusNotModified,
+		`"other"`:          http.StatusOK,
+		"*":                http.StatusNotModified,
+	} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/search/full_text_search?query_str=dune", nil)
+		req.Header.Set("If-None-Match", header)
+		server.router.ServeHTTP(recorder, req)
+
+		require.Equal(t, wantStatus, recorder.Code, header)
+		if wantStatus == http.StatusNotModified {
+			require.Empty(t, recorder.Body.String())
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// encodingContextKey is the gin context key of the encoding compress negotiated,
+// which respondCacheable adds to the ETag of the responses it compresses.
+const encodingContextKey = "api.encoding"
+
+// compress compresses responses of at least minSize bytes with brotli or gzip,
+// whichever the client accepts, preferring brotli. Smaller responses are sent as they are,
+// as are responses a handler already encoded, such as /metrics, and gzip files.
+func compress(minSize int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{ResponseWriter: c.Writer, encoding: encoding, minSize: minSize}
+		c.Writer = w
+		c.Set(encodingContextKey, encoding)
+		defer w.close()
+
+		c.Header("Vary", "Accept-Encoding")
+		c.Next()
+	}
+}
+
+// negotiateEncoding returns br or gzip if the Accept-Encoding header allows it, otherwise an empty string.
+func negotiateEncoding(header string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		accepted[strings.ToLower(name)] = q > 0
+	}
+	for _, encoding := range []string{"br", "gzip"} {
+		if accepted[encoding] {
+			return encoding
+		}
+	}
+	return ""
+}
+
+// compressWriter buffers the response until it reaches minSize bytes,
+// then writes it through an encoder.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	minSize  int
+
+	buf         []byte
+	encoder     io.WriteCloser
+	passthrough bool
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	switch {
+	case w.passthrough:
+		return w.ResponseWriter.Write(b)
+	case w.encoder != nil:
+		return w.encoder.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minSize {
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// start writes the buffered bytes, through an encoder if compress is true and the
+// response is not encoded already.
+func (w *compressWriter) start(compress bool) error {
+	h := w.Header()
+	if !compress || h.Get("Content-Encoding") != "" || h.Get("Content-Type") == "application/gzip" {
+		w.passthrough = true
+	} else {
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		if w.encoding == "br" {
+			w.encoder = brotli.NewWriterLevel(w.ResponseWriter, brotli.DefaultCompression)
+		} else {
+			w.encoder, _ = gzip.NewWriterLevel(w.ResponseWriter, gzip.DefaultCompression)
+		}
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (w *compressWriter) Flush() {
+	if w.encoder == nil && !w.passthrough {
+		_ = w.start(len(w.buf) >= w.minSize)
+	}
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// close writes a response smaller than minSize as it is, or finishes the encoded stream.
+func (w *compressWriter) close() {
+	if w.encoder == nil && !w.passthrough {
+		_ = w.start(false)
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Close()
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"go-elastic-api/es"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/gin-gonic/gin"
+)
+
+// passthroughStatuses are the Elasticsearch error statuses caused by the request itself.
+var passthroughStatuses = []int{
+	http.StatusBadRequest,
+	http.StatusNotFound,
+	http.StatusConflict,
+}
+
+// respondESError writes the response for an error returned by es.Client.
+// Errors meaning the cluster is unavailable or overloaded are reported as 503 with a
+// Retry-After header, so clients and load balancers back off instead of retrying at once.
+// Bad requests, missing documents and version conflicts keep their status.
+func respondESError(c *gin.Context, err error) {
+	var openErr *es.CircuitOpenError
+	var esErr *types.ElasticsearchError
+
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, es.ErrEmptyFilter):
+		status = http.StatusBadRequest
+	case errors.Is(err, es.ErrBookNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, es.ErrOutOfScope):
+		status = http.StatusForbidden
+	case errors.As(err, &openErr):
+		status = http.StatusServiceUnavailable
+		setRetryAfter(c, openErr.RetryAfter)
+	case errors.As(err, &esErr) && esErr.Status == http.StatusTooManyRequests:
+		status = http.StatusServiceUnavailable
+		setRetryAfter(c, time.Second)
+	case errors.As(err, &esErr) && slices.Contains(passthroughStatuses, esErr.Status):
+		status = esErr.Status
+	case errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusGatewayTimeout
+	}
+
+	_ = c.Error(err)
+	c.JSON(status, errorResponse(err))
+}
+
+// setRetryAfter sets the Retry-After header in whole seconds, rounded up.
+func setRetryAfter(c *gin.Context, d time.Duration) {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+}
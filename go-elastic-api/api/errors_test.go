@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"go-elastic-api/es"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRespondESError(t *testing.T) {
+	testCases := []struct {
+		name           string
+		err            error
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{
+			name:           "CircuitOpen",
+			err:            &es.CircuitOpenError{RetryAfter: 12500 * time.Millisecond},
+			wantStatus:     http.StatusServiceUnavailable,
+			wantRetryAfter: "13",
+		},
+		{
+			name:           "TooManyRequests",
+			err:            &types.ElasticsearchError{Status: http.StatusTooManyRequests},
+			wantStatus:     http.StatusServiceUnavailable,
+			wantRetryAfter: "1",
+		},
+		{
+			name:       "Timeout",
+			err:        context.DeadlineExceeded,
+			wantStatus: http.StatusGatewayTimeout,
+		},
+		{
+			name:       "Other",
+			err:        errors.New("boom"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, &fakeStore{searchErr: tc.err})
+
+			recorder := doRequest(server, http.MethodGet, "/search/full_text_search?query_str=dune")
+			require.Equal(t, tc.wantStatus, recorder.Code)
+			require.Equal(t, tc.wantRetryAfter, recorder.Header().Get("Retry-After"))
+		})
+	}
+}
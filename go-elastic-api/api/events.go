@@ -0,0 +1,60 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"go-elastic-api/analytics"
+	"go-elastic-api/auth"
+	"go-elastic-api/es"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EventRecorder queues analytics events to be written in the background, *analytics.Recorder is one.
+type EventRecorder interface {
+	Record(event any)
+}
+
+// SetEventRecorder records the clicks posted to /events/click with recorder.
+// Without one, analytics are disabled and clicks are accepted but not recorded.
+func (server *Server) SetEventRecorder(recorder EventRecorder) {
+	server.events = recorder
+}
+
+type clickRequest struct {
+	Query    string `json:"query" binding:"required"`
+	BookID   string `json:"book_id" binding:"required"`
+	Position int    `json:"position" binding:"min=0"`
+}
+
+// recordClick records that a book listed in the results of a query was clicked.
+// Clicks count in the click-through rate of the query and in the popularity of the book.
+// It returns 202 Accepted once the click is queued, 204 No Content when analytics are disabled,
+// and 400 Bad Request without a query or book_id.
+// Example request: POST /api/v1/events/click {"query": "dune", "book_id": "42", "position": 1}
+func (server *Server) recordClick(c *gin.Context) {
+	var req clickRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("invalid click: %s", err)))
+		return
+	}
+	if server.events == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	event := es.ClickEvent{
+		Timestamp: time.Now().UTC(),
+		Type:      es.EventClick,
+		Query:     analytics.NormalizeQuery(req.Query),
+		BookID:    req.BookID,
+		Position:  req.Position,
+	}
+	if principal, ok := auth.FromContext(c.Request.Context()); ok {
+		event.User = principal.Subject
+	}
+	server.events.Record(event)
+	c.Status(http.StatusAccepted)
+}
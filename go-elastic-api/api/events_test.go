@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-elastic-api/es"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/stretchr/testify/require"
+)
+
+// eventRecorder keeps the events it is given.
+type eventRecorder struct {
+	events []any
+}
+
+func (r *eventRecorder) Record(event any) {
+	r.events = append(r.events, event)
+}
+
+func TestRecordClick(t *testing.T) {
+	server := newTestServer(t, &fakeStore{searchRes: &search.Response{}})
+	postClick := func(body string) int {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/events/click", strings.NewReader(body))
+		server.router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// Without analytics, clicks are accepted and dropped
+	require.Equal(t, http.StatusNoContent, postClick(`{"query": "dune", "book_id": "42"}`))
+
+	events := &eventRecorder{}
+	server.SetEventRecorder(events)
+	require.Equal(t, http.StatusAccepted, postClick(`{"query": "  Snow CRASH ", "book_id": "42", "position": 3}`))
+	require.Equal(t, http.StatusBadRequest, postClick(`{"query": "dune"}`))
+	require.Equal(t, http.StatusBadRequest, postClick(`{"query": "dune", "book_id": "42", "position": -1}`))
+
+	require.Len(t, events.events, 1)
+	click := events.events[0].(es.ClickEvent)
+	require.Equal(t, es.EventClick, click.Type)
+	require.Equal(t, "snow crash", click.Query)
+	require.Equal(t, "42", click.BookID)
+	require.Equal(t, 3, click.Position)
+	require.False(t, click.Timestamp.IsZero())
+}
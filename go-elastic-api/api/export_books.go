@@ -0,0 +1,146 @@
+package api
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"go-elastic-api/es"
+	"go-elastic-api/exporter"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportBatchSize is the number of books read per search request of an export.
+const exportBatchSize = 1000
+
+// exportBooks streams every book matching a filter as CSV or NDJSON.
+// It accepts the query parameters "format" (csv or ndjson, the default), "fields", a comma separated
+// list of the columns to export, "gzip" to download a gzip file, and the filter of filterBooks as
+// "author", "publisher", "categories" (repeated), "language" and "release_after".
+// Books are read from a point in time page after page and written as they come,
+// so an export of the whole index does not hold it in memory.
+// If a parameter is invalid, it returns a 400 Bad Request error.
+// If Elasticsearch fails before the first book is written, it returns the error as filterBooks does;
+// once the export has started, the connection is closed without ending the response
+// so that the client sees a truncated download rather than a short but complete one.
+// Example request: GET /api/v1/books/export?format=csv&fields=id,name,author&publisher=Penguin
+// Example response: id,name,author\n9780141187761,Nineteen Eighty-Four,George Orwell\n...
+func (server *Server) exportBooks(c *gin.Context) {
+	format := exporter.Format(c.DefaultQuery("format", string(exporter.FormatNDJSON)))
+	if !slices.Contains(exporter.Formats, format) {
+		c.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("format must be one of %v, got %q", exporter.Formats, format)))
+		return
+	}
+	columns, err := exporter.ParseColumns(c.Query("fields"), format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("invalid fields: %w", err)))
+		return
+	}
+	compressed, err := strconv.ParseBool(c.DefaultQuery("gzip", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("gzip must be a boolean, got %q", c.Query("gzip"))))
+		return
+	}
+
+	filter := exportFilter(c)
+	if err := server.checkFilter(filter); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// The response starts with the first page of books, errors before it are still reported as JSON
+	var writer exporter.Writer
+	var gz *gzip.Writer
+	start := func() error {
+		filename := "books." + string(format)
+		var w io.Writer = c.Writer
+		if compressed {
+			filename += ".gz"
+			c.Header("Content-Type", "application/gzip")
+			gz = gzip.NewWriter(c.Writer)
+			w = gz
+		} else {
+			c.Header("Content-Type", format.ContentType())
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Status(http.StatusOK)
+
+		var err error
+		writer, err = exporter.NewWriter(format, w, columns)
+		return err
+	}
+	flush := func() error {
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		if gz != nil {
+			if err := gz.Flush(); err != nil {
+				return err
+			}
+		}
+		c.Writer.Flush()
+		return nil
+	}
+
+	exported := 0
+	err = server.esStore.ScanBooks(c.Request.Context(), filter, exportBatchSize, func(books []es.Book) error {
+		if writer == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		for _, book := range books {
+			if err := writer.Write(book); err != nil {
+				return err
+			}
+		}
+		exported += len(books)
+		return flush()
+	})
+	if err == nil && writer == nil {
+		err = start()
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil && gz != nil {
+		err = gz.Close()
+	}
+
+	switch {
+	case err == nil:
+		return
+	case writer == nil:
+		respondESError(c, fmt.Errorf("error exporting books: %w", err))
+	default:
+		server.logger.ErrorContext(c.Request.Context(), "export interrupted",
+			slog.Int("exported", exported),
+			slog.String("error", err.Error()),
+		)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// exportFilter returns the filter of filterBooks set by the query parameters of c.
+func exportFilter(c *gin.Context) map[string]any {
+	filter := make(map[string]any)
+	for _, key := range []string{"author", "publisher", "language", "release_after"} {
+		if v, ok := c.GetQuery(key); ok {
+			filter[key] = v
+		}
+	}
+	if categories, ok := c.GetQueryArray("categories"); ok {
+		// As decoded from a JSON body, which checkFilter expects
+		values := make([]any, len(categories))
+		for i, category := range categories {
+			values[i] = category
+		}
+		filter["categories"] = values
+	}
+	return filter
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-elastic-api/es"
+
+	"github.com/stretchr/testify/require"
+)
+
+// scanStore serves ScanBooks from pages of books, failing with err after them.
+type scanStore struct {
+	fakeStore
+	pages      [][]es.Book
+	err        error
+	lastFilter map[string]any
+}
+
+func (s *scanStore) ScanBooks(ctx context.Context, filter map[string]any, batchSize int, fn func([]es.Book) error) error {
+	s.lastFilter = filter
+	for _, page := range s.pages {
+		if err := fn(page); err != nil {
+			return err
+		}
+	}
+	return s.err
+}
+
+var exportPages = [][]es.Book{
+	{{ID: "1", Name: "Snow Crash", Author: "Neal Stephenson", ReleaseDate: "1992-06-01", PageCount: 480, Categories: []string{"Cyberpunk"}}},
+	{{ID: "2", Name: "Dune", Author: "Frank Herbert", ReleaseDate: "1965-08-01", PageCount: 412}},
+}
+
+func TestExportBooks(t *testing.T) {
+	store := &scanStore{pages: exportPages}
+	server := newTestServer(t, store)
+
+	recorder := doRequest(server, http.MethodGet, "/api/v1/books/export?publisher=Ace&categories=Cyberpunk&categories=Satire&release_after=1990-01-01")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+	require.Equal(t, `attachment; filename="books.ndjson"`, recorder.Header().Get("Content-Disposition"))
+	lines := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n")
+	require.Len(t, lines, 2)
+	require.JSONEq(t, `{"id": "1", "name": "Snow Crash", "author": "Neal Stephenson", "edition": "", "publisher": "",
+		"release_date": "1992-06-01", "page_count": 480, "categories": ["Cyberpunk"]}`, lines[0])
+	require.Equal(t, map[string]any{
+		"publisher":     "Ace",
+		"categories":    []any{"Cyberpunk", "Satire"},
+		"release_after": "1990-01-01",
+	}, store.lastFilter)
+
+	recorder = doRequest(server, http.MethodGet, "/api/v1/books/export?format=csv&fields=id,name,categories")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "text/csv; charset=utf-8", recorder.Header().Get("Content-Type"))
+	require.Equal(t, `attachment; filename="books.csv"`, recorder.Header().Get("Content-Disposition"))
+	require.Equal(t, "id,name,categories\n1,Snow Crash,Cyberpunk\n2,Dune,\n", recorder.Body.String())
+
+	// Without books, the CSV still has its header
+	server = newTestServer(t, &scanStore{})
+	recorder = doRequest(server, http.MethodGet, "/api/v1/books/export?format=csv&fields=id")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "id\n", recorder.Body.String())
+}
+
+func TestExportBooksGzip(t *testing.T) {
+	server := newTestServer(t, &scanStore{pages: exportPages})
+
+	// A gzip download is not compressed again for clients accepting compressed responses
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/books/export?format=csv&fields=id,name&gzip=true", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip")
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "application/gzip", recorder.Header().Get("Content-Type"))
+	require.Empty(t, recorder.Header().Get("Content-Encoding"))
+	require.Equal(t, `attachment; filename="books.csv.gz"`, recorder.Header().Get("Content-Disposition"))
+	gz, err := gzip.NewReader(recorder.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	require.Equal(t, "id,name\n1,Snow Crash\n2,Dune\n", string(body))
+}
+
+func TestExportBooksErrors(t *testing.T) {
+	server := newTestServer(t, &scanStore{pages: exportPages})
+	for _, url := range []string{
+		"/api/v1/books/export?format=xlsx",
+		"/api/v1/books/export?fields=id,price",
+		"/api/v1/books/export?gzip=maybe",
+		"/api/v1/books/export?" + strings.Repeat("categories=c&", 100),
+	} {
+		recorder := doRequest(server, http.MethodGet, url)
+		require.Equal(t, http.StatusBadRequest, recorder.Code, url)
+	}
+
+	// Before the first book, errors are reported as usual
+	server = newTestServer(t, &scanStore{err: &es.CircuitOpenError{RetryAfter: 10 * time.Second}})
+	recorder := doRequest(server, http.MethodGet, "/api/v1/books/export")
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	require.Equal(t, "10", recorder.Header().Get("Retry-After"))
+
+	// After it, the response is aborted so that the client does not take it for a complete export
+	server = newTestServer(t, &scanStore{pages: exportPages, err: errors.New("search_context_missing_exception")})
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		doRequest(server, http.MethodGet, "/api/v1/books/export")
+	})
+}
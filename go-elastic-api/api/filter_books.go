@@ -8,11 +8,15 @@ import (
 )
 
 // filterBooks handles the filtering of books based on a JSON filter.
-// It expects a JSON body with the filter criteria.
-// If the body is invalid, it returns a 400 Bad Request error.
-// If the filtering is successful, it returns a 200 OK response with the list of books found.
-// If there is an error during filtering, it returns a 500 Internal Server Error.
-// Example request body: {"term": {"author": "Some Author"}}
+// It expects a JSON body with the filter criteria, and accepts optional "from" and "size"
+// query parameters for pagination.
+// If the body or the pagination is invalid, or the filter lists too many categories,
+// it returns a 400 Bad Request error.
+// If the filtering is successful, it returns a 200 OK response with the list of books found,
+// or 304 Not Modified when the If-None-Match header matches its ETag.
+// If there is an error during filtering, it returns a 500 Internal Server Error,
+// or a 503 Service Unavailable with Retry-After when Elasticsearch is unavailable.
+// Example request: POST /api/v1/books/filter {"term": {"author": "Some Author"}}
 // Example response: [{"id": "1", "name": "Some Book", "author": "Some Author", ...}, ...]
 func (server *Server) filterBooks(c *gin.Context) {
 	filter := make(map[string]any)
@@ -21,12 +25,23 @@ func (server *Server) filterBooks(c *gin.Context) {
 		return
 	}
 
-	res, err := server.esStore.FilterBooks(c.Request.Context(), filter)
+	if err := server.checkFilter(filter); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	page, err := server.parsePage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	res, err := server.esStore.FilterBooks(c.Request.Context(), filter, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(fmt.Errorf("error filtering books: %s", err)))
+		respondESError(c, fmt.Errorf("error filtering books: %w", err))
 		return
 	}
 
 	books := parseBooksTyped(res)
-	c.JSON(http.StatusOK, books)
+	server.respondCacheable(c, books)
 }
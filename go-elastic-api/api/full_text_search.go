@@ -8,11 +8,15 @@ import (
 )
 
 // fullTextSearch handles the full-text search for books by their name.
-// It expects a query string parameter "query_str" which is the name of the book to search for.
-// If the parameter is missing, it returns a 400 Bad Request error.
-// If the search is successful, it returns a 200 OK response with the list of books found.
-// If there is an error during the search, it returns a 500 Internal Server Error.
-// Example request: GET /api/v1/books/full_text_search?query_str=some_book_name
+// It expects a query string parameter "query_str" which is the name of the book to search for,
+// and accepts optional "from" and "size" parameters for pagination.
+// If the parameter is missing, the query is too expensive (e.g. a leading wildcard)
+// or the pagination is invalid, it returns a 400 Bad Request error.
+// If the search is successful, it returns a 200 OK response with the list of books found,
+// or 304 Not Modified when the If-None-Match header matches its ETag.
+// If there is an error during the search, it returns a 500 Internal Server Error,
+// or a 503 Service Unavailable with Retry-After when Elasticsearch is unavailable.
+// Example request: GET /api/v1/books/full_text_search?query_str=some_book_name&from=0&size=10
 // Example response: [{"id": "1", "name": "Some Book", "author": "Some Author", ...}, ...]
 func (server *Server) fullTextSearch(c *gin.Context) {
 	query_str := c.Query("query_str")
@@ -21,13 +25,24 @@ func (server *Server) fullTextSearch(c *gin.Context) {
 		return
 	}
 
-	res, err := server.esStore.FullTextSearch(c.Request.Context(), query_str)
+	if err := server.checkQueryString(query_str); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	page, err := server.parsePage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	res, err := server.esStore.FullTextSearch(c.Request.Context(), query_str, page)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, errorResponse(fmt.Errorf("error searching for books by name: %s", err)))
+		respondESError(c, fmt.Errorf("error searching for books by name: %w", err))
 		return
 	}
 
 	books := parseBooksTyped(res)
-	c.JSON(http.StatusOK, books)
+	server.respondCacheable(c, books)
 }
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"go-elastic-api/auth"
+	"go-elastic-api/util"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// corsMiddleware answers preflight requests and adds the CORS headers for the configured origins.
+// Origins may contain a wildcard, e.g. https://*.example.com, and * allows any origin.
+func corsMiddleware(cfg util.Config) gin.HandlerFunc {
+	config := cors.Config{
+		AllowMethods:     cfg.CORSAllowedMethods,
+		AllowHeaders:     cfg.CORSAllowedHeaders,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAge:           cfg.CORSMaxAge,
+		AllowWildcard:    true,
+		// Headers the frontend reads: caching, rate limiting and request correlation
+		ExposeHeaders: []string{"ETag", "Retry-After", requestIDHeader},
+	}
+	if slices.Contains(cfg.CORSAllowedOrigins, "*") {
+		config.AllowAllOrigins = true
+	} else {
+		config.AllowOrigins = cfg.CORSAllowedOrigins
+	}
+	if !slices.Contains(config.AllowHeaders, auth.APIKeyHeader) {
+		config.AllowHeaders = append(slices.Clone(config.AllowHeaders), auth.APIKeyHeader)
+	}
+	return cors.New(config)
+}
+
+// defaultContentSecurityPolicy fits JSON responses, which load nothing and are never framed.
+const defaultContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'"
+
+// securityHeaders sets the standard hardening headers on every response.
+// Strict-Transport-Security is only sent when hstsMaxAge is at least a second, since it only makes sense
+// when clients reach the service over HTTPS, e.g. through a TLS terminating load balancer.
+// Handlers may override the Content-Security-Policy.
+func securityHeaders(hstsMaxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "no-referrer")
+		h.Set("Cross-Origin-Opener-Policy", "same-origin")
+		h.Set("Content-Security-Policy", defaultContentSecurityPolicy)
+		if seconds := int64(hstsMaxAge.Seconds()); seconds > 0 {
+			h.Set("Strict-Transport-Security", fmt.Sprintf("max-age=%d; includeSubDomains", seconds))
+		}
+		c.Next()
+	}
+}
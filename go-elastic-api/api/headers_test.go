@@ -0,0 +1,148 @@
+package api
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-elastic-api/util"
+
+	"github.com/andybalholm/brotli"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := util.DefaultConfig()
+	cfg.CORSAllowedOrigins = []string{"https://app.example.com"}
+	server, err := NewServer(cfg, &fakeStore{searchRes: &search.Response{}}, prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	// 1. Preflight from an allowed origin
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodOptions, "/filter/books", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type, X-API-Key")
+	server.router.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+	require.Equal(t, "https://app.example.com", recorder.Header().Get("Access-Control-Allow-Origin"))
+	require.Contains(t, recorder.Header().Get("Access-Control-Allow-Methods"), http.MethodPost)
+	require.Contains(t, recorder.Header().Get("Access-Control-Allow-Headers"), "X-Api-Key")
+
+	// 2. Simple request from an allowed origin exposes the caching headers
+	recorder = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/search/full_text_search?query_str=dune", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	server.router.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "https://app.example.com", recorder.Header().Get("Access-Control-Allow-Origin"))
+	require.Contains(t, recorder.Header().Get("Access-Control-Expose-Headers"), "Etag")
+
+	// 3. Other origins are rejected
+	recorder = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/search/full_text_search?query_str=dune", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	server.router.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+	require.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	server := newTestServer(t, &fakeStore{})
+
+	recorder := doRequest(server, http.MethodGet, "/healthz")
+	require.Equal(t, "nosniff", recorder.Header().Get("X-Content-Type-Options"))
+	require.Equal(t, "DENY", recorder.Header().Get("X-Frame-Options"))
+	require.Equal(t, defaultContentSecurityPolicy, recorder.Header().Get("Content-Security-Policy"))
+	require.Empty(t, recorder.Header().Get("Strict-Transport-Security"))
+
+	recorder = doRequest(server, http.MethodGet, "/docs")
+	require.Equal(t, swaggerUIContentSecurityPolicy, recorder.Header().Get("Content-Security-Policy"))
+}
+
+func TestCompression(t *testing.T) {
+	hits := make([]types.Hit, 50)
+	for i := range hits {
+		id := fmt.Sprint(i)
+		hits[i] = types.Hit{Id_: &id, Source_: json.RawMessage(`{"name": "Dune", "author": "Frank Herbert"}`)}
+	}
+	server := newTestServer(t, &fakeStore{searchRes: &search.Response{Hits: types.HitsMetadata{Hits: hits}}})
+
+	uncompressed := doRequest(server, http.MethodGet, "/search/full_text_search?query_str=dune")
+	require.Empty(t, uncompressed.Header().Get("Content-Encoding"))
+	require.Greater(t, uncompressed.Body.Len(), server.config.CompressionMinSize)
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+	}
+	for encoding, decode := range decoders {
+		t.Run(encoding, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/search/full_text_search?query_str=dune", nil)
+			req.Header.Set("Accept-Encoding", encoding+", identity;q=0.5")
+			server.router.ServeHTTP(recorder, req)
+
+			require.Equal(t, http.StatusOK, recorder.Code)
+			require.Equal(t, encoding, recorder.Header().Get("Content-Encoding"))
+			require.Equal(t, "Accept-Encoding", recorder.Header().Get("Vary"))
+			require.Less(t, recorder.Body.Len(), uncompressed.Body.Len())
+			// Each representation has its own ETag
+			require.Equal(t, strings.TrimSuffix(uncompressed.Header().Get("ETag"), `"`)+"-"+encoding+`"`, recorder.Header().Get("ETag"))
+
+			r, err := decode(recorder.Body)
+			require.NoError(t, err)
+			body, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, uncompressed.Body.String(), string(body))
+
+			// The identity body is not the one the client would get
+			for etag, wantStatus := range map[string]int{
+				uncompressed.Header().Get("ETag"): http.StatusOK,
+				recorder.Header().Get("ETag"):     http.StatusNotModified,
+			} {
+				revalidated := httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodGet, "/search/full_text_search?query_str=dune", nil)
+				req.Header.Set("Accept-Encoding", encoding)
+				req.Header.Set("If-None-Match", etag)
+				server.router.ServeHTTP(revalidated, req)
+				require.Equal(t, wantStatus, revalidated.Code, etag)
+			}
+		})
+	}
+
+	// Small responses are not worth compressing
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	server.router.ServeHTTP(recorder, req)
+	require.Empty(t, recorder.Header().Get("Content-Encoding"))
+	require.JSONEq(t, `{"status": "ok"}`, recorder.Body.String())
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	for header, want := range map[string]string{
+		"":                       "",
+		"identity":               "",
+		"gzip":                   "gzip",
+		"gzip, deflate, br":      "br",
+		"br;q=0, gzip;q=0.8":     "gzip",
+		"GZIP;q=0.5, deflate":    "gzip",
+		"br;q=0.0, gzip;q=0.000": "",
+	} {
+		require.Equal(t, want, negotiateEncoding(header), header)
+	}
+}
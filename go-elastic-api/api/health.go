@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go-elastic-api/es"
+	"go-elastic-api/util"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/healthstatus"
+	"github.com/gin-gonic/gin"
+)
+
+// readinessCheckTimeout bounds the time spent on all readiness checks.
+const readinessCheckTimeout = 3 * time.Second
+
+type checkResult struct {
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type readinessReport struct {
+	Ready     bool                   `json:"ready"`
+	Checks    map[string]checkResult `json:"checks"`
+	CheckedAt time.Time              `json:"checked_at"`
+}
+
+// readinessChecker checks whether Elasticsearch is usable and caches the
+// result for a short time, so frequent probes do not hammer the cluster.
+type readinessChecker struct {
+	esStore es.Client
+	index   string
+	ttl     time.Duration
+
+	mu     sync.Mutex
+	report *readinessReport
+}
+
+func newReadinessChecker(esStore es.Client, index string, ttl time.Duration) *readinessChecker {
+	return &readinessChecker{
+		esStore: esStore,
+		index:   index,
+		ttl:     ttl,
+	}
+}
+
+// Check returns the cached report if it is still fresh, otherwise it runs all checks.
+// Concurrent callers wait for a single in-flight check instead of starting their own.
+// A report of a probe that went away during the checks is not cached, it says nothing of the cluster.
+func (r *readinessChecker) Check(ctx context.Context) readinessReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.report != nil && time.Since(r.report.CheckedAt) < r.ttl {
+		return *r.report
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+
+	report := r.run(checkCtx)
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		r.report = &report
+	}
+	return report
+}
+
+func (r *readinessChecker) run(ctx context.Context) readinessReport {
+	report := readinessReport{
+		Ready:     true,
+		Checks:    make(map[string]checkResult),
+		CheckedAt: time.Now(),
+	}
+	fail := func(name string, err error) {
+		report.Ready = false
+		report.Checks[name] = checkResult{Status: "fail", Detail: err.Error()}
+	}
+
+	// 1. The cluster answers at all
+	ok, err := r.esStore.Ping(ctx)
+	if err == nil && !ok {
+		err = fmt.Errorf("ping was not successful")
+	}
+	if err != nil {
+		fail("ping", err)
+		return report
+	}
+	report.Checks["ping"] = checkResult{Status: "ok"}
+
+	// 2. The cluster is not red; yellow is accepted since a single node cannot allocate replicas
+	health, err := r.esStore.ClusterHealth(ctx)
+	if err != nil {
+		fail("cluster_health", err)
+	} else if health.Status == healthstatus.Red {
+		fail("cluster_health", fmt.Errorf("cluster %s is %s", health.ClusterName, health.Status))
+	} else {
+		report.Checks["cluster_health"] = checkResult{Status: "ok", Detail: health.Status.String()}
+	}
+
+	// 3. The books index or alias exists
+	exists, err := r.esStore.IndexExists(ctx, r.index)
+	if err == nil && !exists {
+		err = fmt.Errorf("index or alias %q does not exist", r.index)
+	}
+	if err != nil {
+		fail("index", err)
+	} else {
+		report.Checks["index"] = checkResult{Status: "ok", Detail: r.index}
+	}
+
+	return report
+}
+
+// healthz reports that the process is alive. It never touches Elasticsearch.
+// Example request: GET /healthz
+// Example response: {"status": "ok"}
+func (server *Server) healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// readyz reports whether the service can serve traffic.
+// It returns 200 OK when Elasticsearch is reachable, the cluster is not red and the books index exists,
+// otherwise it returns 503 Service Unavailable with the failing checks.
+// Example request: GET /readyz
+// Example response: {"ready": true, "checks": {"ping": {"status": "ok"}, ...}, "checked_at": "..."}
+func (server *Server) readyz(c *gin.Context) {
+	report := server.readiness.Check(c.Request.Context())
+	if !report.Ready {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// version reports the build information of the running binary.
+// Example request: GET /version
+// Example response: {"version": "v1.2.0", "commit": "067bd23", "build_date": "...", "go_version": "go1.24.0"}
+func (server *Server) version(c *gin.Context) {
+	c.JSON(http.StatusOK, util.GetBuildInfo())
+}
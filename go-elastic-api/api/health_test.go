@@ -0,0 +1,194 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-elastic-api/es"
+	"go-elastic-api/logging"
+	"go-elastic-api/util"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/cluster/health"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/create"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/delete"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/index"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/healthstatus"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/result"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore implements the health, search and book write methods of es.Client.
+// Calling any other method panics on the nil embedded interface.
+type fakeStore struct {
+	es.Client
+
+	pingErr     error
+	status      healthstatus.HealthStatus
+	indexExists bool
+
+	pings         int
+	lastRequestID string
+
+	searchRes *search.Response
+	searchErr error
+
+	writeErr  error
+	lastScope string
+}
+
+func (f *fakeStore) CreateBook(ctx context.Context, book es.Book) (*create.Response, error) {
+	f.lastScope, _ = es.PublisherScope(ctx)
+	return &create.Response{Id_: book.ID}, f.writeErr
+}
+
+func (f *fakeStore) UpdateBook(ctx context.Context, book es.Book) (*index.Response, error) {
+	f.lastScope, _ = es.PublisherScope(ctx)
+	return &index.Response{Id_: book.ID}, f.writeErr
+}
+
+func (f *fakeStore) DeleteBook(ctx context.Context, bookID string) (*delete.Response, error) {
+	f.lastScope, _ = es.PublisherScope(ctx)
+	return &delete.Response{Id_: bookID, Result: result.Deleted}, f.writeErr
+}
+
+func (f *fakeStore) FullTextSearch(ctx context.Context, query string, page es.Page) (*search.Response, error) {
+	return f.searchRes, f.searchErr
+}
+
+func (f *fakeStore) FilterBooks(ctx context.Context, filter map[string]any, page es.Page) (*search.Response, error) {
+	return f.searchRes, f.searchErr
+}
+
+func (f *fakeStore) Index() string {
+	return es.DefaultBooksIndex
+}
+
+func (f *fakeStore) Ping(ctx context.Context) (bool, error) {
+	f.pings++
+	f.lastRequestID = logging.RequestID(ctx)
+	return f.pingErr == nil, f.pingErr
+}
+
+func (f *fakeStore) ClusterHealth(ctx context.Context) (*health.Response, error) {
+	return &health.Response{ClusterName: "test", Status: f.status}, nil
+}
+
+func (f *fakeStore) IndexExists(ctx context.Context, name string) (bool, error) {
+	return f.indexExists, nil
+}
+
+func newTestServer(t *testing.T, store es.Client) *Server {
+	gin.SetMode(gin.TestMode)
+	server, err := NewServer(util.DefaultConfig(), store, prometheus.NewRegistry())
+	require.NoError(t, err)
+	return server
+}
+
+func doRequest(server *Server, method, url string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(method, url, nil)
+	server.router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestHealthz(t *testing.T) {
+	store := &fakeStore{pingErr: errors.New("connection refused")}
+	server := newTestServer(t, store)
+
+	recorder := doRequest(server, http.MethodGet, "/healthz")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Zero(t, store.pings, "liveness must not call Elasticsearch")
+}
+
+func TestReadyz(t *testing.T) {
+	testCases := []struct {
+		name       string
+		store      *fakeStore
+		wantStatus int
+		wantFailed string
+	}{
+		{
+			name:       "Ready",
+			store:      &fakeStore{status: healthstatus.Yellow, indexExists: true},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "PingFails",
+			store:      &fakeStore{pingErr: errors.New("connection refused")},
+			wantStatus: http.StatusServiceUnavailable,
+			wantFailed: "ping",
+		},
+		{
+			name:       "ClusterRed",
+			store:      &fakeStore{status: healthstatus.Red, indexExists: true},
+			wantStatus: http.StatusServiceUnavailable,
+			wantFailed: "cluster_health",
+		},
+		{
+			name:       "IndexMissing",
+			store:      &fakeStore{status: healthstatus.Green},
+			wantStatus: http.StatusServiceUnavailable,
+			wantFailed: "index",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, tc.store)
+
+			recorder := doRequest(server, http.MethodGet, "/readyz")
+			require.Equal(t, tc.wantStatus, recorder.Code)
+
+			var report readinessReport
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+			if tc.wantFailed != "" {
+				require.Equal(t, "fail", report.Checks[tc.wantFailed].Status)
+			}
+		})
+	}
+}
+
+func TestReadyzIsCached(t *testing.T) {
+	store := &fakeStore{status: healthstatus.Green, indexExists: true}
+	server := newTestServer(t, store)
+
+	for i := 0; i < 3; i++ {
+		recorder := doRequest(server, http.MethodGet, "/readyz")
+		require.Equal(t, http.StatusOK, recorder.Code)
+	}
+	require.Equal(t, 1, store.pings)
+}
+
+func TestReadyzDoesNotCacheCanceledChecks(t *testing.T) {
+	store := &fakeStore{status: healthstatus.Green, indexExists: true}
+	server := newTestServer(t, store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	store.pingErr = ctx.Err()
+	require.False(t, server.readiness.Check(ctx).Ready)
+
+	store.pingErr = nil
+	recorder := doRequest(server, http.MethodGet, "/readyz")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, 2, store.pings)
+}
+
+func TestVersion(t *testing.T) {
+	server := newTestServer(t, &fakeStore{})
+
+	recorder := doRequest(server, http.MethodGet, "/version")
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var info util.BuildInfo
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &info))
+	require.Equal(t, util.Version, info.Version)
+	require.NotEmpty(t, info.GoVersion)
+}
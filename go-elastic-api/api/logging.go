@@ -0,0 +1,110 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"go-elastic-api/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+
+	// maxRequestIDLength bounds client supplied request IDs, longer ones are replaced.
+	maxRequestIDLength = 128
+)
+
+// requestID accepts the X-Request-ID header of the client or generates a new one.
+// The ID is echoed in the response and stored in the request context, so it ends up
+// in every log record and in the X-Opaque-Id of Elasticsearch calls.
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !isValidRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Header(requestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// accessLog logs one structured record per request once it has been served.
+func accessLog(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("bytes", c.Writer.Size()),
+		}
+		if p, ok := principal(c); ok {
+			attrs = append(attrs,
+				slog.String("principal", p.Subject),
+				slog.String("auth_method", p.Method),
+				slog.String("role", p.Role.String()),
+			)
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		logger.LogAttrs(c.Request.Context(), level, "http request", attrs...)
+	}
+}
+
+// recovery turns a panic in a handler into a 500 response and logs it.
+func recovery(logger *slog.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, err any) {
+		// Handlers abort a response that cannot be completed, such as an interrupted export,
+		// net/http then closes the connection instead of ending the response
+		if err == http.ErrAbortHandler {
+			panic(err)
+		}
+		logger.ErrorContext(c.Request.Context(), "panic recovered",
+			slog.Any("panic", err),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("stack", string(debug.Stack())),
+		)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-elastic-api/logging"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/healthstatus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	testCases := []struct {
+		name     string
+		header   string
+		wantSame bool
+	}{
+		{name: "Accepted", header: "abc-123", wantSame: true},
+		{name: "Generated", header: ""},
+		{name: "TooLong", header: strings.Repeat("a", maxRequestIDLength+1)},
+		{name: "InvalidCharacters", header: "abc 123\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeStore{status: healthstatus.Green, indexExists: true}
+			server := newTestServer(t, store)
+
+			req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			if tc.header != "" {
+				req.Header.Set(requestIDHeader, tc.header)
+			}
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, req)
+
+			id := recorder.Header().Get(requestIDHeader)
+			require.NotEmpty(t, id)
+			if tc.wantSame {
+				require.Equal(t, tc.header, id)
+			} else {
+				require.NotEqual(t, tc.header, id)
+			}
+			// The ID reaches the es calls through the request context
+			require.Equal(t, id, store.lastRequestID)
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "info", "json")
+	require.NoError(t, err)
+
+	server := newTestServer(t, &fakeStore{})
+	server.logger = logger
+	require.NoError(t, server.setupRouter())
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.Header.Set(requestIDHeader, "req-42")
+	server.router.ServeHTTP(httptest.NewRecorder(), req)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "http request", record["msg"])
+	require.Equal(t, "req-42", record["request_id"])
+	require.Equal(t, "/healthz", record["route"])
+	require.EqualValues(t, http.StatusOK, record["status"])
+}
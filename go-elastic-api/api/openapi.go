@@ -0,0 +1,53 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPISpec describes every route of the router. TestOpenAPISpecMatchesRoutes fails
+// when a route is added or removed without updating it.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// swaggerUIVersion is the swagger-ui-dist release loaded by /docs.
+const swaggerUIVersion = "5.18.2"
+
+// swaggerUIContentSecurityPolicy lets /docs load Swagger UI from unpkg and run its inline bootstrap script.
+const swaggerUIContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'; connect-src 'self'; " +
+	"script-src https://unpkg.com 'unsafe-inline'; style-src https://unpkg.com; img-src https://unpkg.com data:"
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>go-elastic-api</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
+
+// openAPI serves the OpenAPI 3 specification of the API.
+// Example request: GET /openapi.json
+func (server *Server) openAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openAPISpec)
+}
+
+// docs serves a Swagger UI page rendering /openapi.json.
+// Example request: GET /docs
+func (server *Server) docs(c *gin.Context) {
+	c.Header("Content-Security-Policy", swaggerUIContentSecurityPolicy)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
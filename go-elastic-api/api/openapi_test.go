@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// pathParam matches an OpenAPI path parameter, e.g. {id}.
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+func TestOpenAPISpecMatchesRoutes(t *testing.T) {
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(openAPISpec, &spec))
+	require.True(t, strings.HasPrefix(spec.OpenAPI, "3."))
+
+	documented := make(map[string]bool)
+	for path, item := range spec.Paths {
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			// /books/{id} in OpenAPI is /books/:id in gin
+			documented[strings.ToUpper(method)+" "+pathParam.ReplaceAllString(path, ":$1")] = true
+		}
+	}
+
+	server := newTestServer(t, &fakeStore{})
+	registered := make(map[string]bool)
+	for _, route := range server.router.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+
+	for route := range registered {
+		require.True(t, documented[route], "route %s is not documented in openapi.json", route)
+	}
+	for route := range documented {
+		require.True(t, registered[route], "openapi.json documents %s, which is not a route", route)
+	}
+}
+
+func TestOpenAPIEndpoints(t *testing.T) {
+	server := newTestServer(t, &fakeStore{})
+
+	recorder := doRequest(server, http.MethodGet, "/openapi.json")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.True(t, json.Valid(recorder.Body.Bytes()))
+
+	recorder = doRequest(server, http.MethodGet, "/docs")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `url: "openapi.json"`)
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+
+	"go-elastic-api/es"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parsePage reads the "from" and "size" query parameters.
+// A missing size falls back to the configured default page size,
+// and sizes above the configured maximum are rejected.
+func (server *Server) parsePage(c *gin.Context) (es.Page, error) {
+	page := es.Page{Size: server.config.DefaultPageSize}
+
+	if v := c.Query("from"); v != "" {
+		from, err := strconv.Atoi(v)
+		if err != nil || from < 0 {
+			return page, fmt.Errorf("from must be a non-negative integer, got %q", v)
+		}
+		page.From = from
+	}
+
+	if v := c.Query("size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < 1 {
+			return page, fmt.Errorf("size must be a positive integer, got %q", v)
+		}
+		if size > server.config.MaxPageSize {
+			return page, fmt.Errorf("size must not exceed %d, got %d", server.config.MaxPageSize, size)
+		}
+		page.Size = size
+	}
+
+	// Elasticsearch rejects windows beyond index.max_result_window, which defaults to 10000.
+	// The sum is not computed, it would overflow for a huge from.
+	if page.From > 10000-page.Size {
+		return page, fmt.Errorf("from + size must not exceed 10000, got from %d and size %d", page.From, page.Size)
+	}
+	return page, nil
+}
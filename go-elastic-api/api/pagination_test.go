@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-elastic-api/es"
+	"go-elastic-api/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePage(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    string
+		wantPage es.Page
+		wantErr  string
+	}{
+		{name: "Default", query: "", wantPage: es.Page{From: 0, Size: 10}},
+		{name: "Explicit", query: "from=20&size=5", wantPage: es.Page{From: 20, Size: 5}},
+		{name: "NegativeFrom", query: "from=-1", wantErr: "from must be a non-negative integer"},
+		{name: "ZeroSize", query: "size=0", wantErr: "size must be a positive integer"},
+		{name: "SizeAboveMax", query: "size=101", wantErr: "size must not exceed 100, got 101"},
+		{name: "BeyondResultWindow", query: "from=9950&size=100", wantErr: "from + size must not exceed 10000"},
+		{name: "MaxIntFrom", query: "from=9223372036854775807", wantErr: "from + size must not exceed 10000"},
+	}
+
+	server := &Server{config: util.DefaultConfig()}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/search/full_text_search?"+tc.query, nil)
+
+			page, err := server.parsePage(c)
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.wantPage, page)
+		})
+	}
+}
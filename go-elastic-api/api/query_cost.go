@@ -0,0 +1,112 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// checkQueryString rejects query_string queries that are expensive for the cluster:
+// a term starting with a wildcard, e.g. "*dune" or "name:?une", has to be matched
+// against every term of the index.
+func (server *Server) checkQueryString(query string) error {
+	if server.config.QueryAllowLeadingWildcard {
+		return nil
+	}
+	if term, ok := leadingWildcardTerm(query); ok {
+		return fmt.Errorf("query_str term %q starts with a wildcard, which is not allowed", term)
+	}
+	return nil
+}
+
+// leadingWildcardTerm returns the first term of a query_string query whose value starts with * or ?.
+// Quoted phrases and regular expressions are skipped, wildcards are literal in them.
+func leadingWildcardTerm(query string) (string, bool) {
+	for _, term := range queryTerms(query) {
+		value := strings.TrimLeft(term, "+-!")
+		if field, rest, ok := cutUnescaped(value, ':'); ok && field != "" {
+			value = rest
+		}
+		if strings.HasPrefix(value, "*") || strings.HasPrefix(value, "?") {
+			return term, true
+		}
+	}
+	return "", false
+}
+
+// queryTerms splits a query_string query on whitespace and parentheses,
+// leaving out quoted phrases and /regular expressions/.
+func queryTerms(query string) []string {
+	var terms []string
+	var term strings.Builder
+	flush := func() {
+		if term.Len() > 0 {
+			terms = append(terms, term.String())
+			term.Reset()
+		}
+	}
+
+	var quote rune
+	escaped := false
+	for _, r := range query {
+		switch {
+		case escaped:
+			escaped = false
+			if quote == 0 {
+				term.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			if quote == 0 {
+				term.WriteRune(r)
+			}
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || (r == '/' && (term.Len() == 0 || strings.HasSuffix(term.String(), ":"))):
+			quote = r
+		case unicode.IsSpace(r) || r == '(' || r == ')':
+			flush()
+		default:
+			term.WriteRune(r)
+		}
+	}
+	flush()
+	return terms
+}
+
+// cutUnescaped is strings.Cut for the first sep not preceded by a backslash.
+func cutUnescaped(s string, sep byte) (before, after string, found bool) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+// checkFilter rejects filters with a categories criterion that is not a list of strings,
+// or that lists more categories than configured.
+func (server *Server) checkFilter(filter map[string]any) error {
+	v, ok := filter["categories"]
+	if !ok {
+		return nil
+	}
+	categories, ok := v.([]any)
+	if !ok {
+		return fmt.Errorf("categories must be a list of strings")
+	}
+	for _, category := range categories {
+		if _, ok := category.(string); !ok {
+			return fmt.Errorf("categories must be a list of strings, got %v", category)
+		}
+	}
+	if len(categories) > server.config.FilterMaxCategories {
+		return fmt.Errorf("categories must not list more than %d values, got %d", server.config.FilterMaxCategories, len(categories))
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeadingWildcardTerm(t *testing.T) {
+	testCases := []struct {
+		query    string
+		wantTerm string
+	}{
+		{query: "dune"},
+		{query: "dun*"},
+		{query: "name:dun?"},
+		{query: `"*not a wildcard"`},
+		{query: `name:/.*une/`},
+		{query: `name:\*dune`},
+		{query: "*", wantTerm: "*"},
+		{query: "*dune", wantTerm: "*dune"},
+		{query: "name:?une", wantTerm: "name:?une"},
+		{query: "frank AND -author:*bert", wantTerm: "-author:*bert"},
+		{query: "name:(dune OR *arrakis)", wantTerm: "*arrakis"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			term, ok := leadingWildcardTerm(tc.query)
+			require.Equal(t, tc.wantTerm != "", ok)
+			require.Equal(t, tc.wantTerm, term)
+		})
+	}
+}
+
+func TestQueryCostLimits(t *testing.T) {
+	server := newTestServer(t, &fakeStore{searchRes: &search.Response{}})
+
+	recorder := doRequest(server, http.MethodGet, "/search/full_text_search?query_str=*une")
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "starts with a wildcard")
+
+	recorder = doRequest(server, http.MethodGet, "/search/full_text_search?query_str=dune&size=1000")
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	categories := `["` + strings.Repeat(`a","`, server.config.FilterMaxCategories) + `a"]`
+	for body, wantStatus := range map[string]int{
+		`{"categories": ["Science Fiction", "Classics"]}`: http.StatusOK,
+		`{"categories": "Science Fiction"}`:               http.StatusBadRequest,
+		`{"categories": [1, 2]}`:                          http.StatusBadRequest,
+		`{"categories": ` + categories + `}`:              http.StatusBadRequest,
+	} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/filter/books", strings.NewReader(body))
+		server.router.ServeHTTP(recorder, req)
+		require.Equal(t, wantStatus, recorder.Code, body)
+	}
+}
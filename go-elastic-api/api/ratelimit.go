@@ -0,0 +1,176 @@
+package api
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"go-elastic-api/util"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// rateLimitIdleTTL is how long the bucket of a client that stopped sending requests is kept.
+const rateLimitIdleTTL = 10 * time.Minute
+
+// rateLimiter keeps a token bucket per client for one route.
+type rateLimiter struct {
+	limit util.RateLimit
+	now   func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*clientBucket
+	lastSweep time.Time
+}
+
+type clientBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newRateLimiter(limit util.RateLimit) *rateLimiter {
+	return &rateLimiter{
+		limit:     limit,
+		now:       time.Now,
+		clients:   make(map[string]*clientBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow takes a token from the bucket of client. When the bucket is empty,
+// it returns false and the time until the next token is added.
+func (l *rateLimiter) allow(client string) (bool, time.Duration) {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > rateLimitIdleTTL {
+		for key, bucket := range l.clients {
+			if now.Sub(bucket.lastSeen) > rateLimitIdleTTL {
+				delete(l.clients, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	bucket, ok := l.clients[client]
+	if !ok {
+		bucket = &clientBucket{limiter: rate.NewLimiter(rate.Limit(l.limit.RPS), l.limit.Burst)}
+		l.clients[client] = bucket
+	}
+	bucket.lastSeen = now
+
+	reservation := bucket.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// routeRateLimits holds the limiters of every route, created on first use.
+// Routes listed in RATE_LIMIT_ROUTES get their own limit, the others the default one.
+// Before authentication, a client IP address has one looser bucket for every route.
+type routeRateLimits struct {
+	defaults  util.RateLimit
+	overrides map[string]util.RateLimit
+	preAuth   *rateLimiter
+
+	mu       sync.Mutex
+	limiters map[string]*rateLimiter
+}
+
+// newRouteRateLimits creates the limiters of RATE_LIMIT_ROUTES, whose routes must be among routes,
+// registered under prefix, so that a misspelled route is not silently left at the default limit.
+func newRouteRateLimits(cfg util.Config, prefix string, routes []apiRoute) (*routeRateLimits, error) {
+	overrides, err := cfg.RouteRateLimits()
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_ROUTES: %w", err)
+	}
+	known := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		known[route.method+" "+prefix+route.path] = true
+	}
+	for _, name := range slices.Sorted(maps.Keys(overrides)) {
+		if !known[name] {
+			return nil, fmt.Errorf("invalid RATE_LIMIT_ROUTES: %q is not a route of the API, such as %q", name, "GET "+prefix+"/books/full_text_search")
+		}
+	}
+	return &routeRateLimits{
+		defaults:  util.RateLimit{RPS: cfg.RateLimitRPS, Burst: cfg.RateLimitBurst},
+		overrides: overrides,
+		preAuth:   newRateLimiter(util.RateLimit{RPS: cfg.RateLimitIPRPS, Burst: cfg.RateLimitIPBurst}),
+		limiters:  make(map[string]*rateLimiter),
+	}, nil
+}
+
+func (r *routeRateLimits) limiter(route string) *rateLimiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.limiters[route]
+	if !ok {
+		limit, ok := r.overrides[route]
+		if !ok {
+			limit = r.defaults
+		}
+		l = newRateLimiter(limit)
+		r.limiters[route] = l
+	}
+	return l
+}
+
+// byClientIP tells clients apart by their IP address. When authentication is disabled,
+// it is the only way to tell them apart. Otherwise it throttles floods of missing or invalid
+// credentials before they are verified.
+func byClientIP(c *gin.Context) (string, bool) {
+	return "ip:" + c.ClientIP(), true
+}
+
+// byPrincipal tells clients apart by their authenticated principal, so that clients sharing
+// an IP address also get a bucket of their own. Requests without a principal are not limited again.
+func byPrincipal(c *gin.Context) (string, bool) {
+	p, ok := principal(c)
+	return "principal:" + p.Subject, ok
+}
+
+// middleware answers 429 Too Many Requests with a Retry-After header once a client
+// has used up its tokens for the route. Clients are told apart by client.
+func (r *routeRateLimits) middleware(client func(c *gin.Context) (string, bool)) gin.HandlerFunc {
+	return limitRequests(client, func(c *gin.Context) *rateLimiter {
+		return r.limiter(routeName(c))
+	})
+}
+
+// preAuthMiddleware limits the requests of a client IP address before its credentials are checked,
+// with RATE_LIMIT_IP_RPS and RATE_LIMIT_IP_BURST for all routes. The per route limits apply to
+// principals, which a NAT or gateway address may hold many of.
+func (r *routeRateLimits) preAuthMiddleware() gin.HandlerFunc {
+	return limitRequests(byClientIP, func(*gin.Context) *rateLimiter {
+		return r.preAuth
+	})
+}
+
+func limitRequests(client func(c *gin.Context) (string, bool), limiter func(c *gin.Context) *rateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := client(c)
+		if !ok {
+			c.Next()
+			return
+		}
+		l := limiter(c)
+
+		if ok, retryAfter := l.allow(key); !ok {
+			setRetryAfter(c, retryAfter)
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, errorResponse(fmt.Errorf(
+				"rate limit of %g requests per second exceeded, retry in %s", l.limit.RPS, retryAfter.Round(time.Millisecond))))
+			return
+		}
+		c.Next()
+	}
+}
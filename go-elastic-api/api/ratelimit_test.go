@@ -0,0 +1,171 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-elastic-api/auth"
+	"go-elastic-api/util"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(util.RateLimit{RPS: 2, Burst: 2})
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	// 1. The burst is available at once
+	for i := 0; i < 2; i++ {
+		ok, _ := limiter.allow("ip:10.0.0.1")
+		require.True(t, ok)
+	}
+
+	// 2. Then the client has to wait for the next token
+	ok, retryAfter := limiter.allow("ip:10.0.0.1")
+	require.False(t, ok)
+	require.Equal(t, 500*time.Millisecond, retryAfter)
+
+	// 3. Other clients have their own bucket
+	ok, _ = limiter.allow("ip:10.0.0.2")
+	require.True(t, ok)
+
+	// 4. A rejected request does not take a token
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = limiter.allow("ip:10.0.0.1")
+	require.True(t, ok)
+
+	// 5. Idle clients are forgotten
+	now = now.Add(2 * rateLimitIdleTTL)
+	limiter.allow("ip:10.0.0.3")
+	require.Len(t, limiter.clients, 1)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := util.DefaultConfig()
+	cfg.RateLimitEnabled = true
+	cfg.RateLimitRPS = 100
+	cfg.RateLimitBurst = 100
+	cfg.RateLimitRoutes = []string{"GET /api/v1/books/full_text_search=0.1:1"}
+
+	server, err := NewServer(cfg, &fakeStore{searchRes: &search.Response{}}, prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	recorder := doRequest(server, http.MethodGet, "/api/v1/books/full_text_search?query_str=dune")
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = doRequest(server, http.MethodGet, "/api/v1/books/full_text_search?query_str=dune")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "10", recorder.Header().Get("Retry-After"))
+
+	// The legacy alias shares the bucket of its route
+	recorder = doRequest(server, http.MethodGet, "/search/full_text_search?query_str=dune")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.NotEmpty(t, recorder.Header().Get("Deprecation"))
+
+	// The limit is per route, and probes are never limited
+	recorder = doRequest(server, http.MethodPost, "/api/v1/books/filter")
+	require.NotEqual(t, http.StatusTooManyRequests, recorder.Code)
+	for i := 0; i < 3; i++ {
+		require.Equal(t, http.StatusOK, doRequest(server, http.MethodGet, "/healthz").Code)
+	}
+}
+
+func TestRateLimitUnknownRoute(t *testing.T) {
+	cfg := util.DefaultConfig()
+	cfg.RateLimitEnabled = true
+
+	// Legacy aliases share the bucket of their route, they have none of their own
+	cfg.RateLimitRoutes = []string{"GET /search/full_text_search=5:10"}
+	_, err := NewServer(cfg, &fakeStore{}, prometheus.NewRegistry())
+	require.ErrorContains(t, err, `"GET /search/full_text_search" is not a route of the API`)
+
+	cfg.RateLimitRoutes = []string{"PUT /api/v1/books/:id=5:10", "POST /api/v1/books/filter=5:10"}
+	_, err = NewServer(cfg, &fakeStore{}, prometheus.NewRegistry())
+	require.NoError(t, err)
+}
+
+func TestRateLimitBeforeAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := util.DefaultConfig()
+	cfg.AuthEnabled = true
+	cfg.AuthAPIKeys = []string{"ci:" + auth.HashAPIKey("ci-key")}
+	cfg.RateLimitEnabled = true
+	cfg.RateLimitIPRPS = 0.1
+	cfg.RateLimitIPBurst = 2
+
+	server, err := NewServer(cfg, &fakeStore{searchRes: &search.Response{}}, prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	// Invalid credentials use up the bucket of the IP address before they are verified
+	for _, wantStatus := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/books/full_text_search?query_str=dune", nil)
+		req.Header.Set(auth.APIKeyHeader, "guess")
+		server.router.ServeHTTP(recorder, req)
+		require.Equal(t, wantStatus, recorder.Code)
+	}
+}
+
+func TestRateLimitPerKeyBehindSharedIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := util.DefaultConfig()
+	cfg.AuthEnabled = true
+	cfg.AuthAPIKeys = []string{"ci:" + auth.HashAPIKey("ci-key"), "batch:" + auth.HashAPIKey("batch-key")}
+	cfg.RateLimitEnabled = true
+	cfg.RateLimitRoutes = []string{"GET /api/v1/books/full_text_search=0.1:1"}
+
+	server, err := NewServer(cfg, &fakeStore{searchRes: &search.Response{}}, prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	// Both keys come from the same address, each one has a bucket of its own
+	for _, step := range []struct {
+		key        string
+		wantStatus int
+	}{
+		{"ci-key", http.StatusOK},
+		{"batch-key", http.StatusOK},
+		{"ci-key", http.StatusTooManyRequests},
+	} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/books/full_text_search?query_str=dune", nil)
+		req.Header.Set(auth.APIKeyHeader, step.key)
+		server.router.ServeHTTP(recorder, req)
+		require.Equal(t, step.wantStatus, recorder.Code, step.key)
+	}
+}
+
+func TestRateLimitIgnoresUntrustedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := util.DefaultConfig()
+	cfg.RateLimitEnabled = true
+	cfg.RateLimitRoutes = []string{"GET /api/v1/books/full_text_search=0.1:1"}
+
+	request := func(server *Server, forwardedFor string) int {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/books/full_text_search?query_str=dune", nil)
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		server.router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// A new X-Forwarded-For does not give a new bucket
+	server, err := NewServer(cfg, &fakeStore{searchRes: &search.Response{}}, prometheus.NewRegistry())
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, request(server, "198.51.100.1"))
+	require.Equal(t, http.StatusTooManyRequests, request(server, "198.51.100.2"))
+
+	// Behind a trusted proxy, the header tells clients apart
+	cfg.TrustedProxies = []string{"192.0.2.0/24"}
+	server, err = NewServer(cfg, &fakeStore{searchRes: &search.Response{}}, prometheus.NewRegistry())
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, request(server, "198.51.100.1"))
+	require.Equal(t, http.StatusOK, request(server, "198.51.100.2"))
+	require.Equal(t, http.StatusTooManyRequests, request(server, "198.51.100.2"))
+}
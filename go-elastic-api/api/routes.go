@@ -0,0 +1,120 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-elastic-api/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// legacyRoutesDeprecatedAt is when the routes without a version prefix were deprecated
+// in favor of /api/v1.
+var legacyRoutesDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// routeContextKey is the gin context key of the route name used for rate limits.
+// Legacy aliases set it to the route they stand for, so both share one bucket.
+const routeContextKey = "api.route"
+
+// apiRoute is a route of a versioned API.
+type apiRoute struct {
+	method  string
+	path    string
+	role    auth.Role
+	handler gin.HandlerFunc
+
+	// legacy is the path the route was served at before API versioning,
+	// empty for routes that only exist with a version prefix.
+	legacy string
+}
+
+// v1Routes lists the routes served under /api/v1.
+// A new version gets its own list, so versions can run side by side and share handlers
+// where their responses do not differ.
+func (server *Server) v1Routes() []apiRoute {
+	return []apiRoute{
+		// 1. Search by full_text_search: /books/full_text_search?query_str=random_string, or by meaning: /search/semantic?q=...,
+		// or like a book: /books/:id/similar
+		{method: http.MethodGet, path: "/books/full_text_search", role: auth.RoleReader, handler: server.fullTextSearch, legacy: "/search/full_text_search"},
+		{method: http.MethodGet, path: "/search/semantic", role: auth.RoleReader, handler: server.semanticSearch},
+		{method: http.MethodGet, path: "/books/:id/similar", role: auth.RoleReader, handler: server.similarBooks},
+
+		// 2. Filters books based on a JSON body, or on query parameters to export them all.
+		{method: http.MethodPost, path: "/books/filter", role: auth.RoleReader, handler: server.filterBooks, legacy: "/filter/books"},
+		{method: http.MethodGet, path: "/books/export", role: auth.RoleReader, handler: server.exportBooks},
+
+		// 3. Add, replace and delete books, limited to their publisher for scoped editors
+		{method: http.MethodPost, path: "/books", role: auth.RoleEditor, handler: server.createBook, legacy: "/books"},
+		{method: http.MethodPut, path: "/books/:id", role: auth.RoleEditor, handler: server.updateBook, legacy: "/books/:id"},
+		{method: http.MethodDelete, path: "/books/:id", role: auth.RoleEditor, handler: server.deleteBook, legacy: "/books/:id"},
+
+		// 4. Index management
+		{method: http.MethodPut, path: "/admin/indices/:name", role: auth.RoleAdmin, handler: server.createIndex, legacy: "/admin/indices/:name"},
+		{method: http.MethodDelete, path: "/admin/indices/:name", role: auth.RoleAdmin, handler: server.deleteIndex, legacy: "/admin/indices/:name"},
+		{method: http.MethodPost, path: "/admin/reindex", role: auth.RoleAdmin, handler: server.reindex, legacy: "/admin/reindex"},
+		{method: http.MethodPost, path: "/admin/delete_by_query", role: auth.RoleAdmin, handler: server.deleteByQuery, legacy: "/admin/delete_by_query"},
+		{method: http.MethodGet, path: "/admin/lifecycle", role: auth.RoleAdmin, handler: server.lifecycle},
+		{method: http.MethodGet, path: "/admin/synonyms", role: auth.RoleAdmin, handler: server.synonyms},
+		{method: http.MethodPut, path: "/admin/synonyms", role: auth.RoleAdmin, handler: server.putSynonyms},
+
+		// 5. Search analytics
+		{method: http.MethodGet, path: "/admin/analytics/top_queries", role: auth.RoleAdmin, handler: server.topQueries},
+		{method: http.MethodGet, path: "/admin/analytics/zero_results", role: auth.RoleAdmin, handler: server.zeroResultQueries},
+		{method: http.MethodGet, path: "/admin/analytics/ctr", role: auth.RoleAdmin, handler: server.clickThroughRate},
+		{method: http.MethodPost, path: "/events/click", role: auth.RoleReader, handler: server.recordClick},
+	}
+}
+
+// registerRoutes adds the routes of one API version to group.
+func registerRoutes(group *gin.RouterGroup, routes []apiRoute) {
+	for _, route := range routes {
+		group.Handle(route.method, route.path, requireRole(route.role), route.handler)
+	}
+}
+
+// registerLegacyRoutes adds the legacy alias of every route of the API version at prefix.
+// The middleware of the API routes run after deprecated, so rate limits count requests
+// to an alias and to its route together, and rejected requests carry the deprecation headers too.
+func registerLegacyRoutes(router *gin.Engine, prefix string, routes []apiRoute, sunset time.Time, middleware []gin.HandlerFunc) {
+	for _, route := range routes {
+		if route.legacy == "" {
+			continue
+		}
+		handlers := []gin.HandlerFunc{deprecated(route.method, prefix+route.path, sunset)}
+		handlers = append(handlers, middleware...)
+		handlers = append(handlers, requireRole(route.role), route.handler)
+		router.Handle(route.method, route.legacy, handlers...)
+	}
+}
+
+// deprecated marks the responses of a legacy alias with the Deprecation (RFC 9745)
+// and Sunset (RFC 8594) headers, and links to successor, the route that replaces it.
+func deprecated(method, successor string, sunset time.Time) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", legacyRoutesDeprecatedAt.Unix())
+	sunsetHeader := sunset.UTC().Format(http.TimeFormat)
+	return func(c *gin.Context) {
+		c.Set(routeContextKey, method+" "+successor)
+
+		link := successor
+		for _, param := range c.Params {
+			link = strings.Replace(link, ":"+param.Key, url.PathEscape(param.Value), 1)
+		}
+		c.Header("Deprecation", deprecation)
+		c.Header("Sunset", sunsetHeader)
+		c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, link))
+		c.Next()
+	}
+}
+
+// routeName returns the route of the request as "METHOD /path", with legacy aliases
+// reported as the route they stand for.
+func routeName(c *gin.Context) string {
+	if name := c.GetString(routeContextKey); name != "" {
+		return name
+	}
+	return c.Request.Method + " " + c.FullPath()
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"go-elastic-api/util"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionedRoutes(t *testing.T) {
+	server := newTestServer(t, &fakeStore{searchRes: &search.Response{}})
+
+	// 1. The versioned route carries no deprecation headers
+	recorder := doRequest(server, http.MethodGet, "/api/v1/books/full_text_search?query_str=dune")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Empty(t, recorder.Header().Get("Deprecation"))
+	require.Empty(t, recorder.Header().Get("Sunset"))
+
+	// 2. The legacy alias answers the same, marked as deprecated
+	recorder = doRequest(server, http.MethodGet, "/search/full_text_search?query_str=dune")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "@1792368000", recorder.Header().Get("Deprecation"))
+	require.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", recorder.Header().Get("Sunset"))
+	require.Equal(t, `</api/v1/books/full_text_search>; rel="successor-version"`, recorder.Header().Get("Link"))
+
+	// 3. Path parameters are filled in the successor link
+	recorder = doRequest(server, http.MethodDelete, "/books/a%20b")
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+	require.Equal(t, `</api/v1/books/a%20b>; rel="successor-version"`, recorder.Header().Get("Link"))
+}
+
+func TestLegacyRoutesDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := util.DefaultConfig()
+	cfg.LegacyRoutesEnabled = false
+	server, err := NewServer(cfg, &fakeStore{searchRes: &search.Response{}}, prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusNotFound, doRequest(server, http.MethodGet, "/search/full_text_search?query_str=dune").Code)
+	require.Equal(t, http.StatusOK, doRequest(server, http.MethodGet, "/api/v1/books/full_text_search?query_str=dune").Code)
+}
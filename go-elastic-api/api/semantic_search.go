@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/gin-gonic/gin"
+)
+
+// Modes of semantic search
+const (
+	searchModeKNN    = "knn"
+	searchModeHybrid = "hybrid"
+)
+
+// semanticSearch finds the books closest in meaning to a query, with a kNN search on their embeddings.
+// It expects a query parameter "q", and accepts "mode", knn (the default) or hybrid to fuse the
+// kNN results with the ones of full text search by reciprocal rank fusion, and optional "from"
+// and "size" parameters for pagination. In hybrid mode, q is also a query string and is checked
+// as in fullTextSearch.
+// If a parameter is missing or invalid, it returns a 400 Bad Request error.
+// If the search is successful, it returns a 200 OK response with the list of books found,
+// or 304 Not Modified when the If-None-Match header matches its ETag.
+// If there is an error during the search, it returns a 500 Internal Server Error,
+// or a 503 Service Unavailable with Retry-After when Elasticsearch is unavailable.
+// Example request: GET /api/v1/search/semantic?q=a%20boy%20raised%20by%20dragons&mode=hybrid
+// Example response: [{"id": "1", "name": "Some Book", "author": "Some Author", ...}, ...]
+func (server *Server) semanticSearch(c *gin.Context) {
+	query := c.Query("q")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, errorResponse(errors.New("q parameter is required")))
+		return
+	}
+
+	mode := c.DefaultQuery("mode", searchModeKNN)
+	switch mode {
+	case searchModeKNN:
+	case searchModeHybrid:
+		if err := server.checkQueryString(query); err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("mode must be %s or %s, got %q", searchModeKNN, searchModeHybrid, mode)))
+		return
+	}
+
+	page, err := server.parsePage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var res *search.Response
+	if mode == searchModeHybrid {
+		res, err = server.esStore.HybridSearch(c.Request.Context(), query, page)
+	} else {
+		res, err = server.esStore.SemanticSearch(c.Request.Context(), query, page)
+	}
+	if err != nil {
+		respondESError(c, fmt.Errorf("error searching for books semantically: %w", err))
+		return
+	}
+
+	books := parseBooksTyped(res)
+	server.respondCacheable(c, books)
+}
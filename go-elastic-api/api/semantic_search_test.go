@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go-elastic-api/es"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/stretchr/testify/require"
+)
+
+// semanticStore answers semantic and hybrid searches with one book each, named after the search.
+type semanticStore struct {
+	fakeStore
+	lastQuery string
+}
+
+func (s *semanticStore) SemanticSearch(ctx context.Context, query string, page es.Page) (*search.Response, error) {
+	s.lastQuery = query
+	return bookResponse("knn"), nil
+}
+
+func (s *semanticStore) HybridSearch(ctx context.Context, query string, page es.Page) (*search.Response, error) {
+	s.lastQuery = query
+	return bookResponse("hybrid"), nil
+}
+
+func bookResponse(name string) *search.Response {
+	source, _ := json.Marshal(es.Book{Name: name})
+	return &search.Response{Hits: types.HitsMetadata{Hits: []types.Hit{{Id_: ptr("1"), Source_: source}}}}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestSemanticSearch(t *testing.T) {
+	store := &semanticStore{}
+	server := newTestServer(t, store)
+
+	for mode, want := range map[string]string{"": "knn", "&mode=knn": "knn", "&mode=hybrid": "hybrid"} {
+		recorder := doRequest(server, http.MethodGet, "/api/v1/search/semantic?q=boy%20raised%20by%20dragons"+mode)
+		require.Equal(t, http.StatusOK, recorder.Code, mode)
+		var books []es.Book
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &books))
+		require.Len(t, books, 1)
+		require.Equal(t, want, books[0].Name, mode)
+		require.Equal(t, "boy raised by dragons", store.lastQuery)
+	}
+
+	require.Equal(t, http.StatusBadRequest, doRequest(server, http.MethodGet, "/api/v1/search/semantic").Code)
+	require.Equal(t, http.StatusBadRequest, doRequest(server, http.MethodGet, "/api/v1/search/semantic?q=dune&mode=fuzzy").Code)
+	// Hybrid queries are query strings, checked as in full text search
+	require.Equal(t, http.StatusBadRequest, doRequest(server, http.MethodGet, "/api/v1/search/semantic?q=*une&mode=hybrid").Code)
+	require.Equal(t, http.StatusOK, doRequest(server, http.MethodGet, "/api/v1/search/semantic?q=*une").Code)
+}
@@ -1,48 +1,168 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"go-elastic-api/util"
+	"log/slog"
+	"net/http"
+	"time"
 
+	"go-elastic-api/auth"
 	"go-elastic-api/es"
+	"go-elastic-api/telemetry"
 
 	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 type Server struct {
-	config  util.Config
-	esStore es.Client
-	router  *gin.Engine
+	config    util.Config
+	esStore   es.Client
+	router    *gin.Engine
+	readiness *readinessChecker
+	registry  *prometheus.Registry
+	metrics   *httpMetrics
+	logger    *slog.Logger
+
+	// authenticator is nil when AUTH_ENABLED is false
+	authenticator *auth.Authenticator
+	// rateLimits is nil when RATE_LIMIT_ENABLED is false
+	rateLimits *routeRateLimits
+	// legacySunset is when the routes without the /api/v1 prefix are removed
+	legacySunset time.Time
+	// events is nil when ANALYTICS_ENABLED is false
+	events EventRecorder
 }
 
-func NewServer(cfg util.Config, esStore es.Client) (*Server, error) {
+// NewServer creates the HTTP server. HTTP metrics are registered with registry,
+// which is also what the /metrics endpoint exposes.
+func NewServer(cfg util.Config, esStore es.Client, registry *prometheus.Registry) (*Server, error) {
 	server := &Server{
-		config:  cfg,
-		esStore: esStore,
+		config:    cfg,
+		esStore:   esStore,
+		readiness: newReadinessChecker(esStore, esStore.Index(), cfg.ReadinessCacheTTL),
+		registry:  registry,
+		metrics:   newHTTPMetrics(registry),
+		logger:    slog.Default(),
+	}
+
+	if cfg.AuthEnabled {
+		authenticator, err := auth.New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		server.authenticator = authenticator
+	} else {
+		server.logger.Warn("authentication is disabled, write and admin routes are open to every client")
+	}
+
+	if cfg.RateLimitEnabled {
+		rateLimits, err := newRouteRateLimits(cfg, "/api/v1", server.v1Routes())
+		if err != nil {
+			return nil, err
+		}
+		server.rateLimits = rateLimits
+	}
+
+	if cfg.LegacyRoutesEnabled {
+		sunset, err := cfg.LegacyRoutesSunsetDate()
+		if err != nil {
+			return nil, fmt.Errorf("invalid LEGACY_ROUTES_SUNSET: %w", err)
+		}
+		server.legacySunset = sunset
 	}
 
-	server.setupRouter()
+	if err := server.setupRouter(); err != nil {
+		return nil, err
+	}
 
 	return server, nil
 }
 
-func (server *Server) setupRouter() {
-	router := gin.Default()
+func (server *Server) setupRouter() error {
+	router := gin.New()
+	// Without trusted proxies the client IP is the peer address, a client cannot choose it with X-Forwarded-For
+	if err := router.SetTrustedProxies(server.config.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	router.Use(recovery(server.logger))
+	router.Use(requestID())
+	router.Use(otelgin.Middleware(telemetry.ServiceName))
+	router.Use(server.metrics.middleware())
+	router.Use(accessLog(server.logger))
+	if len(server.config.CORSAllowedOrigins) > 0 {
+		router.Use(corsMiddleware(server.config))
+	}
+	if server.config.SecurityHeadersEnabled {
+		router.Use(securityHeaders(server.config.HSTSMaxAge))
+	}
+	if server.config.CompressionEnabled {
+		router.Use(compress(server.config.CompressionMinSize))
+	}
 
 	// Define routes
-	// 1. Search by full_text_search: /search/full_text_search?query_str=random_string
-	router.GET("/search/full_text_search", server.fullTextSearch)
+	// 0. Probes, build info and API docs for the load balancer and operators
+	router.GET("/healthz", server.healthz)
+	router.GET("/readyz", server.readyz)
+	router.GET("/version", server.version)
+	router.GET("/metrics", gin.WrapH(promhttp.HandlerFor(server.registry, promhttp.HandlerOpts{})))
+	router.GET("/openapi.json", server.openAPI)
+	router.GET("/docs", server.docs)
+
+	// The API routes require credentials when authentication is enabled, the probes above never do.
+	// Clients are then limited by IP address before their credentials are checked, and per route by principal.
+	// Otherwise they are limited per route by IP address.
+	var middleware []gin.HandlerFunc
+	if server.authenticator != nil {
+		if server.rateLimits != nil {
+			middleware = append(middleware, server.rateLimits.preAuthMiddleware())
+		}
+		middleware = append(middleware, authenticate(server.authenticator, server.logger))
+		if server.rateLimits != nil {
+			middleware = append(middleware, server.rateLimits.middleware(byPrincipal))
+		}
+	} else if server.rateLimits != nil {
+		middleware = append(middleware, server.rateLimits.middleware(byClientIP))
+	}
+	apiRoutes := router.Group("/api", middleware...)
+	registerRoutes(apiRoutes.Group("/v1"), server.v1Routes())
 
-	// 2. Filters books based on a JSON body.
-	router.POST("/filter/books", server.filterBooks)
+	// The routes served before versioning stay as deprecated aliases of /api/v1 until their sunset
+	if server.config.LegacyRoutesEnabled {
+		registerLegacyRoutes(router, "/api/v1", server.v1Routes(), server.legacySunset, middleware)
+	}
 
 	server.router = router
+	return nil
 }
 
-// Start runs the HTTP server on a specific address.
-func (server *Server) Start(address string) error {
-	return server.router.Run(address)
+// Start runs the HTTP server on a specific address until ctx is done. It then stops accepting
+// connections and waits up to SHUTDOWN_TIMEOUT for the requests in flight before it returns.
+func (server *Server) Start(ctx context.Context, address string) error {
+	srv := &http.Server{Addr: address, Handler: server.router}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	// ctx is done, the requests in flight get a context of their own to finish
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), server.config.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("cannot stop server: %w", err)
+	}
+	return nil
 }
 
 func errorResponse(err error) gin.H {
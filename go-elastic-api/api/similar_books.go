@@ -0,0 +1,61 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"go-elastic-api/es"
+	"go-elastic-api/val"
+
+	"github.com/gin-gonic/gin"
+)
+
+// similarBooks recommends the books most like the one with the given id, by their description,
+// content, categories and tags, favoring the ones by the same author or publisher.
+// It accepts the optional query parameters "same_language", true to keep the books in the language
+// of the book, "release_after", a YYYY-MM-DD date to keep the books released since, and "from"
+// and "size" for pagination. The book itself is never recommended.
+// If a parameter is invalid, it returns a 400 Bad Request error.
+// If the book does not exist, it returns a 404 Not Found error.
+// If the search is successful, it returns a 200 OK response with the list of books found,
+// or 304 Not Modified when the If-None-Match header matches its ETag.
+// If there is an error during the search, it returns a 500 Internal Server Error,
+// or a 503 Service Unavailable with Retry-After when Elasticsearch is unavailable.
+// Example request: GET /api/v1/books/1/similar?same_language=true&release_after=2000-01-01
+// Example response: [{"id": "2", "name": "Some Book", "author": "Some Author", ...}, ...]
+func (server *Server) similarBooks(c *gin.Context) {
+	bookID := c.Param("id")
+
+	var filter es.SimilarFilter
+	if v, ok := c.GetQuery("same_language"); ok {
+		sameLanguage, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("same_language must be true or false, got %q", v)))
+			return
+		}
+		filter.SameLanguage = sameLanguage
+	}
+	if v := c.Query("release_after"); v != "" {
+		if !val.IsDateValid(v) {
+			c.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("release_after must be a YYYY-MM-DD date, got %q", v)))
+			return
+		}
+		filter.ReleaseAfter = v
+	}
+
+	page, err := server.parsePage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	res, err := server.esStore.SimilarBooks(c.Request.Context(), bookID, filter, page)
+	if err != nil {
+		respondESError(c, fmt.Errorf("error finding books similar to %s: %w", bookID, err))
+		return
+	}
+
+	books := parseBooksTyped(res)
+	server.respondCacheable(c, books)
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go-elastic-api/es"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/stretchr/testify/require"
+)
+
+// similarStore recommends one book for book 1 and knows no other book.
+type similarStore struct {
+	fakeStore
+	lastFilter es.SimilarFilter
+}
+
+func (s *similarStore) SimilarBooks(ctx context.Context, bookID string, filter es.SimilarFilter, page es.Page) (*search.Response, error) {
+	if bookID != "1" {
+		return nil, es.ErrBookNotFound
+	}
+	s.lastFilter = filter
+	return bookResponse("similar"), nil
+}
+
+func TestSimilarBooks(t *testing.T) {
+	store := &similarStore{}
+	server := newTestServer(t, store)
+
+	recorder := doRequest(server, http.MethodGet, "/api/v1/books/1/similar?same_language=true&release_after=2000-01-01")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var books []es.Book
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &books))
+	require.Len(t, books, 1)
+	require.Equal(t, "similar", books[0].Name)
+	require.Equal(t, es.SimilarFilter{SameLanguage: true, ReleaseAfter: "2000-01-01"}, store.lastFilter)
+
+	require.Equal(t, http.StatusOK, doRequest(server, http.MethodGet, "/api/v1/books/1/similar").Code)
+	require.Equal(t, es.SimilarFilter{}, store.lastFilter)
+
+	require.Equal(t, http.StatusNotFound, doRequest(server, http.MethodGet, "/api/v1/books/2/similar").Code)
+	require.Equal(t, http.StatusBadRequest, doRequest(server, http.MethodGet, "/api/v1/books/1/similar?same_language=maybe").Code)
+	require.Equal(t, http.StatusBadRequest, doRequest(server, http.MethodGet, "/api/v1/books/1/similar?release_after=2000").Code)
+}
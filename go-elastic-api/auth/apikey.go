@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// apiKey is a configured API key. Only the SHA-256 digest of the key is kept,
+// so a leaked config does not leak usable credentials.
+type apiKey struct {
+	id        string
+	digest    []byte
+	role      Role
+	publisher string
+}
+
+// HashAPIKey returns the hex encoded SHA-256 digest of key, as expected in AUTH_API_KEYS.
+// It is the same as `printf %s "$KEY" | sha256sum`.
+func HashAPIKey(key string) string {
+	digest := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(digest[:])
+}
+
+// parseAPIKeys parses AUTH_API_KEYS entries of the form id:sha256-hex[:role[:publisher]].
+// Keys without a role are readers.
+func parseAPIKeys(entries []string) ([]apiKey, error) {
+	keys := make([]apiKey, 0, len(entries))
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 4)
+		if len(parts) < 2 || parts[0] == "" {
+			return nil, fmt.Errorf("AUTH_API_KEYS: entry %q must be id:sha256-hex[:role[:publisher]]", entry)
+		}
+		key := apiKey{id: parts[0], role: RoleReader}
+
+		digest, err := hex.DecodeString(parts[1])
+		if err != nil || len(digest) != sha256.Size {
+			return nil, fmt.Errorf("AUTH_API_KEYS: key %q must have a hex encoded SHA-256 digest", key.id)
+		}
+		key.digest = digest
+
+		if len(parts) > 2 && parts[2] != "" {
+			if key.role, err = ParseRole(parts[2]); err != nil {
+				return nil, fmt.Errorf("AUTH_API_KEYS: key %q: %w", key.id, err)
+			}
+		}
+		if len(parts) > 3 {
+			key.publisher = parts[3]
+		}
+
+		if seen[key.id] {
+			return nil, fmt.Errorf("AUTH_API_KEYS: duplicate key id %q", key.id)
+		}
+		seen[key.id] = true
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// authenticateAPIKey compares the digest of key with every configured key in constant time.
+func (a *Authenticator) authenticateAPIKey(key string) (Principal, error) {
+	digest := sha256.Sum256([]byte(key))
+	match := -1
+	for i, k := range a.apiKeys {
+		if subtle.ConstantTimeCompare(digest[:], k.digest) == 1 {
+			match = i
+		}
+	}
+	if match < 0 {
+		return Principal{}, ErrInvalidCredentials
+	}
+	k := a.apiKeys[match]
+	return Principal{Subject: k.id, Method: MethodAPIKey, Role: k.role, Publisher: k.publisher}, nil
+}
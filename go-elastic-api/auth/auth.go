@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go-elastic-api/util"
+)
+
+// Authentication methods reported in Principal.Method.
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// APIKeyHeader is the request header carrying a static API key.
+const APIKeyHeader = "X-API-Key"
+
+var (
+	// ErrMissingCredentials is returned when a request carries neither an API key nor a bearer token.
+	ErrMissingCredentials = errors.New("missing credentials")
+	// ErrInvalidCredentials is returned when the credentials of a request are unknown, expired or malformed.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Subject string `json:"subject"` // API key ID or sub claim of the JWT
+	Method  string `json:"method"`  // MethodAPIKey or MethodJWT
+	Role    Role   `json:"role"`
+
+	// Publisher restricts the books the principal may modify to those of one publisher.
+	// It is empty for principals that may modify every book.
+	Publisher string `json:"publisher,omitempty"`
+}
+
+// Can reports whether the principal has at least the given role.
+func (p Principal) Can(role Role) bool {
+	return p.Role >= role
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal carried by ctx.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Authenticator verifies the credentials of incoming requests.
+type Authenticator struct {
+	apiKeys []apiKey
+	jwt     *jwtVerifier
+}
+
+// New creates an Authenticator from the AUTH_* settings of cfg.
+func New(cfg util.Config) (*Authenticator, error) {
+	apiKeys, err := parseAPIKeys(cfg.AuthAPIKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Authenticator{apiKeys: apiKeys}
+	if cfg.AuthJWTHS256Secret != "" || cfg.AuthJWKSFile != "" {
+		a.jwt, err = newJWTVerifier(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// Authenticate returns the principal of r. An API key is read from the X-API-Key header,
+// a JWT from the Authorization header with the Bearer scheme.
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return a.authenticateAPIKey(key)
+	}
+
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return Principal{}, ErrMissingCredentials
+	}
+	if a.jwt == nil {
+		return Principal{}, ErrInvalidCredentials
+	}
+	return a.jwt.verify(strings.TrimSpace(token))
+}
@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-elastic-api/util"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+const hsSecret = "test-secret-with-enough-entropy"
+
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	jwks := map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	data, err := json.Marshal(jwks)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func newRequest(header, value string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/search/full_text_search", nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	return req
+}
+
+func TestAuthenticate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	cfg := util.DefaultConfig()
+	cfg.AuthAPIKeys = []string{
+		"ci:" + HashAPIKey("ci-key"),
+		"acme-editor:" + HashAPIKey("acme-key") + ":editor:Acme",
+	}
+	cfg.AuthJWTHS256Secret = hsSecret
+	cfg.AuthJWKSFile = writeJWKS(t, "key-1", &rsaKey.PublicKey)
+	cfg.AuthJWTIssuer = "https://issuer.example"
+	cfg.AuthJWTAudience = "books-api"
+
+	authenticator, err := New(cfg)
+	require.NoError(t, err)
+
+	claims := func(modify func(*jwt.RegisteredClaims)) jwt.RegisteredClaims {
+		c := jwt.RegisteredClaims{
+			Subject:   "alice",
+			Issuer:    "https://issuer.example",
+			Audience:  jwt.ClaimStrings{"books-api"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}
+		if modify != nil {
+			modify(&c)
+		}
+		return c
+	}
+	signHS := func(c jwt.RegisteredClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte(hsSecret))
+		require.NoError(t, err)
+		return "Bearer " + token
+	}
+	signHSWithRoles := func(extra jwt.MapClaims) string {
+		c := jwt.MapClaims{
+			"sub": "bob",
+			"iss": "https://issuer.example",
+			"aud": "books-api",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+		for k, v := range extra {
+			c[k] = v
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte(hsSecret))
+		require.NoError(t, err)
+		return "Bearer " + token
+	}
+	signRS := func(key *rsa.PrivateKey, kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims(nil))
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		return "Bearer " + signed
+	}
+
+	testCases := []struct {
+		name          string
+		header, value string
+		wantPrincipal Principal
+		wantErr       error
+	}{
+		{
+			name:          "APIKey",
+			header:        APIKeyHeader,
+			value:         "ci-key",
+			wantPrincipal: Principal{Subject: "ci", Method: MethodAPIKey, Role: RoleReader},
+		},
+		{
+			name:          "APIKeyWithRoleAndPublisher",
+			header:        APIKeyHeader,
+			value:         "acme-key",
+			wantPrincipal: Principal{Subject: "acme-editor", Method: MethodAPIKey, Role: RoleEditor, Publisher: "Acme"},
+		},
+		{
+			name:    "UnknownAPIKey",
+			header:  APIKeyHeader,
+			value:   "guess",
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:          "HS256",
+			header:        "Authorization",
+			value:         signHS(claims(nil)),
+			wantPrincipal: Principal{Subject: "alice", Method: MethodJWT, Role: RoleReader},
+		},
+		{
+			name:          "RoleClaim",
+			header:        "Authorization",
+			value:         signHSWithRoles(jwt.MapClaims{"role": "editor", "publisher": "Acme"}),
+			wantPrincipal: Principal{Subject: "bob", Method: MethodJWT, Role: RoleEditor, Publisher: "Acme"},
+		},
+		{
+			name:          "RolesClaimTakesHighest",
+			header:        "Authorization",
+			value:         signHSWithRoles(jwt.MapClaims{"roles": []string{"reader", "billing", "admin"}}),
+			wantPrincipal: Principal{Subject: "bob", Method: MethodJWT, Role: RoleAdmin},
+		},
+		{
+			name:          "RS256",
+			header:        "Authorization",
+			value:         signRS(rsaKey, "key-1"),
+			wantPrincipal: Principal{Subject: "alice", Method: MethodJWT, Role: RoleReader},
+		},
+		{
+			name:    "RS256UnknownKey",
+			header:  "Authorization",
+			value:   signRS(otherKey, "key-1"),
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:    "Expired",
+			header:  "Authorization",
+			value:   signHS(claims(func(c *jwt.RegisteredClaims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour)) })),
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:    "WrongIssuer",
+			header:  "Authorization",
+			value:   signHS(claims(func(c *jwt.RegisteredClaims) { c.Issuer = "https://evil.example" })),
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:    "WrongAudience",
+			header:  "Authorization",
+			value:   signHS(claims(func(c *jwt.RegisteredClaims) { c.Audience = jwt.ClaimStrings{"other"} })),
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:    "NoExpiry",
+			header:  "Authorization",
+			value:   signHS(claims(func(c *jwt.RegisteredClaims) { c.ExpiresAt = nil })),
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:    "BasicScheme",
+			header:  "Authorization",
+			value:   "Basic YWxpY2U6c2VjcmV0",
+			wantErr: ErrMissingCredentials,
+		},
+		{
+			name:    "NoCredentials",
+			wantErr: ErrMissingCredentials,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			principal, err := authenticator.Authenticate(newRequest(tc.header, tc.value))
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.wantPrincipal, principal)
+		})
+	}
+}
+
+func TestAuthenticateRejectsAlgorithmWithoutKey(t *testing.T) {
+	cfg := util.DefaultConfig()
+	cfg.AuthAPIKeys = []string{"ci:" + HashAPIKey("ci-key")}
+
+	authenticator, err := New(cfg)
+	require.NoError(t, err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   "alice",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString([]byte(""))
+	require.NoError(t, err)
+
+	_, err = authenticator.Authenticate(newRequest("Authorization", "Bearer "+token))
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	_, err := parseAPIKeys([]string{"ci:" + HashAPIKey("a"), "ci:" + HashAPIKey("b")})
+	require.ErrorContains(t, err, "duplicate key id")
+
+	_, err = parseAPIKeys([]string{"ci:not-hex"})
+	require.ErrorContains(t, err, "SHA-256 digest")
+
+	_, err = parseAPIKeys([]string{HashAPIKey("a")})
+	require.ErrorContains(t, err, "must be id:sha256-hex")
+
+	_, err = parseAPIKeys([]string{"ci:" + HashAPIKey("a") + ":owner"})
+	require.ErrorContains(t, err, "unknown role")
+}
@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"time"
+
+	"go-elastic-api/util"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtLeeway tolerates clock skew between the token issuer and this service.
+const jwtLeeway = 30 * time.Second
+
+// jwtVerifier verifies bearer tokens signed with HS256 using a shared secret,
+// or with RS256 using the public keys of a local JWKS file.
+type jwtVerifier struct {
+	secret  []byte
+	rsaKeys map[string]*rsa.PublicKey
+	parser  *jwt.Parser
+}
+
+func newJWTVerifier(cfg util.Config) (*jwtVerifier, error) {
+	v := &jwtVerifier{}
+
+	// Only the algorithms with a configured key are accepted, so an RS256 public key
+	// can never be used as an HS256 secret.
+	var methods []string
+	if cfg.AuthJWTHS256Secret != "" {
+		v.secret = []byte(cfg.AuthJWTHS256Secret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.AuthJWKSFile != "" {
+		keys, err := loadJWKS(cfg.AuthJWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.rsaKeys = keys
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(jwtLeeway),
+	}
+	if cfg.AuthJWTIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.AuthJWTIssuer))
+	}
+	if cfg.AuthJWTAudience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.AuthJWTAudience))
+	}
+	v.parser = jwt.NewParser(opts...)
+
+	return v, nil
+}
+
+// claims are the JWT claims read by the verifier. The role is taken from the role claim,
+// or is the highest role listed in the roles claim; tokens without either are readers.
+type claims struct {
+	jwt.RegisteredClaims
+	Role      string   `json:"role,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	Publisher string   `json:"publisher,omitempty"`
+}
+
+func (v *jwtVerifier) verify(tokenString string) (Principal, error) {
+	var c claims
+	if _, err := v.parser.ParseWithClaims(tokenString, &c, v.key); err != nil {
+		return Principal{}, fmt.Errorf("%w: %s", ErrInvalidCredentials, err)
+	}
+	if c.Subject == "" {
+		return Principal{}, fmt.Errorf("%w: token has no sub claim", ErrInvalidCredentials)
+	}
+
+	principal := Principal{Subject: c.Subject, Method: MethodJWT, Role: RoleReader, Publisher: c.Publisher}
+	// Cloned, so the role claim is never appended into the backing array of the roles claim
+	names := slices.Clone(c.Roles)
+	if c.Role != "" {
+		names = append(names, c.Role)
+	}
+	for _, name := range names {
+		// Roles of other applications may share the claim, unknown names are ignored
+		if role, err := ParseRole(name); err == nil && role > principal.Role {
+			principal.Role = role
+		}
+	}
+	return principal, nil
+}
+
+// key returns the verification key for the algorithm and kid header of token.
+func (v *jwtVerifier) key(token *jwt.Token) (any, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return v.secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := v.rsaKeys[kid]; ok {
+			return key, nil
+		}
+		// Tokens without kid are accepted when the JWKS holds a single key
+		if kid == "" && len(v.rsaKeys) == 1 {
+			for _, key := range v.rsaKeys {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	default:
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+}
+
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Alg string `json:"alg"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// loadJWKS reads the RSA signing keys of a JWKS file, indexed by kid.
+// Keys of other types or meant for encryption are skipped.
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read JWKS file: %w", err)
+	}
+	var set jwks
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("cannot parse JWKS file %s: %w", path, err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != "RS256") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if err := errors.Join(errN, errE); err != nil || len(n) == 0 || len(e) == 0 {
+			return nil, fmt.Errorf("JWKS key %q has an invalid modulus or exponent", k.Kid)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("JWKS key %q has an invalid exponent", k.Kid)
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS file %s has no RS256 signing key", path)
+	}
+	return keys, nil
+}
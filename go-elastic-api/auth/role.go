@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Role is the permission level of a principal. Roles are ordered:
+// an editor can do everything a reader can, and an admin everything an editor can.
+type Role int
+
+const (
+	RoleNone Role = iota
+	// RoleReader can search and read books.
+	RoleReader
+	// RoleEditor can also add, update and delete books.
+	RoleEditor
+	// RoleAdmin can also manage indices, reindex and delete by query.
+	RoleAdmin
+)
+
+var roleNames = map[Role]string{
+	RoleNone:   "none",
+	RoleReader: "reader",
+	RoleEditor: "editor",
+	RoleAdmin:  "admin",
+}
+
+// ParseRole parses a role name: reader, editor or admin.
+func ParseRole(s string) (Role, error) {
+	for role, name := range roleNames {
+		if role != RoleNone && strings.EqualFold(s, name) {
+			return role, nil
+		}
+	}
+	return RoleNone, fmt.Errorf("unknown role %q: must be reader, editor or admin", s)
+}
+
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("Role(%d)", int(r))
+}
+
+func (r Role) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
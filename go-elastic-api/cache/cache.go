@@ -0,0 +1,16 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Store holds cached values by key. Implementations are safe for concurrent use.
+type Store interface {
+	// Get returns the value of key, and false if it is missing or expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Purge drops every entry.
+	Purge(ctx context.Context) error
+}
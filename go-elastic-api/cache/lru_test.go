@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	require.NoError(t, c.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, c.Set(ctx, "b", []byte("2"), time.Minute))
+
+	// 1. Reading a makes b the least recently used entry, which is evicted by c
+	value, ok, err := c.Get(ctx, "a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []byte("1"), value)
+
+	require.NoError(t, c.Set(ctx, "c", []byte("3"), time.Minute))
+	_, ok, _ = c.Get(ctx, "b")
+	require.False(t, ok)
+	require.Equal(t, 2, c.Len())
+
+	// 2. Entries expire after their TTL
+	require.NoError(t, c.Set(ctx, "a", []byte("1"), time.Second))
+	now = now.Add(time.Second)
+	_, ok, _ = c.Get(ctx, "a")
+	require.False(t, ok)
+	_, ok, _ = c.Get(ctx, "c")
+	require.True(t, ok)
+
+	// 3. Purge drops everything
+	require.NoError(t, c.Purge(ctx))
+	_, ok, _ = c.Get(ctx, "c")
+	require.False(t, ok)
+	require.Zero(t, c.Len())
+}
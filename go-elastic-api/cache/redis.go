@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is a Store backed by Redis or a compatible server, shared by every instance of the service.
+//
+// Purge does not delete keys, it increments a generation number that is part of every key,
+// so the entries of older generations are no longer read and expire on their own.
+type Redis struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedis creates a Store using client. Keys are prefixed with prefix.
+func NewRedis(client redis.UniversalClient, prefix string) *Redis {
+	return &Redis{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// NewRedisFromURL connects to the server of a redis:// or rediss:// URL.
+func NewRedisFromURL(url, prefix string) (*Redis, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	return NewRedis(redis.NewClient(opts), prefix), nil
+}
+
+func (c *Redis) generationKey() string {
+	return c.prefix + ":generation"
+}
+
+func (c *Redis) key(ctx context.Context, key string) (string, error) {
+	generation, err := c.client.Get(ctx, c.generationKey()).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%d:%s", c.prefix, generation, key), nil
+}
+
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	k, err := c.key(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	value, err := c.client.Get(ctx, k).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	k, err := c.key(ctx, key)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, k, value, ttl).Err()
+}
+
+func (c *Redis) Purge(ctx context.Context) error {
+	return c.client.Incr(ctx, c.generationKey()).Err()
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func (a *app) aliasCommand() *cobra.Command {
+	alias := &cobra.Command{
+		Use:   "alias",
+		Short: "Manage the alias books are served through",
+	}
+
+	var name string
+	swap := &cobra.Command{
+		Use:   "swap <index>",
+		Short: "Point the alias to an index, atomically removing it from the previous ones",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if name == "" {
+				name = a.cfg.BooksAlias
+			}
+			if name == "" {
+				return errors.New("no alias given with --alias or BOOKS_ALIAS")
+			}
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			previous, err := client.SwapAlias(cmd.Context(), name, args[0])
+			if err != nil {
+				return fmt.Errorf("cannot point alias %s to %s: %w", name, args[0], err)
+			}
+			if len(previous) == 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "alias %s now points to %s\n", name, args[0])
+			} else {
+				fmt.Fprintf(cmd.OutOrStdout(), "alias %s now points to %s instead of %s\n", name, args[0], strings.Join(previous, ", "))
+			}
+			return nil
+		},
+	}
+	swap.Flags().StringVar(&name, "alias", "", "alias to point, BOOKS_ALIAS by default")
+
+	alias.AddCommand(swap)
+	return alias
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+
+	"go-elastic-api/es"
+
+	"github.com/spf13/cobra"
+)
+
+func (a *app) embeddingsCommand() *cobra.Command {
+	embeddings := &cobra.Command{
+		Use:   "embeddings",
+		Short: "Manage the embeddings of books, which semantic search compares",
+	}
+
+	var batchSize int
+	update := &cobra.Command{
+		Use:   "update",
+		Short: "Embed every book again with the configured EMBEDDER",
+		Long: `Embed every book again with the configured EMBEDDER and write its embedding. Books written since semantic search
+exists are embedded as they are written; run this for the books written before, or after changing EMBEDDER,
+as the vectors of two embedders cannot be compared. Only the embedding of the books is written.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			embedded, failed := 0, 0
+			err = client.ScanBooks(cmd.Context(), nil, batchSize, func(books []es.Book) error {
+				res, err := client.UpdateEmbeddings(cmd.Context(), books)
+				if err != nil {
+					return err
+				}
+				for _, item := range res.Items {
+					for _, result := range item {
+						if result.Error != nil {
+							failed++
+						}
+					}
+				}
+				embedded += len(books)
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("cannot embed books: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "embedded %d books of %s, %d failed\n", embedded-failed, client.Index(), failed)
+			if failed > 0 {
+				return fmt.Errorf("%d books were not embedded", failed)
+			}
+			return nil
+		},
+	}
+	update.Flags().IntVar(&batchSize, "batch-size", 100, "books embedded and updated per request")
+
+	embeddings.AddCommand(update)
+	return embeddings
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"go-elastic-api/es"
+	"go-elastic-api/exporter"
+
+	"github.com/spf13/cobra"
+)
+
+func (a *app) exportCommand() *cobra.Command {
+	var (
+		output     string
+		format     string
+		fields     string
+		compressed bool
+		batchSize  int
+		author     string
+		publisher  string
+		categories []string
+		language   string
+		after      string
+	)
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write the books matching a filter as NDJSON, which import reads back, or CSV",
+		Long: `Write the books matching a filter, all of them by default, as NDJSON or CSV.
+Books are read from a point in time page after page with search_after, and written as they come.
+--fields chooses the columns among ` + strings.Join(exporter.Columns, ",") + `;
+CSV leaves out the content by default, and joins categories and tags with "` + exporter.ListSeparator + `".
+The output is compressed with gzip with --gzip, or when its name ends with .gz.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f := exporter.Format(format)
+			if !slices.Contains(exporter.Formats, f) {
+				return fmt.Errorf("unknown format %q, expected one of %v", f, exporter.Formats)
+			}
+			columns, err := exporter.ParseColumns(fields, f)
+			if err != nil {
+				return err
+			}
+
+			var w io.Writer = cmd.OutOrStdout()
+			if output != "-" {
+				file, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				w = file
+			}
+			buf := bufio.NewWriter(w)
+			w = buf
+			var gz *gzip.Writer
+			if compressed || strings.HasSuffix(output, ".gz") {
+				gz = gzip.NewWriter(buf)
+				w = gz
+			}
+			writer, err := exporter.NewWriter(f, w, columns)
+			if err != nil {
+				return err
+			}
+
+			filter := make(map[string]any)
+			for key, value := range map[string]string{"author": author, "publisher": publisher, "language": language, "release_after": after} {
+				if value != "" {
+					filter[key] = value
+				}
+			}
+			if len(categories) > 0 {
+				filter["categories"] = categories
+			}
+
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			exported := 0
+			err = client.ScanBooks(cmd.Context(), filter, batchSize, func(books []es.Book) error {
+				for _, book := range books {
+					if err := writer.Write(book); err != nil {
+						return err
+					}
+				}
+				exported += len(books)
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("cannot export books: %w", err)
+			}
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			if gz != nil {
+				if err := gz.Close(); err != nil {
+					return err
+				}
+			}
+			if err := buf.Flush(); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "exported %d books from %s\n", exported, client.Index())
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "-", "file to write, - for standard output")
+	cmd.Flags().StringVar(&format, "format", string(exporter.FormatNDJSON), fmt.Sprintf("format of the output, one of %v", exporter.Formats))
+	cmd.Flags().StringVar(&fields, "fields", "", "comma separated columns to export, all of them by default")
+	cmd.Flags().BoolVar(&compressed, "gzip", false, "compress the output with gzip")
+	cmd.Flags().IntVar(&batchSize, "batch-size", 1000, "books read per search request")
+	cmd.Flags().StringVar(&author, "author", "", "export the books of this author")
+	cmd.Flags().StringVar(&publisher, "publisher", "", "export the books of this publisher")
+	cmd.Flags().StringSliceVar(&categories, "category", nil, "export the books in any of these categories, repeatable")
+	cmd.Flags().StringVar(&language, "language", "", "export the books in this language, an ISO 639-1 code")
+	cmd.Flags().StringVar(&after, "release-after", "", "export the books released on or after this date, YYYY-MM-DD")
+	return cmd
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"go-elastic-api/importer"
+
+	"github.com/spf13/cobra"
+)
+
+func (a *app) importCommand() *cobra.Command {
+	var (
+		format      string
+		mappingPath string
+		rejectsPath string
+		batchSize   int
+	)
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Index the books of a CSV, JSON Lines, ONIX or MARCXML file, - reads standard input",
+		Long: `Index the books of a catalog file: CSV with a header row, JSON Lines, ONIX for Books 2.1 or 3.0
+with reference tags, or MARCXML. The format is told from the file extension unless --format is set.
+
+Fields are mapped to books by the --mapping file, or by the default mapping of the format:
+CSV and JSON Lines files use the JSON names of the book fields, as written by the export command.
+ISBNs are checked and converted to ISBN-13, and become the ID of books without one.
+Dates are converted to YYYY-MM-DD.
+
+Records that cannot be imported, because a required field is missing, a value is invalid,
+the book appeared earlier in the file or Elasticsearch refused it, are written to --rejects
+with the reason, and the import goes on. Books are sent in bulk requests of --batch-size books
+into the books index or alias; a book with the ID of an existing one replaces it.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var r io.Reader = cmd.InOrStdin()
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				r = f
+			}
+
+			f := importer.Format(format)
+			if f == "" {
+				if args[0] == "-" {
+					return fmt.Errorf("--format is required to read standard input, one of %v", importer.Formats)
+				}
+				var err error
+				if f, r, err = importer.DetectFormat(args[0], r); err != nil {
+					return err
+				}
+			}
+			reader, err := importer.NewReader(f, r)
+			if err != nil {
+				return err
+			}
+
+			mapping := importer.DefaultMapping(f)
+			if mappingPath != "" {
+				if mapping, err = importer.LoadMapping(mappingPath); err != nil {
+					return err
+				}
+			}
+			opts := []importer.Option{importer.WithBatchSize(batchSize)}
+			if rejectsPath != "" {
+				rejects, err := os.Create(rejectsPath)
+				if err != nil {
+					return err
+				}
+				defer rejects.Close()
+				opts = append(opts, importer.WithRejects(rejects))
+			}
+
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			im, err := importer.New(client, mapping, opts...)
+			if err != nil {
+				return err
+			}
+			stats, err := im.Run(cmd.Context(), reader)
+			fmt.Fprintf(cmd.OutOrStdout(), "read %d records, indexed %d books into %s, %d rejected\n",
+				stats.Read, stats.Indexed, client.Index(), stats.Rejected)
+			if err != nil {
+				return err
+			}
+			if stats.Rejected > 0 && rejectsPath == "" {
+				return fmt.Errorf("%d records were rejected, set --rejects to keep them", stats.Rejected)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "", fmt.Sprintf("format of the file, one of %v, told from the extension by default", importer.Formats))
+	cmd.Flags().StringVar(&mappingPath, "mapping", "", "JSON file mapping the fields of the records to book fields")
+	cmd.Flags().StringVar(&rejectsPath, "rejects", "", "file the rejected records are written to as JSON lines, with the reason")
+	cmd.Flags().IntVar(&batchSize, "batch-size", 500, "books per bulk request")
+	return cmd
+}
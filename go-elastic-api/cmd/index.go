@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func (a *app) indexCommand() *cobra.Command {
+	index := &cobra.Command{
+		Use:   "index",
+		Short: "Create, delete and inspect books indices",
+	}
+
+	create := &cobra.Command{
+		Use:   "create [name]",
+		Short: "Create an index with the books settings and mappings, BOOKS_INDEX by default",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			name := a.indexArg(args)
+			if _, err := client.CreateIndex(cmd.Context(), name); err != nil {
+				return fmt.Errorf("cannot create index %s: %w", name, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "created index %s\n", name)
+			return nil
+		},
+	}
+
+	var yes bool
+	remove := &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete an index and all its books",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !yes {
+				return errors.New("deleting an index cannot be undone, confirm with --yes")
+			}
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			if _, err := client.DeleteIndex(cmd.Context(), args[0]); err != nil {
+				return fmt.Errorf("cannot delete index %s: %w", args[0], err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "deleted index %s\n", args[0])
+			return nil
+		},
+	}
+	remove.Flags().BoolVar(&yes, "yes", false, "confirm the deletion")
+
+	status := &cobra.Command{
+		Use:   "status [name]",
+		Short: "Show health, document count and size of indices, the books index or alias by default",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			name := a.nameArg(args)
+			records, err := client.IndexStatus(cmd.Context(), name)
+			if err != nil {
+				return fmt.Errorf("cannot get status of %s: %w", name, err)
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "INDEX\tHEALTH\tSTATUS\tPRIMARIES\tREPLICAS\tDOCS\tSIZE")
+			for _, r := range records {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					str(r.Index), str(r.Health), str(r.Status), str(r.Pri), str(r.Rep), str(r.DocsCount), str(r.StoreSize))
+			}
+			return w.Flush()
+		},
+	}
+
+	index.AddCommand(create, remove, status)
+	return index
+}
+
+// indexArg returns the index named on the command line, or BOOKS_INDEX.
+// Unlike nameArg it never falls back to the alias, which cannot be created as an index.
+func (a *app) indexArg(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return a.cfg.BooksIndex
+}
+
+// str dereferences the optional strings of the cat APIs.
+func str(s *string) string {
+	if s == nil {
+		return "-"
+	}
+	return *s
+}
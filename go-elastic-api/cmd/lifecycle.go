@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"go-elastic-api/es"
+
+	"github.com/spf13/cobra"
+)
+
+func (a *app) lifecycleCommand() *cobra.Command {
+	lifecycle := &cobra.Command{
+		Use:   "lifecycle",
+		Short: "Manage the events indices written through EVENTS_ALIAS",
+		Long: `Manage the events indices written through the rollover alias EVENTS_ALIAS. The write index rolls over
+after EVENTS_ROLLOVER_MAX_AGE or once a primary shard reaches EVENTS_ROLLOVER_MAX_SIZE. Rolled over indices
+are shrunk to EVENTS_SHRINK_SHARDS shards and force merged to EVENTS_FORCE_MERGE_SEGMENTS segments after
+EVENTS_WARM_AFTER, and deleted after EVENTS_DELETE_AFTER.`,
+	}
+
+	setup := &cobra.Command{
+		Use:   "setup",
+		Short: "Put the lifecycle policy and index template, and create the first index behind EVENTS_ALIAS",
+		Long: `Put the lifecycle policy and the index template of the events indices, and create the first of them
+as the write index of EVENTS_ALIAS unless the alias exists. Run it again after changing the EVENTS_* settings:
+indices already rolled over follow the new policy from their next phase on.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			created, err := a.setupEvents(cmd.Context(), client)
+			if err != nil {
+				return err
+			}
+			alias, policy := a.cfg.EventsAlias, es.LifecyclePolicyName(a.cfg.EventsAlias)
+			if created {
+				fmt.Fprintf(cmd.OutOrStdout(), "created %s-000001 as the write index of %s, managed by %s\n", alias, alias, policy)
+			} else {
+				fmt.Fprintf(cmd.OutOrStdout(), "updated %s, %s already exists\n", policy, alias)
+			}
+			return nil
+		},
+	}
+
+	status := &cobra.Command{
+		Use:   "status",
+		Short: "Show the lifecycle phase and health of the indices behind EVENTS_ALIAS",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			indices, err := client.ManagedIndices(cmd.Context(), a.cfg.EventsAlias)
+			if err != nil {
+				return fmt.Errorf("cannot get lifecycle of %s: %w", a.cfg.EventsAlias, err)
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "INDEX\tWRITE\tPHASE\tACTION\tSTEP\tAGE\tHEALTH\tDOCS\tSIZE")
+			for _, index := range indices {
+				step := index.Step
+				if index.Error != "" {
+					step += ": " + index.Error
+				}
+				fmt.Fprintf(w, "%s\t%t\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", index.Index, index.WriteIndex, dash(index.Phase),
+					dash(index.Action), dash(step), dash(index.Age), index.Health, index.DocsCount, index.StoreSize)
+			}
+			return w.Flush()
+		},
+	}
+
+	lifecycle.AddCommand(setup, status)
+	return lifecycle
+}
+
+// setupEvents puts the lifecycle policy and the index template of the events indices,
+// and creates the first of them unless EVENTS_ALIAS exists, which it reports.
+func (a *app) setupEvents(ctx context.Context, client es.Client) (bool, error) {
+	alias := a.cfg.EventsAlias
+	policy := es.LifecyclePolicyName(alias)
+	if _, err := client.PutLifecyclePolicy(ctx, policy, es.EventsLifecyclePolicy(a.cfg)); err != nil {
+		return false, fmt.Errorf("cannot put lifecycle policy %s: %w", policy, err)
+	}
+	created, err := client.BootstrapRolloverAlias(ctx, alias, policy)
+	if err != nil {
+		return false, fmt.Errorf("cannot bootstrap alias %s: %w", alias, err)
+	}
+	return created, nil
+}
+
+// dash stands for an empty column of a listing.
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
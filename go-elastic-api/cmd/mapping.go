@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"go-elastic-api/es"
+
+	"github.com/spf13/cobra"
+)
+
+func (a *app) mappingCommand() *cobra.Command {
+	mapping := &cobra.Command{
+		Use:   "mapping",
+		Short: "Inspect the mappings of books indices",
+	}
+
+	show := &cobra.Command{
+		Use:   "show [name]",
+		Short: "Print the mappings of an index, the books index or alias by default",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			name := a.nameArg(args)
+			mappings, err := client.IndexMapping(cmd.Context(), name)
+			if err != nil {
+				return fmt.Errorf("cannot get mapping of %s: %w", name, err)
+			}
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(mappings)
+		},
+	}
+
+	diff := &cobra.Command{
+		Use:   "diff [name]",
+		Short: "Compare the mappings of an index with the ones this version creates",
+		Long: `Compare the mappings of an index with the ones this version creates.
+Lines starting with - are missing from the index, + are only in the index and ~ differ.
+The command fails when any index differs.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			want, err := es.BooksMapping()
+			if err != nil {
+				return err
+			}
+			name := a.nameArg(args)
+			mappings, err := client.IndexMapping(cmd.Context(), name)
+			if err != nil {
+				return fmt.Errorf("cannot get mapping of %s: %w", name, err)
+			}
+
+			indices := make([]string, 0, len(mappings))
+			for index := range mappings {
+				indices = append(indices, index)
+			}
+			slices.Sort(indices)
+
+			var differing []string
+			for _, index := range indices {
+				lines, err := es.DiffMapping(want, mappings[index])
+				if err != nil {
+					return err
+				}
+				if len(lines) == 0 {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s: up to date\n", index)
+					continue
+				}
+				differing = append(differing, index)
+				fmt.Fprintf(cmd.OutOrStdout(), "%s:\n", index)
+				for _, line := range lines {
+					fmt.Fprintf(cmd.OutOrStdout(), "  %s\n", line)
+				}
+			}
+			if len(differing) > 0 {
+				return fmt.Errorf("mappings of %v differ", differing)
+			}
+			return nil
+		},
+	}
+
+	mapping.AddCommand(show, diff)
+	return mapping
+}
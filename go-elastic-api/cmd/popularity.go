@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+
+	"go-elastic-api/analytics"
+
+	"github.com/spf13/cobra"
+)
+
+func (a *app) popularityCommand() *cobra.Command {
+	popularity := &cobra.Command{
+		Use:   "popularity",
+		Short: "Manage the popularity of books, which full text search blends into its ranking",
+	}
+
+	update := &cobra.Command{
+		Use:   "update",
+		Short: "Write the clicks of every book in the last POPULARITY_WINDOW into its popularity",
+		Long: `Count the clicks recorded to EVENTS_ALIAS in the last POPULARITY_WINDOW and write them into the popularity
+of the books. Books without clicks lose their popularity. With ANALYTICS_ENABLED and POPULARITY_UPDATER_ENABLED,
+serve does the same every POPULARITY_UPDATE_INTERVAL; with several replicas, disable the updater on all but one,
+or on all of them and run this command from a single scheduled job.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			res, err := analytics.NewPopularityUpdater(client, a.cfg.EventsAlias, a.cfg.PopularityWindow, 0).Update(cmd.Context())
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "updated the popularity of %d books, %d clicked books no longer exist, %d failed\n",
+				res.Books, res.Missing, res.Failed)
+			if res.Failed > 0 {
+				return fmt.Errorf("%d books were not updated", res.Failed)
+			}
+			return nil
+		},
+	}
+
+	popularity.AddCommand(update)
+	return popularity
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// taskPollInterval is how often reindex --wait checks the progress of the task.
+const taskPollInterval = 2 * time.Second
+
+func (a *app) reindexCommand() *cobra.Command {
+	var wait bool
+	cmd := &cobra.Command{
+		Use:   "reindex <source> <dest>",
+		Short: "Copy all books of one index into another, e.g. after a mapping change",
+		Long: `Copy all books of one index into another, e.g. after a mapping change.
+The copy runs as a task in the cluster; without --wait the command prints its ID and returns.
+Create dest with "index create" first, and point the alias to it with "alias swap" once done.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			res, err := client.Reindex(cmd.Context(), args[0], args[1])
+			if err != nil {
+				return fmt.Errorf("cannot reindex %s into %s: %w", args[0], args[1], err)
+			}
+			if res.Task == nil {
+				return fmt.Errorf("reindex of %s into %s returned no task", args[0], args[1])
+			}
+			task := fmt.Sprint(res.Task)
+			fmt.Fprintf(cmd.OutOrStdout(), "started task %s\n", task)
+			if !wait {
+				return nil
+			}
+
+			ticker := time.NewTicker(taskPollInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-cmd.Context().Done():
+					return fmt.Errorf("stopped waiting, task %s keeps running: %w", task, cmd.Context().Err())
+				case <-ticker.C:
+				}
+
+				status, err := client.TaskStatus(cmd.Context(), task)
+				if err != nil {
+					return fmt.Errorf("cannot get status of task %s: %w", task, err)
+				}
+				var progress struct {
+					Total   int64 `json:"total"`
+					Created int64 `json:"created"`
+					Updated int64 `json:"updated"`
+				}
+				_ = json.Unmarshal(status.Task.Status, &progress)
+				fmt.Fprintf(cmd.OutOrStdout(), "%d/%d books copied\n", progress.Created+progress.Updated, progress.Total)
+
+				if status.Completed {
+					if status.Error != nil {
+						return fmt.Errorf("task %s failed: %s", task, str(status.Error.Reason))
+					}
+					return nil
+				}
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&wait, "wait", false, "wait until all books are copied")
+	return cmd
+}
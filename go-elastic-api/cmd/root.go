@@ -0,0 +1,117 @@
+// Package cmd implements the command line interface: the HTTP server
+// and the commands operators use to manage the books indices.
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go-elastic-api/embedding"
+	"go-elastic-api/es"
+	"go-elastic-api/logging"
+	"go-elastic-api/util"
+
+	"github.com/spf13/cobra"
+)
+
+// app holds what the commands share: the configuration, loaded once flags are parsed.
+type app struct {
+	configPath string
+	cfg        util.Config
+}
+
+// Execute runs the command line interface and exits with status 1 when the command fails.
+func Execute() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := NewRootCommand().ExecuteContext(ctx); err != nil {
+		stop()
+		os.Exit(1)
+	}
+}
+
+// NewRootCommand creates the go-elastic-api command with all its subcommands.
+// Every configuration key is also a flag, e.g. --books-index for BOOKS_INDEX.
+func NewRootCommand() *cobra.Command {
+	a := &app{}
+	serve := a.serveCommand()
+
+	root := &cobra.Command{
+		Use:   "go-elastic-api",
+		Short: "Search and manage books stored in Elasticsearch",
+		// Without a subcommand the server starts, as it did before the subcommands existed
+		Args:         cobra.NoArgs,
+		RunE:         serve.RunE,
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := util.LoadConfigWithFlags(a.configPath, cmd.Flags())
+			if err != nil {
+				return fmt.Errorf("cannot load config: %w", err)
+			}
+			a.cfg = cfg
+
+			// Commands report on stdout, logs go to stderr; serve replaces this logger
+			logger, err := logging.New(cmd.ErrOrStderr(), cfg.LogLevel, cfg.LogFormat)
+			if err != nil {
+				return fmt.Errorf("cannot set up logging: %w", err)
+			}
+			slog.SetDefault(logger)
+			return nil
+		},
+	}
+	root.PersistentFlags().StringVar(&a.configPath, "config-path", ".", "directory containing config.env")
+	util.RegisterFlags(root.PersistentFlags())
+
+	root.AddCommand(
+		serve,
+		a.indexCommand(),
+		a.mappingCommand(),
+		a.importCommand(),
+		a.exportCommand(),
+		a.reindexCommand(),
+		a.aliasCommand(),
+		a.snapshotCommand(),
+		a.lifecycleCommand(),
+		a.popularityCommand(),
+		a.embeddingsCommand(),
+		a.seedCommand(),
+	)
+	return root
+}
+
+// client creates an es.Client for the books index or alias of the configuration.
+func (a *app) client() (es.Client, error) {
+	typed, err := es.NewTypedClient(a.cfg)
+	if err != nil {
+		return nil, err
+	}
+	relevance, err := es.LoadRelevance(a.cfg.RelevanceFile)
+	if err != nil {
+		return nil, err
+	}
+	embedder, err := embedding.New(a.cfg)
+	if err != nil {
+		return nil, err
+	}
+	return es.NewClient(typed,
+		es.WithIndex(a.cfg.BooksTarget()),
+		es.WithRequestTimeout(a.cfg.ElasticsearchRequestTimeout),
+		es.WithRelevance(relevance),
+		es.WithRanking(es.BooksRanking(a.cfg)),
+		es.WithEmbedder(embedder),
+		es.WithSimilarity(es.BooksSimilarity(a.cfg)),
+	), nil
+}
+
+// nameArg returns the index named on the command line, or the books index or alias of the configuration.
+func (a *app) nameArg(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return a.cfg.BooksTarget()
+}
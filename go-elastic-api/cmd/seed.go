@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"go-elastic-api/es"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/spf13/cobra"
+)
+
+// seedBooks are a few well known books to try the API with on an empty cluster.
+var seedBooks = []es.Book{
+	{ID: "9780553351927", Name: "Snow Crash", Author: "Neal Stephenson", ReleaseDate: "1992-06-01", Language: "en", PageCount: 470},
+	{ID: "9780441017225", Name: "Revelation Space", Author: "Alastair Reynolds", ReleaseDate: "2000-03-15", Language: "en", PageCount: 585},
+	{ID: "9780451524935", Name: "1984", Author: "George Orwell", ReleaseDate: "1985-06-01", Language: "en", PageCount: 328},
+	{ID: "9781451673319", Name: "Fahrenheit 451", Author: "Ray Bradbury", ReleaseDate: "1953-10-15", Language: "en", PageCount: 227},
+	{ID: "9780060850524", Name: "Brave New World", Author: "Aldous Huxley", ReleaseDate: "1932-06-01", Language: "en", PageCount: 268},
+	{ID: "9780385490818", Name: "The Handmaid's Tale", Author: "Margaret Atwood", ReleaseDate: "1985-06-01", Language: "en", PageCount: 311},
+}
+
+func (a *app) seedCommand() *cobra.Command {
+	var recreate bool
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Create the books index if needed and add a few sample books",
+		Long: `Create the books index if needed and add a few sample books.
+With BOOKS_ALIAS set, a new index is created as BOOKS_INDEX and the alias is pointed to it.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+
+			if recreate {
+				_, err := client.DeleteIndex(ctx, a.cfg.BooksIndex)
+				if err != nil && !isNotFound(err) {
+					return fmt.Errorf("cannot delete index %s: %w", a.cfg.BooksIndex, err)
+				}
+			}
+
+			exists, err := client.IndexExists(ctx, client.Index())
+			if err != nil {
+				return err
+			}
+			if !exists {
+				if _, err := client.CreateIndex(ctx, a.cfg.BooksIndex); err != nil {
+					return fmt.Errorf("cannot create index %s: %w", a.cfg.BooksIndex, err)
+				}
+				if a.cfg.BooksAlias != "" {
+					if _, err := client.SwapAlias(ctx, a.cfg.BooksAlias, a.cfg.BooksIndex); err != nil {
+						return fmt.Errorf("cannot point alias %s to %s: %w", a.cfg.BooksAlias, a.cfg.BooksIndex, err)
+					}
+				}
+			}
+
+			indexed, err := bulkIndex(ctx, client, seedBooks)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "indexed %d sample books into %s\n", indexed, client.Index())
+			if indexed < len(seedBooks) {
+				return fmt.Errorf("%d sample books were not indexed", len(seedBooks)-indexed)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&recreate, "recreate", false, "delete BOOKS_INDEX and all its books first")
+	return cmd
+}
+
+// isNotFound reports whether err is a 404 answer of Elasticsearch.
+func isNotFound(err error) bool {
+	var esErr *types.ElasticsearchError
+	return errors.As(err, &esErr) && esErr.Status == http.StatusNotFound
+}
+
+// bulkIndex indexes books in one bulk request and returns how many were indexed.
+// The reasons of failed items are logged.
+func bulkIndex(ctx context.Context, client es.Client, books []es.Book) (int, error) {
+	res, err := client.BulkAddBooks(ctx, books)
+	if err != nil {
+		return 0, fmt.Errorf("bulk request failed: %w", err)
+	}
+	indexed := 0
+	for _, item := range res.Items {
+		for _, result := range item {
+			if result.Error != nil {
+				slog.WarnContext(ctx, "cannot index book",
+					slog.String("id", str(result.Id_)),
+					slog.String("error", result.Error.Type),
+					slog.String("reason", str(result.Error.Reason)),
+				)
+				continue
+			}
+			indexed++
+		}
+	}
+	return indexed, nil
+}
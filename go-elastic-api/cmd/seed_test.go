@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"testing"
+
+	"go-elastic-api/val"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeedBooks(t *testing.T) {
+	ids := make(map[string]bool)
+	for _, book := range seedBooks {
+		require.False(t, ids[book.ID], "duplicate ID %s", book.ID)
+		ids[book.ID] = true
+		require.NotEmpty(t, book.Name)
+		require.True(t, val.IsDateValid(book.ReleaseDate), "invalid release date %q", book.ReleaseDate)
+	}
+}
+
+func TestRootCommand(t *testing.T) {
+	root := NewRootCommand()
+	for _, path := range [][]string{
+		{"serve"}, {"index", "create"}, {"index", "delete"}, {"index", "status"},
+		{"mapping", "show"}, {"mapping", "diff"}, {"import"}, {"export"},
+		{"reindex"}, {"alias", "swap"}, {"snapshot", "register"}, {"snapshot", "create"},
+		{"snapshot", "list"}, {"snapshot", "prune"}, {"snapshot", "restore"}, {"snapshot", "schedule"},
+		{"lifecycle", "setup"}, {"lifecycle", "status"}, {"popularity", "update"}, {"embeddings", "update"}, {"seed"},
+	} {
+		cmd, _, err := root.Find(path)
+		require.NoError(t, err)
+		require.Equal(t, path[len(path)-1], cmd.Name())
+	}
+
+	// Every configuration key is a flag of every command
+	cmd, _, err := root.Find([]string{"index", "status"})
+	require.NoError(t, err)
+	require.NotNil(t, cmd.InheritedFlags().Lookup("books-index"))
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"go-elastic-api/analytics"
+	"go-elastic-api/api"
+	"go-elastic-api/cache"
+	"go-elastic-api/es"
+	"go-elastic-api/logging"
+	"go-elastic-api/telemetry"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel"
+)
+
+func (a *app) serveCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Run the HTTP server",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := a.cfg
+
+			// 1. Set up structured logging on stdout, log.* calls go through it as well
+			logger, err := logging.New(os.Stdout, cfg.LogLevel, cfg.LogFormat)
+			if err != nil {
+				return fmt.Errorf("cannot set up logging: %w", err)
+			}
+			slog.SetDefault(logger)
+			if !strings.EqualFold(cfg.LogLevel, "debug") {
+				gin.SetMode(gin.ReleaseMode)
+			}
+
+			// 2. Set up tracing, spans are exported over OTLP when enabled
+			shutdownTracing, err := telemetry.SetupTracing(cmd.Context(), cfg)
+			if err != nil {
+				return fmt.Errorf("cannot set up tracing: %w", err)
+			}
+			defer func() {
+				// The spans of the last requests are flushed after the signal, which cancels cmd.Context()
+				ctx, cancel := context.WithTimeout(context.WithoutCancel(cmd.Context()), cfg.ShutdownTimeout)
+				defer cancel()
+				if err := shutdownTracing(ctx); err != nil {
+					slog.Warn("cannot flush spans", slog.String("error", err.Error()))
+				}
+			}()
+
+			// 3. Create Elastic client
+			esClient, err := a.client()
+			if err != nil {
+				return err
+			}
+			registry := prometheus.NewRegistry()
+			registry.MustRegister(
+				collectors.NewGoCollector(),
+				collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+			)
+			esStore := es.Observe(esClient,
+				es.NewMetrics(registry),
+				es.NewTracing(otel.GetTracerProvider()),
+				es.NewSlowLog(logger, cfg.SlowQueryThreshold),
+			)
+
+			// The cache sits outside the observers, so Elasticsearch metrics only count calls that reach the cluster
+			if cfg.CacheEnabled {
+				var store cache.Store = cache.NewLRU(cfg.CacheMaxEntries)
+				if cfg.CacheRedisURL != "" {
+					if store, err = cache.NewRedisFromURL(cfg.CacheRedisURL, "go-elastic-api:"+cfg.BooksTarget()); err != nil {
+						return fmt.Errorf("cannot set up cache: %w", err)
+					}
+				}
+				esStore = es.Cached(esStore, store, cfg.CacheTTL, registry)
+			}
+
+			// Background work runs until the server has stopped, not until the signal,
+			// so that the events of the last requests are still written.
+			background, stopBackground := context.WithCancel(context.WithoutCancel(cmd.Context()))
+			var workers sync.WaitGroup
+			defer func() {
+				stopBackground()
+				workers.Wait()
+			}()
+
+			// The recorder sits outside the cache, so searches answered from the cache are recorded too.
+			// Popularity updates go through the cache, which they purge since they change the ranking.
+			var recorder *analytics.Recorder
+			if cfg.AnalyticsEnabled {
+				if _, err := a.setupEvents(cmd.Context(), esStore); err != nil {
+					slog.Warn("search events are not recorded until the events indices are set up", slog.String("error", err.Error()))
+				}
+				recorder = analytics.NewRecorder(esStore, cfg.EventsAlias, cfg.AnalyticsBufferSize, cfg.AnalyticsFlushInterval, registry)
+				workers.Add(1)
+				go func() {
+					defer workers.Done()
+					recorder.Run(background)
+				}()
+				// Every updater rewrites the popularity of all books and purges its cache, one per cluster is enough
+				if cfg.PopularityUpdaterEnabled {
+					updater := analytics.NewPopularityUpdater(esStore, cfg.EventsAlias, cfg.PopularityWindow, cfg.PopularityUpdateInterval)
+					workers.Add(1)
+					go func() {
+						defer workers.Done()
+						updater.Run(background)
+					}()
+				}
+				esStore = analytics.Recorded(esStore, recorder)
+			}
+
+			// 4. Initialize HTTP server
+			server, err := api.NewServer(cfg, esStore, registry)
+			if err != nil {
+				return fmt.Errorf("cannot create server: %w", err)
+			}
+			if recorder != nil {
+				server.SetEventRecorder(recorder)
+			}
+
+			// 5. Run server until SIGINT or SIGTERM, then write the queued events and flush the spans
+			slog.Info("starting server", slog.String("address", cfg.HTTPServerAddress))
+			if err := server.Start(cmd.Context(), cfg.HTTPServerAddress); err != nil {
+				return fmt.Errorf("cannot start server: %w", err)
+			}
+			slog.Info("server stopped")
+			return nil
+		},
+	}
+}
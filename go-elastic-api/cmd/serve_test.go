@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeStopsWhenContextIsDone(t *testing.T) {
+	runServe(t)
+}
+
+func TestServeDebugLogLevel(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "DEBUG")
+	gin.SetMode(gin.DebugMode)
+	t.Cleanup(func() { gin.SetMode(gin.TestMode) })
+
+	// The log level is case-insensitive, gin is left in debug mode
+	runServe(t)
+	require.Equal(t, gin.DebugMode, gin.Mode())
+}
+
+// runServe starts serve, then cancels its context as on SIGTERM and waits for it to stop.
+func runServe(t *testing.T) {
+	root := NewRootCommand()
+	root.SetArgs([]string{"serve", "--config-path", t.TempDir(), "--http-server-address", "127.0.0.1:0"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- root.ExecuteContext(ctx)
+	}()
+
+	// As on SIGTERM
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve is still running after its context is done")
+	}
+}
@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go-elastic-api/es"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/spf13/cobra"
+)
+
+// snapshotPollInterval is how often a snapshot or a restore is checked while waiting for it.
+const snapshotPollInterval = 2 * time.Second
+
+func (a *app) snapshotCommand() *cobra.Command {
+	snapshot := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Back up the books indices to SNAPSHOT_REPOSITORY and restore them",
+		Long: `Back up the books indices to the shared file system repository SNAPSHOT_REPOSITORY,
+located at SNAPSHOT_REPOSITORY_PATH on the nodes. The path must be listed in their path.repo setting.
+Snapshots are named after the books index or alias and the time they were taken, e.g. books-2026.10.19-02.00.00;
+prune and schedule only ever delete snapshots named this way.`,
+	}
+
+	register := &cobra.Command{
+		Use:   "register",
+		Short: "Register the snapshot repository, once per cluster",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			if err := a.registerRepository(cmd.Context(), client); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "registered repository %s at %s\n", a.cfg.SnapshotRepository, a.cfg.SnapshotRepositoryPath)
+			return nil
+		},
+	}
+
+	var wait bool
+	create := &cobra.Command{
+		Use:   "create [name]",
+		Short: "Take a snapshot of the books index or alias, named after it and the time by default",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			name := es.SnapshotName(client.Index(), time.Now())
+			if len(args) > 0 {
+				name = args[0]
+			}
+			return a.takeSnapshot(cmd.Context(), client, name, wait, cmd.OutOrStdout())
+		},
+	}
+	create.Flags().BoolVar(&wait, "wait", false, "wait until the snapshot is done")
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the snapshots of the repository, oldest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			snapshots, err := client.Snapshots(cmd.Context(), a.cfg.SnapshotRepository)
+			if err != nil {
+				return fmt.Errorf("cannot list snapshots of %s: %w", a.cfg.SnapshotRepository, err)
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "SNAPSHOT\tSTATE\tSTARTED\tINDICES")
+			for _, s := range snapshots {
+				started := "-"
+				if s.StartTimeInMillis != nil {
+					started = time.UnixMilli(*s.StartTimeInMillis).UTC().Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Snapshot, str(s.State), started, strings.Join(s.Indices, ","))
+			}
+			return w.Flush()
+		},
+	}
+
+	var dryRun bool
+	prune := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete the snapshots SNAPSHOT_RETENTION_COUNT and SNAPSHOT_RETENTION_MAX_AGE do not keep",
+		Long: `Delete the snapshots of the books index or alias that are not among the SNAPSHOT_RETENTION_COUNT
+most recent ones, or older than SNAPSHOT_RETENTION_MAX_AGE. The most recent successful snapshot is always kept.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			return a.pruneSnapshots(cmd.Context(), client, dryRun, cmd.OutOrStdout())
+		},
+	}
+	prune.Flags().BoolVar(&dryRun, "dry-run", false, "print the snapshots that would be deleted")
+
+	var (
+		index     string
+		target    string
+		swapAlias bool
+	)
+	restore := &cobra.Command{
+		Use:   "restore <snapshot>",
+		Short: "Restore an index of a snapshot as a new index, and optionally point BOOKS_ALIAS to it",
+		Long: `Restore an index of a snapshot as a new index, named after the snapshot unless --target is set,
+and wait until it can serve requests. The index is the only one of the snapshot unless --index is set.
+Existing indices are left as they are: with --swap-alias, BOOKS_ALIAS is pointed to the restored index
+once it is ready, otherwise point it with "alias swap" after checking the restored books.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if swapAlias && a.cfg.BooksAlias == "" {
+				return errors.New("--swap-alias requires BOOKS_ALIAS")
+			}
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			name := args[0]
+			if target == "" {
+				target = name
+			}
+			if index == "" {
+				snapshots, err := client.Snapshots(cmd.Context(), a.cfg.SnapshotRepository, name)
+				if err != nil {
+					return fmt.Errorf("cannot get snapshot %s: %w", name, err)
+				}
+				if len(snapshots) != 1 || len(snapshots[0].Indices) != 1 {
+					return fmt.Errorf("snapshot %s does not hold exactly one index, choose one with --index", name)
+				}
+				index = snapshots[0].Indices[0]
+			}
+
+			if _, err := client.RestoreSnapshot(cmd.Context(), a.cfg.SnapshotRepository, name, index, target); err != nil {
+				return fmt.Errorf("cannot restore %s from snapshot %s: %w", index, name, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "restoring %s from snapshot %s as %s\n", index, name, target)
+			if err := waitForIndex(cmd.Context(), client, target); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "restored %s\n", target)
+
+			if swapAlias {
+				previous, err := client.SwapAlias(cmd.Context(), a.cfg.BooksAlias, target)
+				if err != nil {
+					return fmt.Errorf("cannot point alias %s to %s: %w", a.cfg.BooksAlias, target, err)
+				}
+				if len(previous) == 0 {
+					fmt.Fprintf(cmd.OutOrStdout(), "alias %s now points to %s\n", a.cfg.BooksAlias, target)
+				} else {
+					fmt.Fprintf(cmd.OutOrStdout(), "alias %s now points to %s instead of %s\n", a.cfg.BooksAlias, target, strings.Join(previous, ", "))
+				}
+			}
+			return nil
+		},
+	}
+	restore.Flags().StringVar(&index, "index", "", "index of the snapshot to restore, its only index by default")
+	restore.Flags().StringVar(&target, "target", "", "name of the restored index, the snapshot name by default")
+	restore.Flags().BoolVar(&swapAlias, "swap-alias", false, "point BOOKS_ALIAS to the restored index")
+
+	schedule := &cobra.Command{
+		Use:   "schedule",
+		Short: "Take a snapshot every SNAPSHOT_INTERVAL and prune the old ones, until stopped",
+		Long: `Register the repository, then take a snapshot right away and every SNAPSHOT_INTERVAL after it,
+pruning the snapshots retention does not keep after each one. A failed snapshot is logged and retried
+at the next interval; the command runs until it is stopped with SIGINT or SIGTERM.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			if err := a.registerRepository(ctx, client); err != nil {
+				return err
+			}
+
+			ticker := time.NewTicker(a.cfg.SnapshotInterval)
+			defer ticker.Stop()
+			for {
+				name := es.SnapshotName(client.Index(), time.Now())
+				if err := a.takeSnapshot(ctx, client, name, true, cmd.OutOrStdout()); err != nil {
+					slog.ErrorContext(ctx, "scheduled snapshot failed", slog.String("snapshot", name), slog.String("error", err.Error()))
+				} else if err := a.pruneSnapshots(ctx, client, false, cmd.OutOrStdout()); err != nil {
+					slog.ErrorContext(ctx, "cannot prune snapshots", slog.String("error", err.Error()))
+				}
+
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+				}
+			}
+		},
+	}
+
+	snapshot.AddCommand(register, create, list, prune, restore, schedule)
+	return snapshot
+}
+
+func (a *app) registerRepository(ctx context.Context, client es.Client) error {
+	if _, err := client.RegisterSnapshotRepository(ctx, a.cfg.SnapshotRepository, a.cfg.SnapshotRepositoryPath); err != nil {
+		return fmt.Errorf("cannot register repository %s at %s, is it listed in path.repo? %w",
+			a.cfg.SnapshotRepository, a.cfg.SnapshotRepositoryPath, err)
+	}
+	return nil
+}
+
+// takeSnapshot starts a snapshot of the books index or alias and, with wait, waits until it is done.
+// A partial snapshot, which misses shards, is an error.
+func (a *app) takeSnapshot(ctx context.Context, client es.Client, name string, wait bool, out io.Writer) error {
+	repository := a.cfg.SnapshotRepository
+	if _, err := client.CreateSnapshot(ctx, repository, name, []string{client.Index()}); err != nil {
+		return fmt.Errorf("cannot create snapshot %s: %w", name, err)
+	}
+	fmt.Fprintf(out, "started snapshot %s of %s\n", name, client.Index())
+	if !wait {
+		return nil
+	}
+
+	ticker := time.NewTicker(snapshotPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting, snapshot %s keeps running: %w", name, ctx.Err())
+		case <-ticker.C:
+		}
+
+		snapshots, err := client.Snapshots(ctx, repository, name)
+		if err != nil {
+			return fmt.Errorf("cannot get snapshot %s: %w", name, err)
+		}
+		if len(snapshots) != 1 {
+			return fmt.Errorf("snapshot %s not found", name)
+		}
+		switch state := str(snapshots[0].State); state {
+		case es.SnapshotInProgress:
+			continue
+		case es.SnapshotSuccess:
+			fmt.Fprintf(out, "snapshot %s done\n", name)
+			return nil
+		default:
+			return fmt.Errorf("snapshot %s ended in state %s: %s", name, state, snapshotFailure(snapshots[0]))
+		}
+	}
+}
+
+// snapshotFailure returns why a snapshot failed, as reported by Elasticsearch.
+func snapshotFailure(s types.SnapshotInfo) string {
+	if s.Reason != nil {
+		return *s.Reason
+	}
+	var reasons []string
+	for _, f := range s.Failures {
+		reasons = append(reasons, fmt.Sprintf("%s shard %s: %s", f.Index, f.ShardId, f.Reason))
+	}
+	if len(reasons) == 0 {
+		return "no reason given"
+	}
+	return strings.Join(reasons, "; ")
+}
+
+func (a *app) pruneSnapshots(ctx context.Context, client es.Client, dryRun bool, out io.Writer) error {
+	snapshots, err := client.Snapshots(ctx, a.cfg.SnapshotRepository)
+	if err != nil {
+		return fmt.Errorf("cannot list snapshots of %s: %w", a.cfg.SnapshotRepository, err)
+	}
+	retention := es.SnapshotRetention{MaxCount: a.cfg.SnapshotRetentionCount, MaxAge: a.cfg.SnapshotRetentionMaxAge}
+	for _, name := range es.SnapshotsToPrune(snapshots, client.Index(), retention, time.Now()) {
+		if dryRun {
+			fmt.Fprintf(out, "would delete snapshot %s\n", name)
+			continue
+		}
+		if _, err := client.DeleteSnapshot(ctx, a.cfg.SnapshotRepository, name); err != nil {
+			return fmt.Errorf("cannot delete snapshot %s: %w", name, err)
+		}
+		fmt.Fprintf(out, "deleted snapshot %s\n", name)
+	}
+	return nil
+}
+
+// waitForIndex waits until a restored index has all its primary shards, when its health is no longer red.
+func waitForIndex(ctx context.Context, client es.Client, name string) error {
+	ticker := time.NewTicker(snapshotPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting, %s keeps being restored: %w", name, ctx.Err())
+		case <-ticker.C:
+		}
+
+		records, err := client.IndexStatus(ctx, name)
+		if err != nil {
+			return fmt.Errorf("cannot get status of %s: %w", name, err)
+		}
+		if len(records) == 1 && records[0].Health != nil && *records[0].Health != "red" {
+			return nil
+		}
+	}
+}
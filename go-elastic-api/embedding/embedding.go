@@ -0,0 +1,31 @@
+// Package embedding turns texts into the vectors semantic search compares books and queries with.
+package embedding
+
+import (
+	"context"
+	"fmt"
+
+	"go-elastic-api/util"
+)
+
+// Dims is the number of dimensions of the vectors, the dims of the embedding field of a books index.
+// It is the size of the common small sentence models, such as all-MiniLM-L6-v2.
+const Dims = 384
+
+// Embedder turns texts into vectors of Dims dimensions, one per text in the same order.
+// A text without anything to embed, e.g. only punctuation, has a nil vector.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// New returns the embedder configured by cfg.
+func New(cfg util.Config) (Embedder, error) {
+	switch cfg.Embedder {
+	case "", "local":
+		return Local{}, nil
+	case "http":
+		return NewHTTP(cfg.EmbedderURL, cfg.EmbedderModel, cfg.EmbedderAPIKey, cfg.EmbedderTimeout), nil
+	default:
+		return nil, fmt.Errorf("unknown embedder %q", cfg.Embedder)
+	}
+}
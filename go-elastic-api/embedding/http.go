@@ -0,0 +1,104 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxBatchSize bounds the texts sent in one request, embedding services limit the inputs of a request.
+const maxBatchSize = 100
+
+// HTTP embeds texts with a service exposing the OpenAI embeddings API, as OpenAI, Ollama,
+// Hugging Face text-embeddings-inference and vLLM do: it posts {"model": ..., "input": [...]}
+// and reads the vectors from {"data": [{"index": 0, "embedding": [...]}, ...]}.
+// The model must return vectors of Dims dimensions.
+type HTTP struct {
+	url    string
+	model  string
+	apiKey string
+	client *http.Client
+}
+
+// NewHTTP creates an embedder posting to url, the full URL of the embeddings endpoint,
+// e.g. http://localhost:11434/v1/embeddings. apiKey is sent as a bearer token when set.
+func NewHTTP(url, model, apiKey string, timeout time.Duration) *HTTP {
+	return &HTTP{
+		url:    url,
+		model:  model,
+		apiKey: apiKey,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+type embeddingsRequest struct {
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+type embeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (e *HTTP) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxBatchSize {
+		batch, err := e.embed(ctx, texts[start:min(start+maxBatchSize, len(texts))])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (e *HTTP) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embeddingsRequest{Model: e.model, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot reach embedder: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return nil, fmt.Errorf("embedder returned %s: %s", res.Status, bytes.TrimSpace(message))
+	}
+
+	var embeddings embeddingsResponse
+	if err := json.NewDecoder(res.Body).Decode(&embeddings); err != nil {
+		return nil, fmt.Errorf("cannot decode embedder response: %w", err)
+	}
+	if len(embeddings.Data) != len(texts) {
+		return nil, fmt.Errorf("embedder returned %d vectors for %d texts", len(embeddings.Data), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, data := range embeddings.Data {
+		if data.Index < 0 || data.Index >= len(texts) || vectors[data.Index] != nil {
+			return nil, fmt.Errorf("embedder returned an unexpected vector index %d", data.Index)
+		}
+		if len(data.Embedding) != Dims {
+			return nil, fmt.Errorf("embedder returned a vector of %d dimensions, expected %d", len(data.Embedding), Dims)
+		}
+		vectors[data.Index] = data.Embedding
+	}
+	return vectors, nil
+}
@@ -0,0 +1,66 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTP(t *testing.T) {
+	var requests []embeddingsRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var req embeddingsRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests = append(requests, req)
+
+		var res embeddingsResponse
+		res.Data = make([]struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}, len(req.Input))
+		// Vectors may come in any order, they are placed by index
+		for i := range req.Input {
+			j := len(req.Input) - 1 - i
+			res.Data[i].Index = j
+			res.Data[i].Embedding = make([]float32, Dims)
+			res.Data[i].Embedding[0] = float32(j)
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(res))
+	}))
+	defer server.Close()
+
+	texts := make([]string, maxBatchSize+1)
+	for i := range texts {
+		texts[i] = "text"
+	}
+	vectors, err := NewHTTP(server.URL, "all-minilm", "secret", time.Second).Embed(context.Background(), texts)
+	require.NoError(t, err)
+	require.Len(t, vectors, len(texts))
+	require.Equal(t, float32(1), vectors[1][0])
+	require.Len(t, requests, 2, "texts are sent in batches")
+	require.Equal(t, "all-minilm", requests[0].Model)
+	require.Len(t, requests[1].Input, 1)
+}
+
+func TestHTTPErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/small" {
+			w.Write([]byte(`{"data": [{"index": 0, "embedding": [0.1, 0.2]}]}`))
+			return
+		}
+		http.Error(w, "model not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	_, err := NewHTTP(server.URL, "", "", time.Second).Embed(context.Background(), []string{"text"})
+	require.ErrorContains(t, err, "embedder returned 404 Not Found: model not found")
+
+	_, err = NewHTTP(server.URL+"/small", "", "", time.Second).Embed(context.Background(), []string{"text"})
+	require.ErrorContains(t, err, "vector of 2 dimensions, expected 384")
+}
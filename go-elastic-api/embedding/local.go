@@ -0,0 +1,71 @@
+package embedding
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// trigramWeight is the weight of the character trigrams of a word relative to the word itself.
+// Trigrams let words sharing a stem, such as "dragon" and "dragons", bring texts closer.
+const trigramWeight = 0.5
+
+// Local embeds texts in process with feature hashing: every word and its character trigrams
+// are hashed to a dimension with a sign, and the vector is normalized. It needs no model or
+// service and gives the same vector for the same text, so it runs offline and in tests,
+// but it only brings closer texts that share words, not the ones with the same meaning.
+type Local struct{}
+
+func (Local) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = embedLocal(text)
+	}
+	return vectors, nil
+}
+
+func embedLocal(text string) []float32 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return nil
+	}
+
+	vector := make([]float64, Dims)
+	for _, word := range words {
+		addFeature(vector, "w:"+word, 1)
+		runes := []rune("^" + word + "$")
+		for i := 0; i+3 <= len(runes); i++ {
+			addFeature(vector, "t:"+string(runes[i:i+3]), trigramWeight)
+		}
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+	if norm == 0 {
+		return nil
+	}
+	embedding := make([]float32, Dims)
+	for i, v := range vector {
+		embedding[i] = float32(v / norm)
+	}
+	return embedding
+}
+
+// addFeature adds weight to the dimension feature hashes to, with the sign given by the hash
+// so that colliding features tend to cancel out rather than add up.
+func addFeature(vector []float64, feature string, weight float64) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	vector[sum%Dims] += weight
+}
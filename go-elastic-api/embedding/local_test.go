@@ -0,0 +1,44 @@
+package embedding
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func cosine(a, b []float32) float64 {
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
+
+func TestLocal(t *testing.T) {
+	vectors, err := Local{}.Embed(context.Background(), []string{
+		"A boy and his dragons in a fantasy kingdom",
+		"Fantasy kingdom of dragons",
+		"A detective solves a murder in London",
+		"?!",
+	})
+	require.NoError(t, err)
+	require.Len(t, vectors, 4)
+	require.Len(t, vectors[0], Dims)
+	require.Nil(t, vectors[3], "nothing to embed")
+
+	var norm float64
+	for _, v := range vectors[0] {
+		norm += float64(v) * float64(v)
+	}
+	require.InDelta(t, 1, math.Sqrt(norm), 1e-6)
+
+	// Texts sharing words are closer than unrelated ones
+	require.Greater(t, cosine(vectors[0], vectors[1]), cosine(vectors[1], vectors[2]))
+
+	// The same text gives the same vector
+	again, err := Local{}.Embed(context.Background(), []string{"Fantasy kingdom of dragons"})
+	require.NoError(t, err)
+	require.Equal(t, vectors[1], again[0])
+}
@@ -0,0 +1,306 @@
+package es
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/bulk"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/sortorder"
+)
+
+// Event types, the type field of the documents behind an events alias
+const (
+	EventSearch = "search"
+	EventClick  = "click"
+)
+
+// SearchEvent records one search made through FullTextSearch, SemanticSearch, HybridSearch or FilterBooks.
+type SearchEvent struct {
+	Timestamp time.Time `json:"@timestamp"`
+	Type      string    `json:"type"`
+	// Endpoint is full_text_search, semantic, hybrid or filter
+	Endpoint string `json:"endpoint"`
+	// Query is the normalized query string, empty for filters
+	Query   string         `json:"query,omitempty"`
+	Filters map[string]any `json:"filters,omitempty"`
+	From    int            `json:"from"`
+	Hits    int64          `json:"hits"`
+	// LatencyMS is how long the search took as seen by the API, cache included
+	LatencyMS float64 `json:"latency_ms"`
+	User      string  `json:"user,omitempty"`
+}
+
+// ClickEvent records a click on a book listed in the results of a query.
+type ClickEvent struct {
+	Timestamp time.Time `json:"@timestamp"`
+	Type      string    `json:"type"`
+	// Query is the normalized query string the book was found with
+	Query  string `json:"query"`
+	BookID string `json:"book_id"`
+	// Position is the rank of the book in the results, from 1, or 0 when unknown
+	Position int    `json:"position,omitempty"`
+	User     string `json:"user,omitempty"`
+}
+
+// QueryStats sums up the events of one query in a time window.
+type QueryStats struct {
+	Query       string `json:"query"`
+	Searches    int64  `json:"searches"`
+	ZeroResults int64  `json:"zero_results"`
+	Clicks      int64  `json:"clicks"`
+	// CTR is the number of clicks per search
+	CTR      float64   `json:"ctr"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// ClickThroughBucket sums up the searches and clicks of one interval.
+type ClickThroughBucket struct {
+	Start    time.Time `json:"start"`
+	Searches int64     `json:"searches"`
+	Clicks   int64     `json:"clicks"`
+	CTR      float64   `json:"ctr"`
+}
+
+// IndexEvents writes events to alias in a single bulk request. The request fails rather than creating
+// an index when alias does not exist, so events never end up in an index no lifecycle policy manages.
+// Failures of individual items are reported in the response, not as an error.
+func (es *ESClient) IndexEvents(ctx context.Context, alias string, events []any) (*bulk.Response, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	req := es.client.Bulk().Index(alias).RequireAlias(true)
+	for _, event := range events {
+		if err := req.CreateOp(types.CreateOperation{}, event); err != nil {
+			return nil, fmt.Errorf("cannot add event to bulk request: %w", err)
+		}
+	}
+	return req.Do(ctx)
+}
+
+// TopQueries returns the size queries searched the most since a time, with their clicks.
+func (es *ESClient) TopQueries(ctx context.Context, alias string, since time.Time, size int) ([]QueryStats, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	res, err := es.searchEvents(ctx, alias, eventsSince(since, EventSearch, EventClick), map[string]types.Aggregations{
+		"queries": {
+			Terms: &types.TermsAggregation{
+				Field: ptr("query"),
+				Size:  &size,
+				Order: map[string]sortorder.SortOrder{"searches": sortorder.Desc},
+			},
+			Aggregations: map[string]types.Aggregations{
+				"searches":     {Filter: eventType(EventSearch)},
+				"clicks":       {Filter: eventType(EventClick)},
+				"zero_results": {Filter: &types.Query{Bool: &types.BoolQuery{Filter: []types.Query{*eventType(EventSearch), *zeroHits()}}}},
+				"last_seen":    {Max: &types.MaxAggregation{Field: ptr("@timestamp")}},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseQueryStats(res.Aggregations["queries"])
+}
+
+// ZeroResultQueries returns the size queries most often searched without results since a time.
+func (es *ESClient) ZeroResultQueries(ctx context.Context, alias string, since time.Time, size int) ([]QueryStats, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	query := eventsSince(since, EventSearch)
+	query.Bool.Filter = append(query.Bool.Filter, *zeroHits())
+	res, err := es.searchEvents(ctx, alias, query, map[string]types.Aggregations{
+		"queries": {
+			Terms: &types.TermsAggregation{Field: ptr("query"), Size: &size},
+			Aggregations: map[string]types.Aggregations{
+				// Every event matched is a search without results
+				"searches":     {Filter: zeroHits()},
+				"zero_results": {Filter: zeroHits()},
+				"last_seen":    {Max: &types.MaxAggregation{Field: ptr("@timestamp")}},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseQueryStats(res.Aggregations["queries"])
+}
+
+// ClickThroughRate returns the searches and clicks since a time by interval, oldest first.
+// Intervals without events are included.
+func (es *ESClient) ClickThroughRate(ctx context.Context, alias string, since time.Time, interval time.Duration) ([]ClickThroughBucket, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	minDocCount := 0
+	res, err := es.searchEvents(ctx, alias, eventsSince(since, EventSearch, EventClick), map[string]types.Aggregations{
+		"intervals": {
+			DateHistogram: &types.DateHistogramAggregation{
+				Field:          ptr("@timestamp"),
+				FixedInterval:  esDuration(interval),
+				MinDocCount:    &minDocCount,
+				ExtendedBounds: &types.ExtendedBoundsFieldDateMath{Min: since.UnixMilli(), Max: time.Now().UnixMilli()},
+			},
+			Aggregations: map[string]types.Aggregations{
+				"searches": {Filter: eventType(EventSearch)},
+				"clicks":   {Filter: eventType(EventClick)},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseClickThroughBuckets(res.Aggregations["intervals"])
+}
+
+// BookClicks returns the clicks of each book since a time, for the maxPopularBooks books clicked the most.
+func (es *ESClient) BookClicks(ctx context.Context, alias string, since time.Time) (map[string]int64, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	size := maxPopularBooks
+	res, err := es.searchEvents(ctx, alias, eventsSince(since, EventClick), map[string]types.Aggregations{
+		"books": {Terms: &types.TermsAggregation{Field: ptr("book_id"), Size: &size}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseBookClicks(res.Aggregations["books"])
+}
+
+// searchEvents runs aggregations over the events matching query, without returning hits.
+func (es *ESClient) searchEvents(ctx context.Context, alias string, query *types.Query, aggregations map[string]types.Aggregations) (*search.Response, error) {
+	size := 0
+	return es.client.Search().
+		Index(alias).
+		Request(&search.Request{Query: query, Aggregations: aggregations, Size: &size}).
+		Do(ctx)
+}
+
+// eventsSince matches the events of the given types from since on.
+func eventsSince(since time.Time, eventTypes ...string) *types.Query {
+	from := since.UTC().Format(time.RFC3339)
+	return &types.Query{
+		Bool: &types.BoolQuery{
+			Filter: []types.Query{
+				{Range: map[string]types.RangeQuery{"@timestamp": &types.DateRangeQuery{Gte: &from}}},
+				{Terms: &types.TermsQuery{TermsQuery: map[string]types.TermsQueryField{"type": eventTypes}}},
+			},
+		},
+	}
+}
+
+func eventType(eventType string) *types.Query {
+	return &types.Query{Term: map[string]types.TermQuery{"type": {Value: eventType}}}
+}
+
+func zeroHits() *types.Query {
+	return &types.Query{Term: map[string]types.TermQuery{"hits": {Value: 0}}}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+type docCount struct {
+	DocCount int64 `json:"doc_count"`
+}
+
+// parseQueryStats reads the buckets of a terms aggregation on query with searches, clicks, zero_results
+// and last_seen sub-aggregations, missing ones count as zero. Without typed_keys, the typed client leaves
+// aggregations as decoded JSON.
+func parseQueryStats(aggregation types.Aggregate) ([]QueryStats, error) {
+	var terms struct {
+		Buckets []struct {
+			Key         string   `json:"key"`
+			Searches    docCount `json:"searches"`
+			Clicks      docCount `json:"clicks"`
+			ZeroResults docCount `json:"zero_results"`
+			LastSeen    struct {
+				Value *float64 `json:"value"`
+			} `json:"last_seen"`
+		} `json:"buckets"`
+	}
+	if err := reparse(aggregation, &terms); err != nil {
+		return nil, err
+	}
+
+	stats := make([]QueryStats, 0, len(terms.Buckets))
+	for _, b := range terms.Buckets {
+		s := QueryStats{Query: b.Key, Searches: b.Searches.DocCount, ZeroResults: b.ZeroResults.DocCount, Clicks: b.Clicks.DocCount}
+		s.CTR = rate(s.Clicks, s.Searches)
+		if b.LastSeen.Value != nil {
+			s.LastSeen = time.UnixMilli(int64(*b.LastSeen.Value)).UTC()
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// parseClickThroughBuckets reads the buckets of a date histogram with searches and clicks.
+func parseClickThroughBuckets(aggregation types.Aggregate) ([]ClickThroughBucket, error) {
+	var histogram struct {
+		Buckets []struct {
+			Key      int64    `json:"key"`
+			Searches docCount `json:"searches"`
+			Clicks   docCount `json:"clicks"`
+		} `json:"buckets"`
+	}
+	if err := reparse(aggregation, &histogram); err != nil {
+		return nil, err
+	}
+
+	buckets := make([]ClickThroughBucket, 0, len(histogram.Buckets))
+	for _, b := range histogram.Buckets {
+		buckets = append(buckets, ClickThroughBucket{
+			Start:    time.UnixMilli(b.Key).UTC(),
+			Searches: b.Searches.DocCount,
+			Clicks:   b.Clicks.DocCount,
+			CTR:      rate(b.Clicks.DocCount, b.Searches.DocCount),
+		})
+	}
+	return buckets, nil
+}
+
+// parseBookClicks reads the buckets of a terms aggregation on book_id.
+func parseBookClicks(aggregation types.Aggregate) (map[string]int64, error) {
+	var terms struct {
+		Buckets []struct {
+			Key      string `json:"key"`
+			DocCount int64  `json:"doc_count"`
+		} `json:"buckets"`
+	}
+	if err := reparse(aggregation, &terms); err != nil {
+		return nil, err
+	}
+
+	clicks := make(map[string]int64, len(terms.Buckets))
+	for _, b := range terms.Buckets {
+		clicks[b.Key] = b.DocCount
+	}
+	return clicks, nil
+}
+
+func reparse(aggregation types.Aggregate, v any) error {
+	if aggregation == nil {
+		return fmt.Errorf("aggregation missing from response")
+	}
+	b, err := json.Marshal(aggregation)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// rate returns n per total, 0 when total is 0.
+func rate(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
@@ -0,0 +1,80 @@
+package es
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQueryStats(t *testing.T) {
+	var res search.Response
+	require.NoError(t, json.Unmarshal([]byte(`{"took": 3, "hits": {"hits": []}, "aggregations": {"queries": {"buckets": [
+		{"key": "dune", "doc_count": 12, "searches": {"doc_count": 10}, "clicks": {"doc_count": 2},
+			"zero_results": {"doc_count": 0}, "last_seen": {"value": 1792396800000.0, "value_as_string": "2026-10-19T08:00:00.000Z"}},
+		{"key": "snow crush", "doc_count": 3, "searches": {"doc_count": 3}, "clicks": {"doc_count": 0},
+			"zero_results": {"doc_count": 3}, "last_seen": {"value": null}}
+	]}}}`), &res))
+
+	stats, err := parseQueryStats(res.Aggregations["queries"])
+	require.NoError(t, err)
+	require.Equal(t, []QueryStats{
+		{Query: "dune", Searches: 10, Clicks: 2, CTR: 0.2, LastSeen: time.UnixMilli(1792396800000).UTC()},
+		{Query: "snow crush", Searches: 3, ZeroResults: 3},
+	}, stats)
+
+	_, err = parseQueryStats(res.Aggregations["missing"])
+	require.Error(t, err)
+}
+
+func TestParseClickThroughBuckets(t *testing.T) {
+	var res search.Response
+	require.NoError(t, json.Unmarshal([]byte(`{"took": 3, "hits": {"hits": []}, "aggregations": {"intervals": {"buckets": [
+		{"key": 1792368000000, "key_as_string": "2026-10-19T00:00:00.000Z", "doc_count": 5, "searches": {"doc_count": 4}, "clicks": {"doc_count": 1}},
+		{"key": 1792371600000, "key_as_string": "2026-10-19T01:00:00.000Z", "doc_count": 0, "searches": {"doc_count": 0}, "clicks": {"doc_count": 0}}
+	]}}}`), &res))
+
+	buckets, err := parseClickThroughBuckets(res.Aggregations["intervals"])
+	require.NoError(t, err)
+	require.Equal(t, []ClickThroughBucket{
+		{Start: time.UnixMilli(1792368000000).UTC(), Searches: 4, Clicks: 1, CTR: 0.25},
+		{Start: time.UnixMilli(1792371600000).UTC()},
+	}, buckets)
+}
+
+func TestParseBookClicks(t *testing.T) {
+	var res search.Response
+	require.NoError(t, json.Unmarshal([]byte(`{"took": 3, "hits": {"hits": []}, "aggregations": {"books": {"buckets": [
+		{"key": "42", "doc_count": 12},
+		{"key": "7", "doc_count": 1}
+	]}}}`), &res))
+
+	clicks, err := parseBookClicks(res.Aggregations["books"])
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"42": 12, "7": 1}, clicks)
+}
+
+func TestEventsIndexTemplate(t *testing.T) {
+	// The definition goes through the typed client, which must keep the fields and dynamic templates
+	var template types.IndexTemplateMapping
+	require.NoError(t, json.Unmarshal(eventsIndex, &template))
+	b, err := json.Marshal(template)
+	require.NoError(t, err)
+
+	var got, want struct {
+		Mappings struct {
+			DateDetection    bool             `json:"date_detection"`
+			DynamicTemplates []map[string]any `json:"dynamic_templates"`
+			Properties       map[string]any   `json:"properties"`
+		} `json:"mappings"`
+	}
+	require.NoError(t, json.Unmarshal(b, &got))
+	require.NoError(t, json.Unmarshal(eventsIndex, &want))
+	require.Equal(t, want.Mappings.Properties, got.Mappings.Properties)
+	require.False(t, got.Mappings.DateDetection)
+	require.Len(t, got.Mappings.DynamicTemplates, 1)
+	require.Contains(t, got.Mappings.DynamicTemplates[0], "strings_as_keywords")
+}
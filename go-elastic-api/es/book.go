@@ -2,13 +2,25 @@ package es
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
 
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/bulk"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/create"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/core/delete"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/deletebyquery"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/core/index"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/conflicts"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/optype"
 )
 
+// ErrEmptyFilter is returned by DeleteBooksByQuery for a filter without any known criterion.
+var ErrEmptyFilter = errors.New("filter has no author, publisher, categories, language or release_after criterion")
+
 type Book struct {
 	ID          string `json:"id"`
 	Name        string `json:"name"`
@@ -16,6 +28,8 @@ type Book struct {
 	Edition     string `json:"edition"`
 	Publisher   string `json:"publisher"`
 	ReleaseDate string `json:"release_date"`
+	// Language is the ISO 639-1 code of the language of the book, which selects its analyzers
+	Language string `json:"language,omitempty"`
 
 	// Content and description
 	Description string `json:"description,omitempty"`
@@ -27,6 +41,25 @@ type Book struct {
 	Tags        []string `json:"tags,omitempty"`
 	Rating      float32  `json:"rating,omitempty"`
 	ReviewCount int      `json:"review_count,omitempty"`
+
+	// Embedding is the vector semantic search compares books with, computed by the embedder set
+	// with WithEmbedder when a book is written without one. Books are read without it.
+	Embedding []float32 `json:"embedding,omitempty"`
+}
+
+// Page selects a window of search hits. A zero Size uses the Elasticsearch default of 10.
+type Page struct {
+	From int `json:"from"`
+	Size int `json:"size"`
+}
+
+func (p Page) apply(req *search.Request) {
+	if p.From > 0 {
+		req.From = &p.From
+	}
+	if p.Size > 0 {
+		req.Size = &p.Size
+	}
 }
 
 type BookInfo struct {
@@ -36,6 +69,8 @@ type BookInfo struct {
 	Edition     string `json:"edition"`
 	Publisher   string `json:"publisher"`
 	ReleaseDate string `json:"release_date"`
+	// Language is the ISO 639-1 code of the language of the book, which selects its analyzers
+	Language string `json:"language,omitempty"`
 
 	// Content and description
 	Description string `json:"description,omitempty"`
@@ -48,22 +83,217 @@ type BookInfo struct {
 	ReviewCount int      `json:"review_count,omitempty"`
 }
 
+// bookSource leaves the embedding out of the books read, it is only used to search
+// and would make up most of every hit.
+var bookSource = &types.SourceFilter{Excludes: []string{"embedding"}}
+
+// AddBook indexes a book, replacing the book with the same ID if any, whose popularity is carried over.
+// With a publisher scope, ErrOutOfScope is returned if the book, or the one it would replace,
+// is of another publisher.
 func (es *ESClient) AddBook(ctx context.Context, book Book) (*index.Response, error) {
-	res, err := es.client.Index("books").
+	if err := checkScope(ctx, book); err != nil {
+		return nil, err
+	}
+	book, err := es.embedBook(ctx, book)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	stored, found, err := es.read(ctx, book.ID)
+	if err != nil {
+		return nil, err
+	}
+	req := es.client.Index(es.index).
 		Id(book.ID).
+		Request(storedBook{Book: book, Popularity: stored.popularity})
+	if _, ok := PublisherScope(ctx); ok {
+		switch {
+		case !found:
+			// A book added meanwhile by another publisher is not replaced either
+			req.OpType(optype.Create)
+		case checkScope(ctx, Book{Publisher: stored.publisher}) != nil:
+			return nil, ErrOutOfScope
+		default:
+			req.IfSeqNo(stored.seqNo).IfPrimaryTerm(stored.primaryTerm)
+		}
+	}
+	return req.Do(ctx)
+}
+
+// CreateBook indexes a new book. It fails with a 409 Conflict if a book with the same ID exists,
+// and with ErrOutOfScope if the book is outside the publisher scope of ctx.
+func (es *ESClient) CreateBook(ctx context.Context, book Book) (*create.Response, error) {
+	if err := checkScope(ctx, book); err != nil {
+		return nil, err
+	}
+	book, err := es.embedBook(ctx, book)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	return es.client.Create(es.index, book.ID).
 		Request(book).
 		Do(ctx)
-	return res, err
 }
 
+// UpdateBook replaces an existing book, ErrBookNotFound is returned if it does not exist.
+// With a publisher scope, the stored book must belong to the publisher too. The write fails
+// with a 409 Conflict if the book was changed since it was checked.
+func (es *ESClient) UpdateBook(ctx context.Context, book Book) (*index.Response, error) {
+	if err := checkScope(ctx, book); err != nil {
+		return nil, err
+	}
+	book, err := es.embedBook(ctx, book)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	stored, err := es.lookup(ctx, book.ID)
+	if err != nil {
+		return nil, err
+	}
+	// The book is replaced whole, the popularity set by UpdatePopularity is carried over
+	return es.client.Index(es.index).
+		Id(book.ID).
+		Request(storedBook{Book: book, Popularity: stored.popularity}).
+		IfSeqNo(stored.seqNo).
+		IfPrimaryTerm(stored.primaryTerm).
+		Do(ctx)
+}
+
+// BulkAddBooks indexes all books in a single bulk request, replacing the books with the same IDs,
+// whose popularity is carried over. Failures of individual items are reported in the response, not as an error.
+func (es *ESClient) BulkAddBooks(ctx context.Context, books []Book) (*bulk.Response, error) {
+	books, err := es.embedBooks(ctx, books)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	ids := make([]string, len(books))
+	for i, book := range books {
+		ids[i] = book.ID
+	}
+	popularity, err := es.popularities(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	req := es.client.Bulk().Index(es.index)
+	for _, book := range books {
+		id := book.ID
+		stored := storedBook{Book: book}
+		if p, ok := popularity[id]; ok {
+			stored.Popularity = &p
+		}
+		if err := req.IndexOp(types.IndexOperation{Id_: &id}, stored); err != nil {
+			return nil, fmt.Errorf("cannot add book %s to bulk request: %w", book.ID, err)
+		}
+	}
+	return req.Do(ctx)
+}
+
+// DeleteBook deletes a book. With a publisher scope, the book must belong to the publisher,
+// otherwise ErrBookNotFound is returned.
 func (es *ESClient) DeleteBook(ctx context.Context, bookID string) (*delete.Response, error) {
-	res, err := es.client.Delete("books", bookID).Do(ctx)
-	return res, err
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	req := es.client.Delete(es.index, bookID)
+	if _, ok := PublisherScope(ctx); ok {
+		stored, err := es.lookup(ctx, bookID)
+		if err != nil {
+			return nil, err
+		}
+		req.IfSeqNo(stored.seqNo).IfPrimaryTerm(stored.primaryTerm)
+	}
+	return req.Do(ctx)
+}
+
+// storedVersion is what a write needs to know of a stored book: its sequence number and primary term,
+// so that the write only applies to the version that was checked, and the fields it checks or carries over.
+type storedVersion struct {
+	seqNo       string
+	primaryTerm string
+	publisher   string
+	popularity  *float64
+}
+
+// lookup reads a stored book in real time. It returns ErrBookNotFound if the book does not exist,
+// or is not of the publisher scope of ctx.
+func (es *ESClient) lookup(ctx context.Context, bookID string) (storedVersion, error) {
+	stored, found, err := es.read(ctx, bookID)
+	if err != nil {
+		return storedVersion{}, err
+	}
+	if !found || checkScope(ctx, Book{Publisher: stored.publisher}) != nil {
+		return storedVersion{}, ErrBookNotFound
+	}
+	return stored, nil
+}
+
+// read reads a stored book in real time, found is false if it does not exist.
+func (es *ESClient) read(ctx context.Context, bookID string) (stored storedVersion, found bool, err error) {
+	res, err := es.client.Get(es.index, bookID).
+		SourceIncludes_("publisher", "popularity").
+		Do(ctx)
+	if err != nil {
+		return storedVersion{}, false, fmt.Errorf("cannot read book %s: %w", bookID, err)
+	}
+	if !res.Found || res.SeqNo_ == nil || res.PrimaryTerm_ == nil {
+		return storedVersion{}, false, nil
+	}
+	var source struct {
+		Publisher  string   `json:"publisher"`
+		Popularity *float64 `json:"popularity"`
+	}
+	if err := json.Unmarshal(res.Source_, &source); err != nil {
+		return storedVersion{}, false, fmt.Errorf("cannot decode book %s: %w", bookID, err)
+	}
+	return storedVersion{
+		seqNo:       strconv.FormatInt(*res.SeqNo_, 10),
+		primaryTerm: strconv.FormatInt(*res.PrimaryTerm_, 10),
+		publisher:   source.Publisher,
+		popularity:  source.Popularity,
+	}, true, nil
+}
+
+// DeleteBooksByQuery deletes all books matching filter, which has the same format as in FilterBooks.
+// An empty filter is rejected rather than deleting every book. With a publisher scope,
+// only the books of the publisher are deleted.
+func (es *ESClient) DeleteBooksByQuery(ctx context.Context, filter map[string]any) (*deletebyquery.Response, error) {
+	query := filterQuery(filter)
+	if len(query.Bool.Must) == 0 {
+		return nil, ErrEmptyFilter
+	}
+	query.Bool.Filter = scopeFilter(ctx)
+
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	return es.client.DeleteByQuery(es.index).
+		Query(query).
+		Conflicts(conflicts.Proceed).
+		Do(ctx)
 }
 
 func (es *ESClient) GetBook(ctx context.Context, bookID string) (*search.Response, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
 	return es.client.Search().
-		Index("books").
+		Index(es.index).
 		Request(&search.Request{
 			Query: &types.Query{
 				Term: map[string]types.TermQuery{
@@ -72,30 +302,56 @@ func (es *ESClient) GetBook(ctx context.Context, bookID string) (*search.Respons
 					},
 				},
 			},
-			Size: func(i int) *int { return &i }(1), // Limit to 1 result
+			Source_: bookSource,
+			Size:    func(i int) *int { return &i }(1), // Limit to 1 result
 		}).Do(ctx)
 }
 
-func (es *ESClient) FilterBooks(ctx context.Context, filter map[string]any) (*search.Response, error) {
+func (es *ESClient) FilterBooks(ctx context.Context, filter map[string]any, page Page) (*search.Response, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	req := &search.Request{
+		Query:   filterQuery(filter),
+		Source_: bookSource,
+	}
+	page.apply(req)
+
+	return es.client.Search().
+		Index(es.index).
+		Request(req).
+		Do(ctx)
+}
+
+// filterQuery builds the bool query of the author, publisher, categories, language and release_after criteria of filter.
+// Authors and publishers match with or without diacritics, "Nguyen Nhat Anh" finds the books of "Nguyễn Nhật Ánh".
+// filter is normalized first, so that filters sharing a cache key find the same books.
+func filterQuery(filter map[string]any) *types.Query {
+	filter = NormalizeFilter(filter)
 	var mustClauses []types.Query
 
 	if author, ok := filter["author"].(string); ok {
 		mustClauses = append(mustClauses, types.Query{
-			Match: map[string]types.MatchQuery{
-				"author": {Query: author},
+			MultiMatch: &types.MultiMatchQuery{
+				Query:  author,
+				Fields: []string{"author", "author.folded"},
 			},
 		})
 	}
 
 	if publisher, ok := filter["publisher"].(string); ok {
 		mustClauses = append(mustClauses, types.Query{
-			Term: map[string]types.TermQuery{
-				"publisher.keyword": {Value: publisher},
+			Bool: &types.BoolQuery{
+				Should: []types.Query{
+					{Term: map[string]types.TermQuery{"publisher.keyword": {Value: publisher}}},
+					{Term: map[string]types.TermQuery{"publisher.normalized": {Value: publisher}}},
+				},
+				MinimumShouldMatch: 1,
 			},
 		})
 	}
 
-	if categories, ok := filter["categories"].([]string); ok && len(categories) > 0 {
+	if categories := stringSlice(filter["categories"]); len(categories) > 0 {
 		mustClauses = append(mustClauses, types.Query{
 			Terms: &types.TermsQuery{
 				TermsQuery: map[string]types.TermsQueryField{
@@ -105,6 +361,14 @@ func (es *ESClient) FilterBooks(ctx context.Context, filter map[string]any) (*se
 		})
 	}
 
+	if language, ok := filter["language"].(string); ok {
+		mustClauses = append(mustClauses, types.Query{
+			Term: map[string]types.TermQuery{
+				"language": {Value: language},
+			},
+		})
+	}
+
 	if releaseDateAfter, ok := filter["release_after"].(string); ok {
 		mustClauses = append(mustClauses, types.Query{
 			Range: map[string]types.RangeQuery{
@@ -115,27 +379,46 @@ func (es *ESClient) FilterBooks(ctx context.Context, filter map[string]any) (*se
 		})
 	}
 
-	return es.client.Search().
-		Index("books").
-		Request(&search.Request{
-			Query: &types.Query{
-				Bool: &types.BoolQuery{
-					Must: mustClauses,
-				},
-			},
-		}).
-		Do(ctx)
+	return &types.Query{
+		Bool: &types.BoolQuery{
+			Must: mustClauses,
+		},
+	}
+}
+
+// stringSlice returns the strings of v, which is a []string or, when decoded from JSON, a []any.
+// Values that are not strings are skipped.
+func stringSlice(v any) []string {
+	switch v := v.(type) {
+	case []string:
+		return v
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
 }
 
-func (es *ESClient) FullTextSearch(ctx context.Context, query string) (*search.Response, error) {
+// FullTextSearch finds the books matching a query string in the fields set with WithRelevance,
+// ranked as set with WithRanking.
+func (es *ESClient) FullTextSearch(ctx context.Context, query string, page Page) (*search.Response, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	req := &search.Request{
+		Query:   es.ranking.query(es.relevance.query(query)),
+		Source_: bookSource,
+	}
+	page.apply(req)
+
 	return es.client.Search().
-		Index("books").
-		Request(&search.Request{
-			Query: &types.Query{
-				QueryString: &types.QueryStringQuery{
-					Query: query,
-				},
-			},
-		}).
+		Index(es.index).
+		Request(req).
 		Do(ctx)
 }
@@ -33,7 +33,7 @@ func TestFilterBooks(t *testing.T) {
 		},
 	}
 
-	res, err := testClient.FilterBooks(context.Background(), filter)
+	res, err := testClient.FilterBooks(context.Background(), filter, Page{})
 	require.NoError(t, err)
 
 	// Check if we got results
@@ -43,6 +43,23 @@ func TestFilterBooks(t *testing.T) {
 	checkResult(t, res, book)
 }
 
+func TestFilterBooksTrimsCriteria(t *testing.T) {
+	book := createRandomBook()
+	_, err := testClient.AddBook(context.Background(), book)
+	require.NoError(t, err)
+	defer testClient.DeleteBook(context.Background(), book.ID)
+
+	time.Sleep(1 * time.Second)
+
+	// Both spellings share a cache key, so they must find the same books
+	res, err := testClient.FilterBooks(context.Background(), map[string]any{"publisher": " " + book.Publisher + " "}, Page{Size: 100})
+	require.NoError(t, err)
+	checkResult(t, res, book)
+	trimmed, err := testClient.FilterBooks(context.Background(), map[string]any{"publisher": book.Publisher}, Page{Size: 100})
+	require.NoError(t, err)
+	require.Equal(t, trimmed.Hits.Total.Value, res.Hits.Total.Value)
+}
+
 // TestFullTextSearch tests the full-text search functionality
 func TestFullTextSearch(t *testing.T) {
 	// Create a random book
@@ -58,7 +75,7 @@ func TestFullTextSearch(t *testing.T) {
 
 	// Perform full-text search on the book's name
 	query := fmt.Sprintf("name:%s", book.Name)
-	res, err := testClient.FullTextSearch(context.Background(), query)
+	res, err := testClient.FullTextSearch(context.Background(), query, Page{})
 	require.NoError(t, err)
 
 	// Check if we got results
@@ -86,7 +103,7 @@ func TestAddBook(t *testing.T) {
 	tes, ok := testClient.(*ESClient)
 	require.True(t, ok, "testClient is not of type *ESClient")
 
-	getRes, err := tes.client.Get("books", book.ID).Do(context.Background())
+	getRes, err := tes.client.Get(testClient.Index(), book.ID).Do(context.Background())
 	require.NoError(t, err)
 	require.True(t, getRes.Found)
 
@@ -102,6 +119,39 @@ func TestAddBook(t *testing.T) {
 	require.Equal(t, book.PageCount, got.PageCount)
 }
 
+func TestAddBookInScope(t *testing.T) {
+	book := createRandomBook()
+	_, err := testClient.AddBook(context.Background(), book)
+	require.NoError(t, err)
+	defer testClient.DeleteBook(context.Background(), book.ID)
+
+	// Another publisher cannot replace the book, even with a book of its own
+	scoped := WithPublisherScope(context.Background(), "Other "+book.Publisher)
+	replacement := book
+	replacement.Publisher = "Other " + book.Publisher
+	_, err = testClient.AddBook(scoped, replacement)
+	require.ErrorIs(t, err, ErrOutOfScope)
+
+	// The publisher of the book can
+	_, err = testClient.AddBook(WithPublisherScope(context.Background(), book.Publisher), book)
+	require.NoError(t, err)
+}
+
+func TestUpdateMissingBook(t *testing.T) {
+	book := createRandomBook()
+
+	// Updating a book that was never added does not create it
+	_, err := testClient.UpdateBook(context.Background(), book)
+	require.ErrorIs(t, err, ErrBookNotFound)
+
+	tes, ok := testClient.(*ESClient)
+	require.True(t, ok, "testClient is not of type *ESClient")
+
+	getRes, err := tes.client.Get(testClient.Index(), book.ID).Do(context.Background())
+	require.NoError(t, err)
+	require.False(t, getRes.Found)
+}
+
 func TestDeleteBook(t *testing.T) {
 	book := createRandomBook()
 
@@ -121,7 +171,7 @@ func TestDeleteBook(t *testing.T) {
 	tes, ok := testClient.(*ESClient)
 	require.True(t, ok, "testClient is not of type *ESClient")
 
-	getRes, err := tes.client.Get("books", book.ID).Do(context.Background())
+	getRes, err := tes.client.Get(testClient.Index(), book.ID).Do(context.Background())
 	require.NoError(t, err)
 	require.False(t, getRes.Found)
 }
@@ -149,6 +199,64 @@ func TestGetBook(t *testing.T) {
 	checkResult(t, res, book)
 }
 
+func TestScanBooks(t *testing.T) {
+	// Add books of a publisher only these books have
+	publisher := util.RandomString(12)
+	ids := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		book := createRandomBook()
+		book.Publisher = publisher
+		_, err := testClient.AddBook(context.Background(), book)
+		require.NoError(t, err)
+		defer testClient.DeleteBook(context.Background(), book.ID)
+		ids[book.ID] = true
+	}
+
+	// Wait a bit to ensure indexing (optional)
+	time.Sleep(1 * time.Second)
+
+	// Scan them in pages smaller than their number
+	scanned := make(map[string]bool)
+	pages := 0
+	err := testClient.ScanBooks(context.Background(), map[string]any{"publisher": publisher}, 2, func(books []Book) error {
+		pages++
+		require.LessOrEqual(t, len(books), 2)
+		for _, book := range books {
+			require.False(t, scanned[book.ID], "book %s scanned twice", book.ID)
+			scanned[book.ID] = true
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, ids, scanned)
+	require.Equal(t, 3, pages)
+}
+
+func TestSearchWithoutDiacritics(t *testing.T) {
+	book := createRandomBook()
+	book.Author = "Nguyễn Nhật Ánh"
+	book.Publisher = "Nhà Xuất Bản Trẻ"
+	book.Language = "vi"
+	_, err := testClient.AddBook(context.Background(), book)
+	require.NoError(t, err)
+	defer testClient.DeleteBook(context.Background(), book.ID)
+
+	time.Sleep(1 * time.Second)
+
+	res, err := testClient.FullTextSearch(context.Background(), fmt.Sprintf("%s Nguyen Nhat Anh", book.Name), Page{})
+	require.NoError(t, err)
+	require.NotEmpty(t, res.Hits.Hits)
+	checkResult(t, res, book)
+
+	res, err = testClient.FilterBooks(context.Background(), map[string]any{
+		"author":    "Nguyen Nhat Anh",
+		"publisher": "nha xuat ban tre",
+		"language":  "vi",
+	}, Page{})
+	require.NoError(t, err)
+	require.NotEmpty(t, res.Hits.Hits)
+}
+
 func createRandomBook() Book {
 	book := Book{
 		ID:          fmt.Sprintf("%d", rand.Int63()),
@@ -176,7 +284,7 @@ func checkResult(t *testing.T, res *search.Response, book Book) {
 		var got Book
 		err := json.Unmarshal(hit.Source_, &got)
 		require.NoError(t, err)
-		fmt.Println("Got book:", got, "from search:", book.ID)
+		t.Logf("got book %s from search for %s", got.ID, book.ID)
 
 		if got.ID == book.ID {
 			found = true
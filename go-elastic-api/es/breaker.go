@@ -0,0 +1,157 @@
+package es
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is matched by errors returned while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("elasticsearch circuit breaker is open")
+
+// CircuitOpenError is returned without contacting Elasticsearch while the circuit breaker is open.
+type CircuitOpenError struct {
+	// RetryAfter is the time left until the breaker lets a probe request through.
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrCircuitOpen, e.RetryAfter.Round(time.Second))
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker is an http.RoundTripper that stops sending requests to Elasticsearch
+// after a number of consecutive failed attempts, and fails fast until a cooldown has passed.
+// Then a single probe request is let through: it closes the breaker when it succeeds
+// and opens it again when it fails.
+//
+// Network errors and 502, 503 and 504 responses count as failures.
+// A 429 means the cluster is alive but busy, so it does not.
+type CircuitBreaker struct {
+	next      http.RoundTripper
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+// NewCircuitBreaker wraps next with a circuit breaker that opens after threshold
+// consecutive failures and stays open for cooldown.
+func NewCircuitBreaker(next http.RoundTripper, threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		next:      next,
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+func (b *CircuitBreaker) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+
+	res, err := b.next.RoundTrip(req)
+	b.record(req.Context(), res, err)
+	return res, err
+}
+
+// allow reports whether a request may be sent and moves an open breaker
+// to half-open once the cooldown has passed.
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		elapsed := b.now().Sub(b.openedAt)
+		if elapsed < b.cooldown {
+			return &CircuitOpenError{RetryAfter: b.cooldown - elapsed}
+		}
+		b.setState(breakerHalfOpen)
+		return nil
+	case breakerHalfOpen:
+		// A probe is in flight, the others wait for its outcome
+		return &CircuitOpenError{RetryAfter: time.Second}
+	default:
+		return nil
+	}
+}
+
+func (b *CircuitBreaker) record(ctx context.Context, res *http.Response, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// The caller giving up says nothing about the health of the cluster.
+	// A canceled probe lets the next request probe again.
+	if err != nil && ctx.Err() != nil {
+		if b.state == breakerHalfOpen {
+			b.setState(breakerOpen)
+			b.openedAt = b.now().Add(-b.cooldown)
+		}
+		return
+	}
+
+	failed := err != nil
+	if res != nil {
+		switch res.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			failed = true
+		}
+	}
+
+	if !failed {
+		b.failures = 0
+		b.setState(breakerClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.setState(breakerOpen)
+		b.openedAt = b.now()
+	}
+}
+
+// setState must be called with b.mu held.
+func (b *CircuitBreaker) setState(state breakerState) {
+	if b.state == state {
+		return
+	}
+	slog.Warn("elasticsearch circuit breaker state changed",
+		slog.String("from", b.state.String()),
+		slog.String("to", state.String()),
+		slog.Int("consecutive_failures", b.failures),
+	)
+	b.state = state
+}
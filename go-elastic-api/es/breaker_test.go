@@ -0,0 +1,100 @@
+package es
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// stubTransport answers every request with the given status, or fails when status is 0.
+type stubTransport struct {
+	status int
+	calls  int
+}
+
+func (s *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.calls++
+	if s.status == 0 {
+		return nil, errors.New("connection refused")
+	}
+	return &http.Response{StatusCode: s.status, Body: http.NoBody}, nil
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	stub := &stubTransport{}
+	breaker := NewCircuitBreaker(stub, 3, 30*time.Second)
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+
+	send := func() error {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:9200/books/_search", nil)
+		res, err := breaker.RoundTrip(req)
+		if res != nil {
+			res.Body.Close()
+		}
+		return err
+	}
+
+	// 1. Consecutive failures open the breaker
+	for i := 0; i < 3; i++ {
+		require.Error(t, send())
+	}
+	require.Equal(t, 3, stub.calls)
+
+	// 2. While open, requests fail fast without reaching the cluster
+	now = now.Add(10 * time.Second)
+	err := send()
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	var openErr *CircuitOpenError
+	require.ErrorAs(t, err, &openErr)
+	require.Equal(t, 20*time.Second, openErr.RetryAfter)
+	require.Equal(t, 3, stub.calls)
+
+	// 3. After the cooldown a failing probe opens it again
+	now = now.Add(20 * time.Second)
+	stub.status = http.StatusServiceUnavailable
+	require.NoError(t, send())
+	require.Equal(t, 4, stub.calls)
+	require.ErrorIs(t, send(), ErrCircuitOpen)
+
+	// 4. A successful probe closes it
+	now = now.Add(30 * time.Second)
+	stub.status = http.StatusOK
+	require.NoError(t, send())
+	require.NoError(t, send())
+	require.Equal(t, 6, stub.calls)
+}
+
+func TestCircuitBreakerIgnoresTooManyRequests(t *testing.T) {
+	stub := &stubTransport{status: http.StatusTooManyRequests}
+	breaker := NewCircuitBreaker(stub, 1, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:9200/", nil)
+		_, err := breaker.RoundTrip(req)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 3, stub.calls)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	backoff := RetryBackoff(100*time.Millisecond, time.Second)
+
+	for attempt, want := range map[int]time.Duration{
+		1:  100 * time.Millisecond,
+		2:  200 * time.Millisecond,
+		3:  400 * time.Millisecond,
+		5:  time.Second,
+		64: time.Second,
+	} {
+		for i := 0; i < 20; i++ {
+			d := backoff(attempt)
+			require.GreaterOrEqual(t, d, want/2, "attempt %d", attempt)
+			require.LessOrEqual(t, d, want, "attempt %d", attempt)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package es
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go-elastic-api/cache"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/bulk"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/create"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/delete"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/deletebyquery"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/index"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/reindex"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	indicesdelete "github.com/elastic/go-elasticsearch/v8/typedapi/indices/delete"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/synonyms/putsynonym"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// cachedClient serves FullTextSearch, SemanticSearch, HybridSearch, SimilarBooks and FilterBooks from a cache.Store.
+// Every write made through it refreshes the index, then purges the store, so readers see their own writes.
+// A search that ran while a write was made is not cached, its hits may predate the write.
+// Writes made by other means, or searches racing a write of another instance sharing the store,
+// are seen after the TTL. The methods it does not override are passed to the embedded Client.
+type cachedClient struct {
+	Client
+
+	store    cache.Store
+	ttl      time.Duration
+	requests *prometheus.CounterVec
+
+	// generation counts the writes made through the client
+	generation atomic.Uint64
+}
+
+// Cached wraps a Client so that searches are cached in store for ttl.
+// Cache hits and misses are counted in a cache_requests_total metric registered with reg.
+// Errors of the store are logged and the call goes to the wrapped Client.
+func Cached(next Client, store cache.Store, ttl time.Duration, reg prometheus.Registerer) Client {
+	c := &cachedClient{
+		Client: next,
+		store:  store,
+		ttl:    ttl,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_requests_total",
+			Help: "Number of cacheable es.Client calls by method and result: hit, miss or error.",
+		}, []string{"method", "result"}),
+	}
+	reg.MustRegister(c.requests)
+	return c
+}
+
+func (c *cachedClient) FullTextSearch(ctx context.Context, query string, page Page) (*search.Response, error) {
+	key := cacheKey("FullTextSearch", c.Index(), strings.Join(strings.Fields(query), " "), page)
+	return c.search(ctx, "FullTextSearch", key, func() (*search.Response, error) {
+		return c.Client.FullTextSearch(ctx, query, page)
+	})
+}
+
+func (c *cachedClient) SemanticSearch(ctx context.Context, query string, page Page) (*search.Response, error) {
+	key := cacheKey("SemanticSearch", c.Index(), strings.Join(strings.Fields(query), " "), page)
+	return c.search(ctx, "SemanticSearch", key, func() (*search.Response, error) {
+		return c.Client.SemanticSearch(ctx, query, page)
+	})
+}
+
+func (c *cachedClient) HybridSearch(ctx context.Context, query string, page Page) (*search.Response, error) {
+	key := cacheKey("HybridSearch", c.Index(), strings.Join(strings.Fields(query), " "), page)
+	return c.search(ctx, "HybridSearch", key, func() (*search.Response, error) {
+		return c.Client.HybridSearch(ctx, query, page)
+	})
+}
+
+func (c *cachedClient) SimilarBooks(ctx context.Context, bookID string, filter SimilarFilter, page Page) (*search.Response, error) {
+	key := cacheKey("SimilarBooks", c.Index(), map[string]any{"id": bookID, "filter": filter}, page)
+	return c.search(ctx, "SimilarBooks", key, func() (*search.Response, error) {
+		return c.Client.SimilarBooks(ctx, bookID, filter, page)
+	})
+}
+
+func (c *cachedClient) FilterBooks(ctx context.Context, filter map[string]any, page Page) (*search.Response, error) {
+	key := cacheKey("FilterBooks", c.Index(), NormalizeFilter(filter), page)
+	return c.search(ctx, "FilterBooks", key, func() (*search.Response, error) {
+		return c.Client.FilterBooks(ctx, filter, page)
+	})
+}
+
+func (c *cachedClient) search(ctx context.Context, method, key string, next func() (*search.Response, error)) (*search.Response, error) {
+	generation := c.generation.Load()
+	value, ok, err := c.store.Get(ctx, key)
+	if err != nil {
+		c.requests.WithLabelValues(method, "error").Inc()
+		slog.WarnContext(ctx, "cannot read from cache", slog.String("method", method), slog.String("error", err.Error()))
+	}
+	if ok {
+		var res search.Response
+		if err := json.Unmarshal(value, &res); err == nil {
+			c.requests.WithLabelValues(method, "hit").Inc()
+			return &res, nil
+		}
+	}
+	if err == nil {
+		c.requests.WithLabelValues(method, "miss").Inc()
+	}
+
+	res, err := next()
+	if err != nil {
+		return res, err
+	}
+	if c.generation.Load() != generation {
+		return res, nil
+	}
+	if value, err := json.Marshal(res); err == nil {
+		if err := c.store.Set(ctx, key, value, c.ttl); err != nil {
+			slog.WarnContext(ctx, "cannot write to cache", slog.String("method", method), slog.String("error", err.Error()))
+		}
+	}
+	return res, nil
+}
+
+// purge drops the cached searches after a write, even a failed one since it may have been applied in part.
+// The index is refreshed first, so that the searches cached next see the write, and the searches
+// that ran meanwhile are not cached. It runs even when the request was canceled,
+// stale entries must not outlive the write.
+func (c *cachedClient) purge(ctx context.Context) {
+	ctx = context.WithoutCancel(ctx)
+	if _, err := c.Client.RefreshIndex(ctx, c.Index()); err != nil {
+		slog.WarnContext(ctx, "cannot refresh index after write", slog.String("index", c.Index()), slog.String("error", err.Error()))
+	}
+	c.generation.Add(1)
+	if err := c.store.Purge(ctx); err != nil {
+		slog.ErrorContext(ctx, "cannot purge cache after write", slog.String("error", err.Error()))
+	}
+}
+
+func (c *cachedClient) AddBook(ctx context.Context, book Book) (*index.Response, error) {
+	defer c.purge(ctx)
+	return c.Client.AddBook(ctx, book)
+}
+
+func (c *cachedClient) CreateBook(ctx context.Context, book Book) (*create.Response, error) {
+	defer c.purge(ctx)
+	return c.Client.CreateBook(ctx, book)
+}
+
+func (c *cachedClient) UpdateBook(ctx context.Context, book Book) (*index.Response, error) {
+	defer c.purge(ctx)
+	return c.Client.UpdateBook(ctx, book)
+}
+
+func (c *cachedClient) BulkAddBooks(ctx context.Context, books []Book) (*bulk.Response, error) {
+	defer c.purge(ctx)
+	return c.Client.BulkAddBooks(ctx, books)
+}
+
+// UpdatePopularity changes the ranking of full text search, so cached results are stale too.
+func (c *cachedClient) UpdatePopularity(ctx context.Context, popularity map[string]float64) (*bulk.Response, error) {
+	defer c.purge(ctx)
+	return c.Client.UpdatePopularity(ctx, popularity)
+}
+
+// UpdateEmbeddings changes what semantic search finds, so cached results are stale too.
+func (c *cachedClient) UpdateEmbeddings(ctx context.Context, books []Book) (*bulk.Response, error) {
+	defer c.purge(ctx)
+	return c.Client.UpdateEmbeddings(ctx, books)
+}
+
+// PutSynonyms changes what queries match, so cached results are stale too.
+func (c *cachedClient) PutSynonyms(ctx context.Context, set string, rules []string) (*putsynonym.Response, error) {
+	defer c.purge(ctx)
+	return c.Client.PutSynonyms(ctx, set, rules)
+}
+
+func (c *cachedClient) DeleteBook(ctx context.Context, bookID string) (*delete.Response, error) {
+	defer c.purge(ctx)
+	return c.Client.DeleteBook(ctx, bookID)
+}
+
+func (c *cachedClient) DeleteBooksByQuery(ctx context.Context, filter map[string]any) (*deletebyquery.Response, error) {
+	defer c.purge(ctx)
+	return c.Client.DeleteBooksByQuery(ctx, filter)
+}
+
+func (c *cachedClient) DeleteIndex(ctx context.Context, name string) (*indicesdelete.Response, error) {
+	defer c.purge(ctx)
+	return c.Client.DeleteIndex(ctx, name)
+}
+
+func (c *cachedClient) Reindex(ctx context.Context, source, dest string) (*reindex.Response, error) {
+	defer c.purge(ctx)
+	return c.Client.Reindex(ctx, source, dest)
+}
+
+func (c *cachedClient) SwapAlias(ctx context.Context, alias, index string) ([]string, error) {
+	defer c.purge(ctx)
+	return c.Client.SwapAlias(ctx, alias, index)
+}
+
+// cacheKey hashes the normalized arguments of a call, so equivalent calls share an entry.
+func cacheKey(method, index string, args any, page Page) string {
+	b, _ := json.Marshal(struct {
+		Args any  `json:"args"`
+		Page Page `json:"page"`
+	}{args, page})
+	digest := sha256.Sum256(b)
+	return method + ":" + index + ":" + hex.EncodeToString(digest[:])
+}
+
+// NormalizeFilter keeps the criteria used by FilterBooks, with surrounding spaces
+// trimmed and categories sorted, since their order does not change the result.
+// Both the cache key and the query of a filter are built from its normalized form.
+func NormalizeFilter(filter map[string]any) map[string]any {
+	normalized := make(map[string]any)
+	for _, key := range []string{"author", "publisher", "language", "release_after"} {
+		if v, ok := filter[key].(string); ok {
+			normalized[key] = strings.TrimSpace(v)
+		}
+	}
+	if categories := stringSlice(filter["categories"]); len(categories) > 0 {
+		categories = slices.Clone(categories)
+		slices.Sort(categories)
+		normalized["categories"] = slices.Compact(categories)
+	}
+	return normalized
+}
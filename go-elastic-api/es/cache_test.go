@@ -0,0 +1,120 @@
+package es
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-elastic-api/cache"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/delete"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/indices/refresh"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// writableFakeClient adds DeleteBook and RefreshIndex to fakeClient.
+type writableFakeClient struct {
+	*fakeClient
+}
+
+func (f writableFakeClient) DeleteBook(ctx context.Context, bookID string) (*delete.Response, error) {
+	return &delete.Response{Id_: bookID}, nil
+}
+
+func (f writableFakeClient) RefreshIndex(ctx context.Context, name string) (*refresh.Response, error) {
+	return &refresh.Response{}, nil
+}
+
+// racingFakeClient runs write while FullTextSearch is served, as a concurrent request would.
+type racingFakeClient struct {
+	writableFakeClient
+	write func()
+}
+
+func (f racingFakeClient) FullTextSearch(ctx context.Context, query string, page Page) (*search.Response, error) {
+	res, err := f.fakeClient.FullTextSearch(ctx, query, page)
+	if f.write != nil {
+		f.write()
+	}
+	return res, err
+}
+
+func TestCachedClient(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeClient{res: newSearchResponse(12, 3)}
+	reg := prometheus.NewRegistry()
+	client := Cached(writableFakeClient{fake}, cache.NewLRU(10), time.Minute, reg)
+
+	// 1. The second call, with the same query up to spaces, is served from the cache
+	res, err := client.FullTextSearch(ctx, "name:dune", Page{Size: 10})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), res.Hits.Total.Value)
+
+	res, err = client.FullTextSearch(ctx, "  name:dune ", Page{Size: 10})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), res.Hits.Total.Value)
+	require.Equal(t, 1, fake.searches)
+
+	// 2. Another page is another entry
+	_, err = client.FullTextSearch(ctx, "name:dune", Page{From: 10, Size: 10})
+	require.NoError(t, err)
+	require.Equal(t, 2, fake.searches)
+
+	// 3. A write purges the cache
+	_, err = client.DeleteBook(ctx, "42")
+	require.NoError(t, err)
+	_, err = client.FullTextSearch(ctx, "name:dune", Page{Size: 10})
+	require.NoError(t, err)
+	require.Equal(t, 3, fake.searches)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(client.(*cachedClient).requests.WithLabelValues("FullTextSearch", "hit")))
+	require.Equal(t, 3.0, testutil.ToFloat64(client.(*cachedClient).requests.WithLabelValues("FullTextSearch", "miss")))
+}
+
+func TestCachedClientSkipsSearchesRacingWrites(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeClient{res: newSearchResponse(12, 3)}
+	racing := &racingFakeClient{writableFakeClient: writableFakeClient{fake}}
+	client := Cached(racing, cache.NewLRU(10), time.Minute, prometheus.NewRegistry())
+
+	// The hits of a search that ran while a book was deleted are returned, but not cached
+	racing.write = func() {
+		_, err := client.DeleteBook(ctx, "42")
+		require.NoError(t, err)
+	}
+	_, err := client.FullTextSearch(ctx, "name:dune", Page{Size: 10})
+	require.NoError(t, err)
+
+	racing.write = nil
+	fake.res = newSearchResponse(12, 2)
+	res, err := client.FullTextSearch(ctx, "name:dune", Page{Size: 10})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), res.Hits.Total.Value)
+	require.Equal(t, 2, fake.searches)
+
+	// Without a write, the next search is served from the cache
+	_, err = client.FullTextSearch(ctx, "name:dune", Page{Size: 10})
+	require.NoError(t, err)
+	require.Equal(t, 2, fake.searches)
+}
+
+func TestNormalizeFilter(t *testing.T) {
+	a := cacheKey("FilterBooks", "books", NormalizeFilter(map[string]any{
+		"author":     " Frank Herbert",
+		"categories": []any{"Fiction", "Classics", "Fiction"},
+		"unknown":    true,
+	}), Page{})
+	b := cacheKey("FilterBooks", "books", NormalizeFilter(map[string]any{
+		"categories": []any{"Classics", "Fiction"},
+		"author":     "Frank Herbert ",
+	}), Page{})
+	require.Equal(t, a, b)
+
+	c := cacheKey("FilterBooks", "books", NormalizeFilter(map[string]any{
+		"author": "Frank Herbert",
+	}), Page{})
+	require.NotEqual(t, a, c)
+}
@@ -2,27 +2,164 @@ package es
 
 import (
 	"context"
+	"time"
+
+	"go-elastic-api/embedding"
 
 	"github.com/elastic/go-elasticsearch/v8"
+	catindices "github.com/elastic/go-elasticsearch/v8/typedapi/cat/indices"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/cluster/health"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/bulk"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/create"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/core/delete"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/deletebyquery"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/core/index"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/reindex"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/ilm/putlifecycle"
+	indicescreate "github.com/elastic/go-elasticsearch/v8/typedapi/indices/create"
+	indicesdelete "github.com/elastic/go-elasticsearch/v8/typedapi/indices/delete"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/indices/refresh"
+	snapshotcreate "github.com/elastic/go-elasticsearch/v8/typedapi/snapshot/create"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/snapshot/createrepository"
+	snapshotdelete "github.com/elastic/go-elasticsearch/v8/typedapi/snapshot/delete"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/snapshot/restore"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/synonyms/putsynonym"
+	taskget "github.com/elastic/go-elasticsearch/v8/typedapi/tasks/get"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
 )
 
+// DefaultBooksIndex is the index (or alias) holding the book documents
+// when no other name is configured.
+const DefaultBooksIndex = "books"
+
 type Client interface {
+	// Index returns the index or alias books are read from and written to.
+	Index() string
+
+	BookStore
+	IndexAdmin
+	SnapshotAdmin
+	LifecycleAdmin
+	EventStore
+	Cluster
+}
+
+// BookStore reads and writes book documents.
+// Writes are restricted to the publisher set with WithPublisherScope, if any.
+type BookStore interface {
 	AddBook(ctx context.Context, book Book) (*index.Response, error)
+	CreateBook(ctx context.Context, book Book) (*create.Response, error)
+	UpdateBook(ctx context.Context, book Book) (*index.Response, error)
+	BulkAddBooks(ctx context.Context, books []Book) (*bulk.Response, error)
 	DeleteBook(ctx context.Context, bookID string) (*delete.Response, error)
 	GetBook(ctx context.Context, bookID string) (*search.Response, error)
-	FilterBooks(ctx context.Context, filter map[string]any) (*search.Response, error)
-	FullTextSearch(ctx context.Context, query string) (*search.Response, error)
+	FilterBooks(ctx context.Context, filter map[string]any, page Page) (*search.Response, error)
+	FullTextSearch(ctx context.Context, query string, page Page) (*search.Response, error)
+	SemanticSearch(ctx context.Context, query string, page Page) (*search.Response, error)
+	HybridSearch(ctx context.Context, query string, page Page) (*search.Response, error)
+	SimilarBooks(ctx context.Context, bookID string, filter SimilarFilter, page Page) (*search.Response, error)
+	UpdatePopularity(ctx context.Context, popularity map[string]float64) (*bulk.Response, error)
+	UpdateEmbeddings(ctx context.Context, books []Book) (*bulk.Response, error)
+	ScanBooks(ctx context.Context, filter map[string]any, batchSize int, fn func([]Book) error) error
+}
+
+// IndexAdmin manages indices and bulk changes to their documents.
+type IndexAdmin interface {
+	CreateIndex(ctx context.Context, name string) (*indicescreate.Response, error)
+	DeleteIndex(ctx context.Context, name string) (*indicesdelete.Response, error)
+	RefreshIndex(ctx context.Context, name string) (*refresh.Response, error)
+	Reindex(ctx context.Context, source, dest string) (*reindex.Response, error)
+	DeleteBooksByQuery(ctx context.Context, filter map[string]any) (*deletebyquery.Response, error)
+	IndexStatus(ctx context.Context, name string) (catindices.Response, error)
+	IndexMapping(ctx context.Context, name string) (map[string]types.TypeMapping, error)
+	SwapAlias(ctx context.Context, alias, index string) ([]string, error)
+	TaskStatus(ctx context.Context, taskID string) (*taskget.Response, error)
+	Synonyms(ctx context.Context, set string) ([]types.SynonymRuleRead, error)
+	PutSynonyms(ctx context.Context, set string, rules []string) (*putsynonym.Response, error)
+}
+
+// SnapshotAdmin backs indices up to a snapshot repository and restores them.
+type SnapshotAdmin interface {
+	RegisterSnapshotRepository(ctx context.Context, repository, location string) (*createrepository.Response, error)
+	CreateSnapshot(ctx context.Context, repository, snapshot string, indices []string) (*snapshotcreate.Response, error)
+	Snapshots(ctx context.Context, repository string, names ...string) ([]types.SnapshotInfo, error)
+	DeleteSnapshot(ctx context.Context, repository, snapshot string) (*snapshotdelete.Response, error)
+	RestoreSnapshot(ctx context.Context, repository, snapshot, index, target string) (*restore.Response, error)
+}
+
+// LifecycleAdmin manages time series indices, such as events, written through a rollover alias.
+type LifecycleAdmin interface {
+	PutLifecyclePolicy(ctx context.Context, name string, policy LifecyclePolicy) (*putlifecycle.Response, error)
+	BootstrapRolloverAlias(ctx context.Context, alias, policy string) (bool, error)
+	ManagedIndices(ctx context.Context, alias string) ([]ManagedIndex, error)
+}
+
+// EventStore writes events, such as searches, to a rollover alias and sums them up.
+type EventStore interface {
+	IndexEvents(ctx context.Context, alias string, events []any) (*bulk.Response, error)
+	TopQueries(ctx context.Context, alias string, since time.Time, size int) ([]QueryStats, error)
+	ZeroResultQueries(ctx context.Context, alias string, since time.Time, size int) ([]QueryStats, error)
+	ClickThroughRate(ctx context.Context, alias string, since time.Time, interval time.Duration) ([]ClickThroughBucket, error)
+	BookClicks(ctx context.Context, alias string, since time.Time) (map[string]int64, error)
+}
+
+// Cluster reports the state of the cluster.
+type Cluster interface {
+	Ping(ctx context.Context) (bool, error)
+	ClusterHealth(ctx context.Context) (*health.Response, error)
+	IndexExists(ctx context.Context, name string) (bool, error)
 }
 
 type ESClient struct {
-	client *elasticsearch.TypedClient
+	client  *elasticsearch.TypedClient
+	index   string
+	timeout time.Duration
+
+	relevance  Relevance
+	ranking    Ranking
+	embedder   embedding.Embedder
+	similarity Similarity
+}
+
+// Option configures an ESClient.
+type Option func(*ESClient)
+
+// WithIndex sets the index or alias used for books.
+func WithIndex(name string) Option {
+	return func(es *ESClient) {
+		es.index = name
+	}
+}
+
+// WithRequestTimeout bounds every call by timeout, unless the caller's context
+// already has an earlier deadline. A zero timeout leaves calls bounded by the context only.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(es *ESClient) {
+		es.timeout = timeout
+	}
+}
+
+func NewClient(client *elasticsearch.TypedClient, opts ...Option) Client {
+	es := &ESClient{
+		client:    client,
+		index:     DefaultBooksIndex,
+		relevance: DefaultRelevance(),
+		embedder:  embedding.Local{},
+	}
+	for _, opt := range opts {
+		opt(es)
+	}
+	return es
+}
+
+func (es *ESClient) Index() string {
+	return es.index
 }
 
-func NewClient(client *elasticsearch.TypedClient) Client {
-	return &ESClient{
-		client: client,
+func (es *ESClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if es.timeout <= 0 {
+		return ctx, func() {}
 	}
+	return context.WithTimeout(ctx, es.timeout)
 }
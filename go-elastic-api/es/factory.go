@@ -0,0 +1,154 @@
+package es
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go-elastic-api/util"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// retryOnStatus lists the response statuses worth retrying: a full write or search queue (429)
+// and a node that is restarting or behind an unhealthy proxy (502, 503, 504).
+var retryOnStatus = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryBackoff returns an exponential backoff with jitter: the n-th retry waits a random duration
+// between half and all of base * 2^(n-1), capped at maxBackoff. The jitter spreads retries of
+// concurrent requests so they do not hit a recovering node at the same time.
+func RetryBackoff(base, maxBackoff time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		if base <= 0 {
+			return 0
+		}
+		backoff := maxBackoff
+		if attempt < 32 {
+			if d := base << (attempt - 1); d > 0 && d < maxBackoff {
+				backoff = d
+			}
+		}
+		half := backoff / 2
+		return half + rand.N(backoff-half+1)
+	}
+}
+
+// NewTypedClient creates the Elasticsearch typed client described by cfg.
+// It is the single place where addresses, credentials, TLS, retry and circuit breaker settings are applied,
+// and is shared by the server, the tests and the tools.
+func NewTypedClient(cfg util.Config) (*elasticsearch.TypedClient, error) {
+	transport, err := newHTTPTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// The breaker sits closest to the network so that it sees every retry attempt
+	var roundTripper http.RoundTripper = transport
+	if cfg.ElasticsearchBreakerThreshold > 0 {
+		roundTripper = NewCircuitBreaker(roundTripper, cfg.ElasticsearchBreakerThreshold, cfg.ElasticsearchBreakerCooldown)
+	}
+
+	client, err := elasticsearch.NewTypedClient(elasticsearch.Config{
+		Addresses: cfg.ElasticsearchAddresses,
+		CloudID:   cfg.ElasticsearchCloudID,
+		Username:  cfg.ElasticsearchUsername,
+		Password:  cfg.ElasticsearchPassword,
+		APIKey:    cfg.ElasticsearchAPIKey,
+
+		RetryOnStatus: retryOnStatus,
+		MaxRetries:    cfg.ElasticsearchMaxRetries,
+		RetryBackoff:  RetryBackoff(cfg.ElasticsearchRetryBackoff, cfg.ElasticsearchRetryMaxBackoff),
+		RetryOnError: func(req *http.Request, err error) bool {
+			return !errors.Is(err, ErrCircuitOpen) && req.Context().Err() == nil
+		},
+
+		DiscoverNodesOnStart:  cfg.ElasticsearchDiscoverNodesOnStart,
+		DiscoverNodesInterval: cfg.ElasticsearchDiscoverNodesInterval,
+
+		// Spans come from the Tracing observer, the client is not instrumented too, which would nest a span in each
+		Transport: RequestIDTransport(TraceContextTransport(roundTripper)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot create Elasticsearch client: %w", err)
+	}
+	return client, nil
+}
+
+// newHTTPTransport creates the HTTP transport with the TLS settings of cfg:
+// a custom CA, certificate fingerprint pinning and a client certificate.
+func newHTTPTransport(cfg util.Config) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.ElasticsearchCACert != "" {
+		pem, err := os.ReadFile(cfg.ElasticsearchCACert)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificate found in %s", cfg.ElasticsearchCACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ElasticsearchClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ElasticsearchClientCert, cfg.ElasticsearchClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if cfg.ElasticsearchCertFingerprint != "" {
+		fingerprint, err := ParseFingerprint(cfg.ElasticsearchCertFingerprint)
+		if err != nil {
+			return nil, err
+		}
+		// Without a CA the chain cannot be verified, the pinned fingerprint is what is trusted.
+		// With a CA the chain is verified first and the fingerprint must match as well.
+		if tlsConfig.RootCAs == nil {
+			tlsConfig.InsecureSkipVerify = true
+		}
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyFingerprint(cs.PeerCertificates, fingerprint)
+		}
+	}
+
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// ParseFingerprint decodes a SHA-256 certificate fingerprint given as hex,
+// with or without colons, e.g. as printed by openssl x509 -fingerprint -sha256.
+func ParseFingerprint(s string) ([]byte, error) {
+	fingerprint, err := hex.DecodeString(strings.ReplaceAll(s, ":", ""))
+	if err != nil || len(fingerprint) != sha256.Size {
+		return nil, fmt.Errorf("certificate fingerprint must be a hex encoded SHA-256 digest, got %q", s)
+	}
+	return fingerprint, nil
+}
+
+func verifyFingerprint(certs []*x509.Certificate, fingerprint []byte) error {
+	for _, cert := range certs {
+		digest := sha256.Sum256(cert.Raw)
+		if bytes.Equal(digest[:], fingerprint) {
+			return nil
+		}
+	}
+	return errors.New("no certificate presented by the server matches the pinned fingerprint")
+}
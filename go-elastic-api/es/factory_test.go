@@ -0,0 +1,143 @@
+package es
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-elastic-api/util"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTLSCluster starts an HTTPS server answering like Elasticsearch and
+// records the Authorization header of the last request.
+func newTLSCluster(t *testing.T) (*httptest.Server, *string) {
+	var authorization string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.WriteHeader(http.StatusOK)
+	}))
+	// Rejected handshakes are expected, keep them out of the test output
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv, &authorization
+}
+
+func writeCACert(t *testing.T, srv *httptest.Server) string {
+	path := filepath.Join(t.TempDir(), "ca.crt")
+	block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.NoError(t, os.WriteFile(path, block, 0o600))
+	return path
+}
+
+func fingerprintOf(srv *httptest.Server) string {
+	digest := sha256.Sum256(srv.Certificate().Raw)
+	return hex.EncodeToString(digest[:])
+}
+
+func TestNewTypedClientTLS(t *testing.T) {
+	srv, authorization := newTLSCluster(t)
+
+	testCases := []struct {
+		name    string
+		modify  func(cfg *util.Config)
+		wantOK  bool
+		wantHdr string
+	}{
+		{
+			name:   "UntrustedCertificate",
+			modify: func(cfg *util.Config) {},
+		},
+		{
+			name:   "CustomCA",
+			modify: func(cfg *util.Config) { cfg.ElasticsearchCACert = writeCACert(t, srv) },
+			wantOK: true,
+		},
+		{
+			name:   "PinnedFingerprint",
+			modify: func(cfg *util.Config) { cfg.ElasticsearchCertFingerprint = fingerprintOf(srv) },
+			wantOK: true,
+		},
+		{
+			name: "WrongFingerprint",
+			modify: func(cfg *util.Config) {
+				cfg.ElasticsearchCertFingerprint = hex.EncodeToString(make([]byte, sha256.Size))
+			},
+		},
+		{
+			name: "BasicAuth",
+			modify: func(cfg *util.Config) {
+				cfg.ElasticsearchCACert = writeCACert(t, srv)
+				cfg.ElasticsearchUsername = "elastic"
+				cfg.ElasticsearchPassword = "changeme"
+			},
+			wantOK:  true,
+			wantHdr: "Basic ZWxhc3RpYzpjaGFuZ2VtZQ==",
+		},
+		{
+			name: "APIKey",
+			modify: func(cfg *util.Config) {
+				cfg.ElasticsearchCACert = writeCACert(t, srv)
+				cfg.ElasticsearchAPIKey = "a2V5OnNlY3JldA=="
+			},
+			wantOK:  true,
+			wantHdr: "APIKey a2V5OnNlY3JldA==",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := util.DefaultConfig()
+			cfg.ElasticsearchAddresses = []string{srv.URL}
+			cfg.ElasticsearchMaxRetries = 0
+			tc.modify(&cfg)
+
+			client, err := NewTypedClient(cfg)
+			require.NoError(t, err)
+
+			ok, err := client.Ping().IsSuccess(context.Background())
+			if !tc.wantOK {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, ok)
+			if tc.wantHdr != "" {
+				require.Equal(t, tc.wantHdr, *authorization)
+			}
+		})
+	}
+}
+
+func TestParseFingerprint(t *testing.T) {
+	digest := sha256.Sum256([]byte("certificate"))
+	plain := hex.EncodeToString(digest[:])
+
+	var colons string
+	for i := 0; i < len(plain); i += 2 {
+		if i > 0 {
+			colons += ":"
+		}
+		colons += plain[i : i+2]
+	}
+
+	for _, s := range []string{plain, colons} {
+		fingerprint, err := ParseFingerprint(s)
+		require.NoError(t, err)
+		require.Equal(t, digest[:], fingerprint)
+	}
+
+	_, err := ParseFingerprint("abcd")
+	require.Error(t, err)
+}
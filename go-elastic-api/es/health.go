@@ -0,0 +1,31 @@
+package es
+
+import (
+	"context"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/cluster/health"
+)
+
+// Ping reports whether the cluster answers a HEAD / request.
+func (es *ESClient) Ping(ctx context.Context) (bool, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	return es.client.Ping().IsSuccess(ctx)
+}
+
+// ClusterHealth returns the cluster health, including its status color.
+func (es *ESClient) ClusterHealth(ctx context.Context) (*health.Response, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	return es.client.Cluster.Health().Do(ctx)
+}
+
+// IndexExists reports whether an index or an alias with the given name exists.
+func (es *ESClient) IndexExists(ctx context.Context, name string) (bool, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	return es.client.Indices.Exists(name).IsSuccess(ctx)
+}
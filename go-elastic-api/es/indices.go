@@ -0,0 +1,149 @@
+package es
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"slices"
+
+	catindices "github.com/elastic/go-elasticsearch/v8/typedapi/cat/indices"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/reindex"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/indices/create"
+	indicesdelete "github.com/elastic/go-elasticsearch/v8/typedapi/indices/delete"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/indices/refresh"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/indices/updatealiases"
+	taskget "github.com/elastic/go-elasticsearch/v8/typedapi/tasks/get"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+)
+
+// booksIndex holds the settings and mappings of a books index.
+// Fields are mapped explicitly, so a misspelled field is rejected instead of silently indexed.
+//
+//go:embed books_index.json
+var booksIndex []byte
+
+// BooksIndexDefinition returns the settings and mappings used by CreateIndex as JSON.
+func BooksIndexDefinition() []byte {
+	return bytes.Clone(booksIndex)
+}
+
+// CreateIndex creates an index with the books settings and mappings, and the stopwords set with WithRelevance.
+// The books synonyms set is created first, with the synonyms set with WithRelevance, unless it exists,
+// and the language pipeline is put, so that the index can be written to right away.
+func (es *ESClient) CreateIndex(ctx context.Context, name string) (*create.Response, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	definition, err := es.relevance.indexDefinition()
+	if err != nil {
+		return nil, err
+	}
+	if err := es.ensureSynonyms(ctx); err != nil {
+		return nil, err
+	}
+	if err := es.putLanguagePipeline(ctx); err != nil {
+		return nil, err
+	}
+	return es.client.Indices.Create(name).
+		Raw(bytes.NewReader(definition)).
+		Do(ctx)
+}
+
+// DeleteIndex deletes an index. Aliases cannot be deleted this way.
+func (es *ESClient) DeleteIndex(ctx context.Context, name string) (*indicesdelete.Response, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	return es.client.Indices.Delete(name).Do(ctx)
+}
+
+// RefreshIndex makes the writes to an index or alias visible to searches.
+func (es *ESClient) RefreshIndex(ctx context.Context, name string) (*refresh.Response, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	return es.client.Indices.Refresh().Index(name).Do(ctx)
+}
+
+// Reindex starts copying all documents of source into dest and returns without waiting,
+// the response holds the ID of the task to follow with the tasks API.
+func (es *ESClient) Reindex(ctx context.Context, source, dest string) (*reindex.Response, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	return es.client.Reindex().
+		Source(&types.ReindexSource{Index: []string{source}}).
+		Dest(&types.ReindexDestination{Index: dest}).
+		WaitForCompletion(false).
+		Do(ctx)
+}
+
+// IndexStatus returns the health, document count and size of the indices matching name,
+// which may be an index, an alias or a pattern.
+func (es *ESClient) IndexStatus(ctx context.Context, name string) (catindices.Response, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	return es.client.Cat.Indices().Index(name).Do(ctx)
+}
+
+// IndexMapping returns the mappings of the indices matching name, keyed by index.
+func (es *ESClient) IndexMapping(ctx context.Context, name string) (map[string]types.TypeMapping, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	res, err := es.client.Indices.GetMapping().Index(name).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mappings := make(map[string]types.TypeMapping, len(res))
+	for index, record := range res {
+		mappings[index] = record.Mappings
+	}
+	return mappings, nil
+}
+
+// SwapAlias points alias to index in a single atomic request, removing it from the indices
+// it pointed to before, which are returned. Readers never see the alias missing or doubled.
+func (es *ESClient) SwapAlias(ctx context.Context, alias, index string) ([]string, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	var previous []string
+	exists, err := es.client.Indices.ExistsAlias(alias).IsSuccess(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		res, err := es.client.Indices.GetAlias().Name(alias).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for name := range res {
+			previous = append(previous, name)
+		}
+		slices.Sort(previous)
+	}
+
+	actions := []types.IndicesAction{{Add: &types.AddAction{Index: &index, Alias: &alias}}}
+	for _, name := range previous {
+		if name != index {
+			actions = append(actions, types.IndicesAction{Remove: &types.RemoveAction{Index: &name, Alias: &alias}})
+		}
+	}
+	_, err = es.client.Indices.UpdateAliases().
+		Request(&updatealiases.Request{Actions: actions}).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return previous, nil
+}
+
+// TaskStatus returns the state of a task started without waiting, e.g. by Reindex.
+func (es *ESClient) TaskStatus(ctx context.Context, taskID string) (*taskget.Response, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	return es.client.Tasks.Get(taskID).Do(ctx)
+}
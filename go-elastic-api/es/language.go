@@ -0,0 +1,34 @@
+package es
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+)
+
+// BooksLanguagePipeline is the default ingest pipeline of a books index. It copies the name,
+// description and content of a book under i18n.<language>, where they are analyzed for its language.
+const BooksLanguagePipeline = "books-language"
+
+// BookLanguages are the ISO 639-1 codes of the languages books have dedicated analyzers for.
+// Books in other languages, or without one, are still matched with or without diacritics
+// through the folded sub-fields.
+var BookLanguages = []string{"de", "en", "es", "fr", "vi"}
+
+// booksPipeline holds the definition of BooksLanguagePipeline.
+//
+//go:embed books_pipeline.json
+var booksPipeline []byte
+
+// putLanguagePipeline creates or updates BooksLanguagePipeline. An index cannot be written to
+// while its default pipeline does not exist.
+func (es *ESClient) putLanguagePipeline(ctx context.Context) error {
+	_, err := es.client.Ingest.PutPipeline(BooksLanguagePipeline).
+		Raw(bytes.NewReader(booksPipeline)).
+		Do(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot put ingest pipeline %s: %w", BooksLanguagePipeline, err)
+	}
+	return nil
+}
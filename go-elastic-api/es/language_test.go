@@ -0,0 +1,34 @@
+package es
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBooksLanguagePipeline(t *testing.T) {
+	var pipeline types.IngestPipeline
+	require.NoError(t, json.Unmarshal(booksPipeline, &pipeline))
+	require.Len(t, pipeline.Processors, 1)
+	script := pipeline.Processors[0].Script
+	require.NotNil(t, script)
+
+	var languages []string
+	require.NoError(t, json.Unmarshal(script.Params["languages"], &languages))
+	require.Equal(t, BookLanguages, languages)
+
+	// Every language the pipeline copies text for has its fields mapped, as the mapping is strict
+	var definition map[string]any
+	require.NoError(t, json.Unmarshal(booksIndex, &definition))
+	require.Equal(t, BooksLanguagePipeline, lookup(definition, "settings", "default_pipeline"))
+	for _, language := range BookLanguages {
+		for _, field := range []string{"name", "description", "content"} {
+			require.NotNil(t, lookup(definition, "mappings", "properties", "i18n", "properties", language, "properties", field, "analyzer"),
+				"i18n.%s.%s", language, field)
+		}
+	}
+	require.Equal(t, "folded", lookup(definition, "mappings", "properties", "author", "fields", "folded", "analyzer"))
+	require.Equal(t, "lowercase_folded", lookup(definition, "mappings", "properties", "publisher", "fields", "normalized", "normalizer"))
+}
@@ -0,0 +1,251 @@
+package es
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go-elastic-api/util"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/ilm/putlifecycle"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+)
+
+// eventsIndex holds the settings and mappings of the indices behind an events alias.
+// Unlike books, events are mapped dynamically, strings as keywords, so new event types need no migration.
+// Filters are flattened, so that their values never conflict with the mapping of another event.
+//
+//go:embed events_index.json
+var eventsIndex []byte
+
+// LifecyclePolicy tells how the indices behind a rollover alias age. The write index rolls over
+// when it reaches MaxAge or MaxPrimaryShardSize; WarmAfter and DeleteAfter are counted from the rollover.
+// Zero values disable a rule.
+type LifecyclePolicy struct {
+	// RolloverMaxAge is how long an index is written to
+	RolloverMaxAge time.Duration
+	// RolloverMaxPrimaryShardSize is how large a primary shard grows before rollover, e.g. 10gb
+	RolloverMaxPrimaryShardSize string
+	// WarmAfter is when a rolled over index is shrunk and force merged
+	WarmAfter time.Duration
+	// ShrinkShards is the number of primary shards of a warm index
+	ShrinkShards int
+	// ForceMergeSegments is the number of segments per shard of a warm index
+	ForceMergeSegments int
+	// DeleteAfter is when a rolled over index is deleted
+	DeleteAfter time.Duration
+}
+
+// EventsLifecyclePolicy returns the lifecycle of the events indices configured by cfg.
+func EventsLifecyclePolicy(cfg util.Config) LifecyclePolicy {
+	return LifecyclePolicy{
+		RolloverMaxAge:              cfg.EventsRolloverMaxAge,
+		RolloverMaxPrimaryShardSize: cfg.EventsRolloverMaxSize,
+		WarmAfter:                   cfg.EventsWarmAfter,
+		ShrinkShards:                cfg.EventsShrinkShards,
+		ForceMergeSegments:          cfg.EventsForceMergeSegments,
+		DeleteAfter:                 cfg.EventsDeleteAfter,
+	}
+}
+
+// LifecyclePolicyName returns the name of the policy managing the indices behind alias.
+func LifecyclePolicyName(alias string) string {
+	return alias + "-policy"
+}
+
+// ilmPolicy returns the hot, warm and delete phases of p. Indices being written to have
+// the highest recovery priority after a restart, warm ones come next.
+func (p LifecyclePolicy) ilmPolicy() types.IlmPolicy {
+	hotPriority, warmPriority := 100, 50
+
+	rollover := &types.RolloverAction{}
+	if p.RolloverMaxAge > 0 {
+		rollover.MaxAge = esDuration(p.RolloverMaxAge)
+	}
+	if p.RolloverMaxPrimaryShardSize != "" {
+		rollover.MaxPrimaryShardSize = p.RolloverMaxPrimaryShardSize
+	}
+	phases := types.Phases{
+		Hot: &types.Phase{
+			MinAge: "0ms",
+			Actions: &types.IlmActions{
+				Rollover:    rollover,
+				SetPriority: &types.SetPriorityAction{Priority: &hotPriority},
+			},
+		},
+	}
+
+	if p.WarmAfter > 0 {
+		warm := &types.IlmActions{SetPriority: &types.SetPriorityAction{Priority: &warmPriority}}
+		if p.ShrinkShards > 0 {
+			warm.Shrink = &types.ShrinkAction{NumberOfShards: &p.ShrinkShards}
+		}
+		if p.ForceMergeSegments > 0 {
+			warm.Forcemerge = &types.ForceMergeAction{MaxNumSegments: p.ForceMergeSegments}
+		}
+		phases.Warm = &types.Phase{MinAge: esDuration(p.WarmAfter), Actions: warm}
+	}
+
+	if p.DeleteAfter > 0 {
+		phases.Delete = &types.Phase{
+			MinAge:  esDuration(p.DeleteAfter),
+			Actions: &types.IlmActions{Delete: &types.DeleteAction{}},
+		}
+	}
+	return types.IlmPolicy{Phases: phases}
+}
+
+// esDuration formats d with the largest Elasticsearch time unit that represents it exactly,
+// since Elasticsearch does not read Go durations such as 1h30m0s.
+func esDuration(d time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case d%day == 0:
+		return fmt.Sprintf("%dd", d/day)
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	case d%time.Second == 0:
+		return fmt.Sprintf("%ds", d/time.Second)
+	default:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+}
+
+// PutLifecyclePolicy creates or replaces a lifecycle policy. Indices already managed by it
+// switch to the new definition once they are done with their current phase.
+func (es *ESClient) PutLifecyclePolicy(ctx context.Context, name string, policy LifecyclePolicy) (*putlifecycle.Response, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	ilmPolicy := policy.ilmPolicy()
+	return es.client.Ilm.PutLifecycle(name).
+		Request(&putlifecycle.Request{Policy: &ilmPolicy}).
+		Do(ctx)
+}
+
+// BootstrapRolloverAlias puts an index template giving the indices named alias-* the events mappings
+// and the lifecycle policy, then creates alias-000001 as the write index of alias, unless alias already exists.
+// It reports whether the first index was created; calling it again only updates the template.
+func (es *ESClient) BootstrapRolloverAlias(ctx context.Context, alias, policy string) (bool, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	var template types.IndexTemplateMapping
+	if err := json.Unmarshal(eventsIndex, &template); err != nil {
+		return false, fmt.Errorf("invalid events index definition: %w", err)
+	}
+	if template.Settings == nil {
+		template.Settings = &types.IndexSettings{}
+	}
+	template.Settings.Lifecycle = &types.IndexSettingsLifecycle{Name: &policy, RolloverAlias: &alias}
+
+	_, err := es.client.Indices.PutIndexTemplate(alias).
+		IndexPatterns(alias + "-*").
+		Template(&template).
+		Do(ctx)
+	if err != nil {
+		return false, fmt.Errorf("error putting index template: %w", err)
+	}
+
+	exists, err := es.client.Indices.ExistsAlias(alias).IsSuccess(ctx)
+	if err != nil || exists {
+		return false, err
+	}
+	isWriteIndex := true
+	_, err = es.client.Indices.Create(alias + "-000001").
+		Aliases(map[string]types.Alias{alias: {IsWriteIndex: &isWriteIndex}}).
+		Do(ctx)
+	if err != nil {
+		return false, fmt.Errorf("error creating first index: %w", err)
+	}
+	return true, nil
+}
+
+// ManagedIndex is the lifecycle state of one index behind a rollover alias.
+type ManagedIndex struct {
+	Index string `json:"index"`
+	// Managed is false when no lifecycle policy applies to the index
+	Managed bool   `json:"managed"`
+	Policy  string `json:"policy,omitempty"`
+	Phase   string `json:"phase,omitempty"`
+	Action  string `json:"action,omitempty"`
+	Step    string `json:"step,omitempty"`
+	// Age is the time since the index was rolled over, or created while it is the write index
+	Age string `json:"age,omitempty"`
+	// Error is why the lifecycle is stuck, set when Step is ERROR
+	Error      string `json:"error,omitempty"`
+	WriteIndex bool   `json:"write_index"`
+	Health     string `json:"health,omitempty"`
+	DocsCount  string `json:"docs_count,omitempty"`
+	StoreSize  string `json:"store_size,omitempty"`
+}
+
+// ManagedIndices returns the lifecycle phase and health of every index behind alias, by name.
+func (es *ESClient) ManagedIndices(ctx context.Context, alias string) ([]ManagedIndex, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	explain, err := es.client.Ilm.ExplainLifecycle(alias).Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error explaining lifecycle: %w", err)
+	}
+	aliases, err := es.client.Indices.GetAlias().Name(alias).Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting alias: %w", err)
+	}
+	status, err := es.client.Cat.Indices().Index(alias).Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting index status: %w", err)
+	}
+
+	indices := make([]ManagedIndex, 0, len(explain.Indices))
+	for name, state := range explain.Indices {
+		index := ManagedIndex{Index: name}
+		if managed, ok := state.(*types.LifecycleExplainManaged); ok {
+			index.Managed = true
+			index.Policy = stringValue(managed.Policy)
+			index.Phase = managed.Phase
+			index.Action = stringValue(managed.Action)
+			index.Step = stringValue(managed.Step)
+			if managed.Age != nil {
+				index.Age = fmt.Sprint(managed.Age)
+			}
+			index.Error = stepError(managed.StepInfo)
+		}
+		if definition, ok := aliases[name].Aliases[alias]; ok {
+			index.WriteIndex = definition.IsWriteIndex != nil && *definition.IsWriteIndex
+		}
+		for _, record := range status {
+			if stringValue(record.Index) == name {
+				index.Health = stringValue(record.Health)
+				index.DocsCount = stringValue(record.DocsCount)
+				index.StoreSize = stringValue(record.StoreSize)
+			}
+		}
+		indices = append(indices, index)
+	}
+	slices.SortFunc(indices, func(a, b ManagedIndex) int { return strings.Compare(a.Index, b.Index) })
+	return indices, nil
+}
+
+// stepError returns the reason of a failed lifecycle step from its step info.
+func stepError(info map[string]json.RawMessage) string {
+	var reason string
+	if raw, ok := info["reason"]; ok && json.Unmarshal(raw, &reason) == nil {
+		return reason
+	}
+	return ""
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,98 @@
+package es
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"go-elastic-api/util"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifecyclePolicy(t *testing.T) {
+	testCases := []struct {
+		name   string
+		policy LifecyclePolicy
+		want   string
+	}{
+		{
+			name: "AllPhases",
+			policy: LifecyclePolicy{
+				RolloverMaxAge:              24 * time.Hour,
+				RolloverMaxPrimaryShardSize: "10gb",
+				WarmAfter:                   7 * 24 * time.Hour,
+				ShrinkShards:                1,
+				ForceMergeSegments:          1,
+				DeleteAfter:                 90 * 24 * time.Hour,
+			},
+			want: `{"phases":{
+				"delete":{"actions":{"delete":{}},"min_age":"90d"},
+				"hot":{"actions":{"rollover":{"max_age":"1d","max_primary_shard_size":"10gb"},"set_priority":{"priority":100}},"min_age":"0ms"},
+				"warm":{"actions":{"forcemerge":{"max_num_segments":1},"set_priority":{"priority":50},"shrink":{"number_of_shards":1}},"min_age":"7d"}
+			}}`,
+		},
+		{
+			name:   "RolloverOnly",
+			policy: LifecyclePolicy{RolloverMaxAge: 90 * time.Minute},
+			want: `{"phases":{
+				"hot":{"actions":{"rollover":{"max_age":"90m"},"set_priority":{"priority":100}},"min_age":"0ms"}
+			}}`,
+		},
+		{
+			name:   "WarmWithoutShrink",
+			policy: LifecyclePolicy{RolloverMaxAge: 12 * time.Hour, WarmAfter: 36 * time.Hour, ForceMergeSegments: 2},
+			want: `{"phases":{
+				"hot":{"actions":{"rollover":{"max_age":"12h"},"set_priority":{"priority":100}},"min_age":"0ms"},
+				"warm":{"actions":{"forcemerge":{"max_num_segments":2},"set_priority":{"priority":50}},"min_age":"36h"}
+			}}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := json.Marshal(tc.policy.ilmPolicy())
+			require.NoError(t, err)
+			require.JSONEq(t, tc.want, string(policy))
+		})
+	}
+}
+
+func TestESDuration(t *testing.T) {
+	require.Equal(t, "2d", esDuration(48*time.Hour))
+	require.Equal(t, "25h", esDuration(25*time.Hour))
+	require.Equal(t, "90s", esDuration(90*time.Second))
+	require.Equal(t, "1500ms", esDuration(1500*time.Millisecond))
+}
+
+func TestBootstrapRolloverAlias(t *testing.T) {
+	ctx := context.Background()
+	client := testClient.(*ESClient).client
+	alias := "test-events-" + strings.ToLower(util.RandomString(8))
+	policy := LifecyclePolicyName(alias)
+
+	_, err := testClient.PutLifecyclePolicy(ctx, policy, LifecyclePolicy{RolloverMaxAge: time.Hour, DeleteAfter: 24 * time.Hour})
+	require.NoError(t, err)
+	defer client.Ilm.DeleteLifecycle(policy).Do(ctx)
+
+	created, err := testClient.BootstrapRolloverAlias(ctx, alias, policy)
+	require.NoError(t, err)
+	require.True(t, created)
+	defer client.Indices.DeleteIndexTemplate(alias).Do(ctx)
+	defer testClient.DeleteIndex(ctx, alias+"-000001")
+
+	// Bootstrapping again leaves the write index alone
+	created, err = testClient.BootstrapRolloverAlias(ctx, alias, policy)
+	require.NoError(t, err)
+	require.False(t, created)
+
+	indices, err := testClient.ManagedIndices(ctx, alias)
+	require.NoError(t, err)
+	require.Len(t, indices, 1)
+	require.Equal(t, alias+"-000001", indices[0].Index)
+	require.True(t, indices[0].Managed)
+	require.True(t, indices[0].WriteIndex)
+	require.Equal(t, policy, indices[0].Policy)
+	require.NotEmpty(t, indices[0].Health)
+}
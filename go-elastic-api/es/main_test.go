@@ -5,8 +5,6 @@ import (
 	"log"
 	"os"
 	"testing"
-
-	elastic "github.com/elastic/go-elasticsearch/v8"
 )
 
 var txKey = struct{}{}
@@ -19,13 +17,16 @@ func TestMain(m *testing.M) {
 		log.Fatal("cannot load config:", err)
 	}
 
-	esClientTyped, err := elastic.NewTypedClient(elastic.Config{
-		Addresses: []string{cfg.ElasticsearchServerAddress},
-	})
+	esClientTyped, err := NewTypedClient(cfg)
 	if err != nil {
 		log.Fatalf("Error creating Elasticsearch typed client: %s", err)
 	}
 
-	testClient = NewClient(esClientTyped)
+	testClient = NewClient(esClientTyped,
+		WithIndex(cfg.BooksTarget()),
+		WithRequestTimeout(cfg.ElasticsearchRequestTimeout),
+		WithRanking(BooksRanking(cfg)),
+		WithSimilarity(BooksSimilarity(cfg)),
+	)
 	os.Exit(m.Run())
 }
@@ -0,0 +1,83 @@
+package es
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+)
+
+// BooksMapping returns the mappings of BooksIndexDefinition.
+func BooksMapping() (types.TypeMapping, error) {
+	var definition struct {
+		Mappings types.TypeMapping `json:"mappings"`
+	}
+	if err := json.Unmarshal(booksIndex, &definition); err != nil {
+		return types.TypeMapping{}, fmt.Errorf("cannot decode books index definition: %w", err)
+	}
+	return definition.Mappings, nil
+}
+
+// DiffMapping compares the mapping of an index with the expected one and returns
+// one line per difference, sorted by path: "- path" for a setting that is missing,
+// "+ path: value" for one that is not expected, and "~ path: want -> got" for a changed one.
+// Identical mappings return no lines.
+func DiffMapping(want, got types.TypeMapping) ([]string, error) {
+	wantTree, err := toTree(want)
+	if err != nil {
+		return nil, err
+	}
+	gotTree, err := toTree(got)
+	if err != nil {
+		return nil, err
+	}
+
+	var diff []string
+	diffTree("", wantTree, gotTree, &diff)
+	slices.SortFunc(diff, func(a, b string) int { return strings.Compare(a[2:], b[2:]) })
+	return diff, nil
+}
+
+func toTree(mapping types.TypeMapping) (map[string]any, error) {
+	b, err := json.Marshal(mapping)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode mapping: %w", err)
+	}
+	var tree map[string]any
+	if err := json.Unmarshal(b, &tree); err != nil {
+		return nil, fmt.Errorf("cannot decode mapping: %w", err)
+	}
+	return tree, nil
+}
+
+func diffTree(prefix string, want, got map[string]any, diff *[]string) {
+	for key, w := range want {
+		path := prefix + key
+		g, ok := got[key]
+		if !ok {
+			*diff = append(*diff, "- "+path)
+			continue
+		}
+		wm, wok := w.(map[string]any)
+		gm, gok := g.(map[string]any)
+		switch {
+		case wok && gok:
+			diffTree(path+".", wm, gm, diff)
+		case !reflect.DeepEqual(w, g):
+			*diff = append(*diff, fmt.Sprintf("~ %s: %s -> %s", path, compactJSON(w), compactJSON(g)))
+		}
+	}
+	for key, g := range got {
+		if _, ok := want[key]; !ok {
+			*diff = append(*diff, fmt.Sprintf("+ %s: %s", prefix+key, compactJSON(g)))
+		}
+	}
+}
+
+func compactJSON(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,34 @@
+package es
+
+import (
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffMapping(t *testing.T) {
+	want, err := BooksMapping()
+	require.NoError(t, err)
+	require.Contains(t, want.Properties, "name")
+
+	// 1. The mapping of an index created by CreateIndex matches
+	got, err := BooksMapping()
+	require.NoError(t, err)
+	diff, err := DiffMapping(want, got)
+	require.NoError(t, err)
+	require.Empty(t, diff)
+
+	// 2. Missing, added and changed fields are reported, sorted by path
+	delete(got.Properties, "rating")
+	got.Properties["isbn"] = types.NewKeywordProperty()
+	got.Properties["page_count"] = types.NewLongNumberProperty()
+
+	diff, err = DiffMapping(want, got)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`+ properties.isbn: {"type":"keyword"}`,
+		`~ properties.page_count.type: "integer" -> "long"`,
+		`- properties.rating`,
+	}, diff)
+}
@@ -0,0 +1,103 @@
+package es
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics is an Observer that records Prometheus metrics for every Client call.
+type Metrics struct {
+	calls    *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	took     *prometheus.HistogramVec
+	hits     *prometheus.HistogramVec
+	bulkDocs *prometheus.CounterVec
+}
+
+// NewMetrics creates the Client metrics and registers them with reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "elasticsearch_client_calls_total",
+			Help: "Number of es.Client calls.",
+		}, []string{"method"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "elasticsearch_client_errors_total",
+			Help: "Number of failed es.Client calls by error type.",
+		}, []string{"method", "type"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "elasticsearch_client_call_duration_seconds",
+			Help:    "Duration of es.Client calls as seen by the client, including network time.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method"}),
+		took: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "elasticsearch_took_seconds",
+			Help:    "Time reported by Elasticsearch in the took field of the response.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method"}),
+		hits: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "elasticsearch_search_hits",
+			Help:    "Total hits returned by search calls.",
+			Buckets: []float64{0, 1, 5, 10, 50, 100, 500, 1000, 10000},
+		}, []string{"method"}),
+		bulkDocs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "elasticsearch_bulk_documents_total",
+			Help: "Number of documents sent through bulk requests by result.",
+		}, []string{"result"}),
+	}
+	reg.MustRegister(m.calls, m.errors, m.duration, m.took, m.hits, m.bulkDocs)
+	return m
+}
+
+func (m *Metrics) Begin(ctx context.Context, call Call) (context.Context, func(Outcome)) {
+	start := time.Now()
+	return ctx, func(out Outcome) {
+		m.calls.WithLabelValues(call.Method).Inc()
+		m.duration.WithLabelValues(call.Method).Observe(time.Since(start).Seconds())
+
+		if out.Err != nil {
+			m.errors.WithLabelValues(call.Method, ErrorType(out.Err)).Inc()
+			return
+		}
+		if out.Took > 0 {
+			m.took.WithLabelValues(call.Method).Observe(out.Took.Seconds())
+		}
+		if call.QueryType != "" {
+			m.hits.WithLabelValues(call.Method).Observe(float64(out.Hits))
+		}
+		if out.Docs > 0 {
+			m.bulkDocs.WithLabelValues("indexed").Add(float64(out.Docs - out.Failed))
+			m.bulkDocs.WithLabelValues("failed").Add(float64(out.Failed))
+		}
+	}
+}
+
+// ErrorType classifies an error returned by a Client call into a short, low-cardinality label.
+// Errors reported by Elasticsearch use their error type, e.g. "index_not_found_exception".
+func ErrorType(err error) string {
+	var esErr *types.ElasticsearchError
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &esErr):
+		if esErr.ErrorCause.Type != "" {
+			return esErr.ErrorCause.Type
+		}
+		return "elasticsearch"
+	case errors.As(err, &netErr):
+		return "network"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,90 @@
+package es
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClient answers searches with a canned response or error.
+// Calling any other method panics on the nil embedded interface.
+type fakeClient struct {
+	Client
+
+	res *search.Response
+	err error
+
+	searches int
+}
+
+func (f *fakeClient) Index() string {
+	return DefaultBooksIndex
+}
+
+func (f *fakeClient) FullTextSearch(ctx context.Context, query string, page Page) (*search.Response, error) {
+	f.searches++
+	return f.res, f.err
+}
+
+func newSearchResponse(took, hits int64) *search.Response {
+	return &search.Response{
+		Took: took,
+		Hits: types.HitsMetadata{Total: &types.TotalHits{Value: hits}},
+	}
+}
+
+func TestMetricsObserver(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics := NewMetrics(reg)
+	fake := &fakeClient{res: newSearchResponse(12, 3)}
+	client := Observe(fake, metrics)
+
+	_, err := client.FullTextSearch(context.Background(), "name:dune", Page{})
+	require.NoError(t, err)
+
+	fake.res, fake.err = nil, &types.ElasticsearchError{
+		Status:     404,
+		ErrorCause: types.ErrorCause{Type: "index_not_found_exception"},
+	}
+	_, err = client.FullTextSearch(context.Background(), "name:dune", Page{})
+	require.Error(t, err)
+
+	require.Equal(t, 2.0, testutil.ToFloat64(metrics.calls.WithLabelValues("FullTextSearch")))
+	require.Equal(t, 1.0, testutil.ToFloat64(metrics.errors.WithLabelValues("FullTextSearch", "index_not_found_exception")))
+
+	expected := `
+# HELP elasticsearch_search_hits Total hits returned by search calls.
+# TYPE elasticsearch_search_hits histogram
+elasticsearch_search_hits_bucket{method="FullTextSearch",le="0"} 0
+elasticsearch_search_hits_bucket{method="FullTextSearch",le="1"} 0
+elasticsearch_search_hits_bucket{method="FullTextSearch",le="5"} 1
+elasticsearch_search_hits_bucket{method="FullTextSearch",le="10"} 1
+elasticsearch_search_hits_bucket{method="FullTextSearch",le="50"} 1
+elasticsearch_search_hits_bucket{method="FullTextSearch",le="100"} 1
+elasticsearch_search_hits_bucket{method="FullTextSearch",le="500"} 1
+elasticsearch_search_hits_bucket{method="FullTextSearch",le="1000"} 1
+elasticsearch_search_hits_bucket{method="FullTextSearch",le="10000"} 1
+elasticsearch_search_hits_bucket{method="FullTextSearch",le="+Inf"} 1
+elasticsearch_search_hits_sum{method="FullTextSearch"} 3
+elasticsearch_search_hits_count{method="FullTextSearch"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "elasticsearch_search_hits"))
+}
+
+func TestErrorType(t *testing.T) {
+	require.Equal(t, "", ErrorType(nil))
+	require.Equal(t, "timeout", ErrorType(context.DeadlineExceeded))
+	require.Equal(t, "canceled", ErrorType(context.Canceled))
+	require.Equal(t, "other", ErrorType(errors.New("boom")))
+	require.Equal(t, "version_conflict_engine_exception", ErrorType(&types.ElasticsearchError{
+		Status:     409,
+		ErrorCause: types.ErrorCause{Type: "version_conflict_engine_exception"},
+	}))
+}
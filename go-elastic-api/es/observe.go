@@ -0,0 +1,408 @@
+package es
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	catindices "github.com/elastic/go-elasticsearch/v8/typedapi/cat/indices"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/cluster/health"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/bulk"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/create"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/delete"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/deletebyquery"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/index"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/reindex"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/ilm/putlifecycle"
+	indicescreate "github.com/elastic/go-elasticsearch/v8/typedapi/indices/create"
+	indicesdelete "github.com/elastic/go-elasticsearch/v8/typedapi/indices/delete"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/indices/refresh"
+	snapshotcreate "github.com/elastic/go-elasticsearch/v8/typedapi/snapshot/create"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/snapshot/createrepository"
+	snapshotdelete "github.com/elastic/go-elasticsearch/v8/typedapi/snapshot/delete"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/snapshot/restore"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/synonyms/putsynonym"
+	taskget "github.com/elastic/go-elasticsearch/v8/typedapi/tasks/get"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+)
+
+// Call describes a single Client call.
+type Call struct {
+	Method    string // Client method, e.g. "FullTextSearch"
+	Index     string // Target index or alias, empty for cluster level calls
+	QueryType string // Query DSL used by search calls, e.g. "query_string"
+}
+
+// Outcome describes the result of a Client call.
+type Outcome struct {
+	Err    error
+	Took   time.Duration // Time reported by Elasticsearch, zero when the response has none
+	Hits   int64         // Total hits of a search
+	Docs   int           // Documents sent in a bulk request
+	Failed int           // Bulk items that failed
+}
+
+// Observer is notified around every call made through a Client returned by Observe.
+// Begin may return a derived context, which is passed to the wrapped Client,
+// and must return a function that is called once with the outcome of the call.
+type Observer interface {
+	Begin(ctx context.Context, call Call) (context.Context, func(Outcome))
+}
+
+type observedClient struct {
+	next      Client
+	observers []Observer
+}
+
+// Observe wraps a Client so that every call is reported to the given observers.
+// Observers are started in order and finished in reverse order.
+func Observe(next Client, observers ...Observer) Client {
+	return &observedClient{
+		next:      next,
+		observers: observers,
+	}
+}
+
+func (o *observedClient) begin(ctx context.Context, call Call) (context.Context, func(Outcome)) {
+	ends := make([]func(Outcome), 0, len(o.observers))
+	for _, observer := range o.observers {
+		var end func(Outcome)
+		ctx, end = observer.Begin(ctx, call)
+		ends = append(ends, end)
+	}
+	return ctx, func(out Outcome) {
+		for i := len(ends) - 1; i >= 0; i-- {
+			ends[i](out)
+		}
+	}
+}
+
+func (o *observedClient) Index() string {
+	return o.next.Index()
+}
+
+func (o *observedClient) AddBook(ctx context.Context, book Book) (*index.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "AddBook", Index: o.next.Index()})
+	res, err := o.next.AddBook(ctx, book)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) CreateBook(ctx context.Context, book Book) (*create.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "CreateBook", Index: o.next.Index()})
+	res, err := o.next.CreateBook(ctx, book)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) UpdateBook(ctx context.Context, book Book) (*index.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "UpdateBook", Index: o.next.Index()})
+	res, err := o.next.UpdateBook(ctx, book)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) BulkAddBooks(ctx context.Context, books []Book) (*bulk.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "BulkAddBooks", Index: o.next.Index()})
+	res, err := o.next.BulkAddBooks(ctx, books)
+	end(bulkOutcome(res, err, len(books)))
+	return res, err
+}
+
+// bulkOutcome counts the items of a bulk request that failed.
+func bulkOutcome(res *bulk.Response, err error, docs int) Outcome {
+	out := Outcome{Err: err, Docs: docs}
+	if res != nil {
+		out.Took = time.Duration(res.Took) * time.Millisecond
+		for _, item := range res.Items {
+			for _, result := range item {
+				if result.Error != nil {
+					out.Failed++
+				}
+			}
+		}
+	}
+	return out
+}
+
+func (o *observedClient) DeleteBook(ctx context.Context, bookID string) (*delete.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "DeleteBook", Index: o.next.Index()})
+	res, err := o.next.DeleteBook(ctx, bookID)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) GetBook(ctx context.Context, bookID string) (*search.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "GetBook", Index: o.next.Index(), QueryType: "term"})
+	res, err := o.next.GetBook(ctx, bookID)
+	end(searchOutcome(res, err))
+	return res, err
+}
+
+func (o *observedClient) FilterBooks(ctx context.Context, filter map[string]any, page Page) (*search.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "FilterBooks", Index: o.next.Index(), QueryType: "bool"})
+	res, err := o.next.FilterBooks(ctx, filter, page)
+	end(searchOutcome(res, err))
+	return res, err
+}
+
+func (o *observedClient) FullTextSearch(ctx context.Context, query string, page Page) (*search.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "FullTextSearch", Index: o.next.Index(), QueryType: "query_string"})
+	res, err := o.next.FullTextSearch(ctx, query, page)
+	end(searchOutcome(res, err))
+	return res, err
+}
+
+func (o *observedClient) SemanticSearch(ctx context.Context, query string, page Page) (*search.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "SemanticSearch", Index: o.next.Index(), QueryType: "knn"})
+	res, err := o.next.SemanticSearch(ctx, query, page)
+	end(searchOutcome(res, err))
+	return res, err
+}
+
+func (o *observedClient) HybridSearch(ctx context.Context, query string, page Page) (*search.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "HybridSearch", Index: o.next.Index(), QueryType: "hybrid"})
+	res, err := o.next.HybridSearch(ctx, query, page)
+	end(searchOutcome(res, err))
+	return res, err
+}
+
+func (o *observedClient) SimilarBooks(ctx context.Context, bookID string, filter SimilarFilter, page Page) (*search.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "SimilarBooks", Index: o.next.Index(), QueryType: "more_like_this"})
+	res, err := o.next.SimilarBooks(ctx, bookID, filter, page)
+	end(searchOutcome(res, err))
+	return res, err
+}
+
+func (o *observedClient) UpdatePopularity(ctx context.Context, popularity map[string]float64) (*bulk.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "UpdatePopularity", Index: o.next.Index()})
+	res, err := o.next.UpdatePopularity(ctx, popularity)
+	end(bulkOutcome(res, err, len(popularity)))
+	return res, err
+}
+
+func (o *observedClient) UpdateEmbeddings(ctx context.Context, books []Book) (*bulk.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "UpdateEmbeddings", Index: o.next.Index()})
+	res, err := o.next.UpdateEmbeddings(ctx, books)
+	end(bulkOutcome(res, err, len(books)))
+	return res, err
+}
+
+func (o *observedClient) ScanBooks(ctx context.Context, filter map[string]any, batchSize int, fn func([]Book) error) error {
+	ctx, end := o.begin(ctx, Call{Method: "ScanBooks", Index: o.next.Index(), QueryType: "bool"})
+	var scanned int64
+	err := o.next.ScanBooks(ctx, filter, batchSize, func(books []Book) error {
+		scanned += int64(len(books))
+		return fn(books)
+	})
+	end(Outcome{Err: err, Hits: scanned})
+	return err
+}
+
+func (o *observedClient) CreateIndex(ctx context.Context, name string) (*indicescreate.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "CreateIndex", Index: name})
+	res, err := o.next.CreateIndex(ctx, name)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) DeleteIndex(ctx context.Context, name string) (*indicesdelete.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "DeleteIndex", Index: name})
+	res, err := o.next.DeleteIndex(ctx, name)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) RefreshIndex(ctx context.Context, name string) (*refresh.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "RefreshIndex", Index: name})
+	res, err := o.next.RefreshIndex(ctx, name)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) Reindex(ctx context.Context, source, dest string) (*reindex.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "Reindex", Index: dest})
+	res, err := o.next.Reindex(ctx, source, dest)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) DeleteBooksByQuery(ctx context.Context, filter map[string]any) (*deletebyquery.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "DeleteBooksByQuery", Index: o.next.Index(), QueryType: "bool"})
+	res, err := o.next.DeleteBooksByQuery(ctx, filter)
+	out := Outcome{Err: err}
+	if res != nil && res.Took != nil {
+		out.Took = time.Duration(*res.Took) * time.Millisecond
+	}
+	end(out)
+	return res, err
+}
+
+func (o *observedClient) IndexStatus(ctx context.Context, name string) (catindices.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "IndexStatus", Index: name})
+	res, err := o.next.IndexStatus(ctx, name)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) IndexMapping(ctx context.Context, name string) (map[string]types.TypeMapping, error) {
+	ctx, end := o.begin(ctx, Call{Method: "IndexMapping", Index: name})
+	res, err := o.next.IndexMapping(ctx, name)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) SwapAlias(ctx context.Context, alias, index string) ([]string, error) {
+	ctx, end := o.begin(ctx, Call{Method: "SwapAlias", Index: alias})
+	res, err := o.next.SwapAlias(ctx, alias, index)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) TaskStatus(ctx context.Context, taskID string) (*taskget.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "TaskStatus"})
+	res, err := o.next.TaskStatus(ctx, taskID)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) Synonyms(ctx context.Context, set string) ([]types.SynonymRuleRead, error) {
+	ctx, end := o.begin(ctx, Call{Method: "Synonyms"})
+	res, err := o.next.Synonyms(ctx, set)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) PutSynonyms(ctx context.Context, set string, rules []string) (*putsynonym.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "PutSynonyms"})
+	res, err := o.next.PutSynonyms(ctx, set, rules)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) RegisterSnapshotRepository(ctx context.Context, repository, location string) (*createrepository.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "RegisterSnapshotRepository"})
+	res, err := o.next.RegisterSnapshotRepository(ctx, repository, location)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) CreateSnapshot(ctx context.Context, repository, snapshot string, indices []string) (*snapshotcreate.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "CreateSnapshot", Index: strings.Join(indices, ",")})
+	res, err := o.next.CreateSnapshot(ctx, repository, snapshot, indices)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) Snapshots(ctx context.Context, repository string, names ...string) ([]types.SnapshotInfo, error) {
+	ctx, end := o.begin(ctx, Call{Method: "Snapshots"})
+	res, err := o.next.Snapshots(ctx, repository, names...)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) DeleteSnapshot(ctx context.Context, repository, snapshot string) (*snapshotdelete.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "DeleteSnapshot"})
+	res, err := o.next.DeleteSnapshot(ctx, repository, snapshot)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) RestoreSnapshot(ctx context.Context, repository, snapshot, index, target string) (*restore.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "RestoreSnapshot", Index: target})
+	res, err := o.next.RestoreSnapshot(ctx, repository, snapshot, index, target)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) PutLifecyclePolicy(ctx context.Context, name string, policy LifecyclePolicy) (*putlifecycle.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "PutLifecyclePolicy"})
+	res, err := o.next.PutLifecyclePolicy(ctx, name, policy)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) BootstrapRolloverAlias(ctx context.Context, alias, policy string) (bool, error) {
+	ctx, end := o.begin(ctx, Call{Method: "BootstrapRolloverAlias", Index: alias})
+	created, err := o.next.BootstrapRolloverAlias(ctx, alias, policy)
+	end(Outcome{Err: err})
+	return created, err
+}
+
+func (o *observedClient) ManagedIndices(ctx context.Context, alias string) ([]ManagedIndex, error) {
+	ctx, end := o.begin(ctx, Call{Method: "ManagedIndices", Index: alias})
+	res, err := o.next.ManagedIndices(ctx, alias)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) IndexEvents(ctx context.Context, alias string, events []any) (*bulk.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "IndexEvents", Index: alias})
+	res, err := o.next.IndexEvents(ctx, alias, events)
+	end(bulkOutcome(res, err, len(events)))
+	return res, err
+}
+
+func (o *observedClient) TopQueries(ctx context.Context, alias string, since time.Time, size int) ([]QueryStats, error) {
+	ctx, end := o.begin(ctx, Call{Method: "TopQueries", Index: alias})
+	res, err := o.next.TopQueries(ctx, alias, since, size)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) ZeroResultQueries(ctx context.Context, alias string, since time.Time, size int) ([]QueryStats, error) {
+	ctx, end := o.begin(ctx, Call{Method: "ZeroResultQueries", Index: alias})
+	res, err := o.next.ZeroResultQueries(ctx, alias, since, size)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) ClickThroughRate(ctx context.Context, alias string, since time.Time, interval time.Duration) ([]ClickThroughBucket, error) {
+	ctx, end := o.begin(ctx, Call{Method: "ClickThroughRate", Index: alias})
+	res, err := o.next.ClickThroughRate(ctx, alias, since, interval)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) BookClicks(ctx context.Context, alias string, since time.Time) (map[string]int64, error) {
+	ctx, end := o.begin(ctx, Call{Method: "BookClicks", Index: alias})
+	res, err := o.next.BookClicks(ctx, alias, since)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) Ping(ctx context.Context) (bool, error) {
+	ctx, end := o.begin(ctx, Call{Method: "Ping"})
+	ok, err := o.next.Ping(ctx)
+	end(Outcome{Err: err})
+	return ok, err
+}
+
+func (o *observedClient) ClusterHealth(ctx context.Context) (*health.Response, error) {
+	ctx, end := o.begin(ctx, Call{Method: "ClusterHealth"})
+	res, err := o.next.ClusterHealth(ctx)
+	end(Outcome{Err: err})
+	return res, err
+}
+
+func (o *observedClient) IndexExists(ctx context.Context, name string) (bool, error) {
+	ctx, end := o.begin(ctx, Call{Method: "IndexExists", Index: name})
+	ok, err := o.next.IndexExists(ctx, name)
+	end(Outcome{Err: err})
+	return ok, err
+}
+
+func searchOutcome(res *search.Response, err error) Outcome {
+	out := Outcome{Err: err}
+	if res == nil {
+		return out
+	}
+	out.Took = time.Duration(res.Took) * time.Millisecond
+	if res.Hits.Total != nil {
+		out.Hits = res.Hits.Total.Value
+	} else {
+		out.Hits = int64(len(res.Hits.Hits))
+	}
+	return out
+}
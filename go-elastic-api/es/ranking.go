@@ -0,0 +1,179 @@
+package es
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"go-elastic-api/util"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/bulk"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/conflicts"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/fieldvaluefactormodifier"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/functionboostmode"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/functionscoremode"
+)
+
+// maxPopularBooks bounds the books given a popularity, the ones clicked the most.
+const maxPopularBooks = 10000
+
+// Ranking weighs the signals FullTextSearch blends into the score of a book:
+//
+//	score = Text * relevance + Popularity * log10(1 + popularity) + Rating * log10(1 + rating) + ReviewCount * log10(1 + review_count)
+//
+// The signals are log-scaled, so that a few books with many clicks or reviews do not drown text relevance.
+// A zero weight leaves its signal out, and the zero Ranking ranks by text relevance only.
+type Ranking struct {
+	Text        float64
+	Popularity  float64
+	Rating      float64
+	ReviewCount float64
+}
+
+// BooksRanking returns the ranking of full text search configured by cfg.
+func BooksRanking(cfg util.Config) Ranking {
+	return Ranking{
+		Text:        cfg.RankingTextWeight,
+		Popularity:  cfg.RankingPopularityWeight,
+		Rating:      cfg.RankingRatingWeight,
+		ReviewCount: cfg.RankingReviewCountWeight,
+	}
+}
+
+// WithRanking sets how FullTextSearch ranks books.
+func WithRanking(ranking Ranking) Option {
+	return func(es *ESClient) {
+		es.ranking = ranking
+	}
+}
+
+// query scores the books matching text with the signals of the ranking.
+func (r Ranking) query(text *types.Query) *types.Query {
+	var functions []types.FunctionScore
+	for _, signal := range []struct {
+		field  string
+		weight float64
+	}{
+		{"popularity", r.Popularity},
+		{"rating", r.Rating},
+		{"review_count", r.ReviewCount},
+	} {
+		if signal.weight <= 0 {
+			continue
+		}
+		functions = append(functions, types.FunctionScore{
+			FieldValueFactor: &types.FieldValueFactorScoreFunction{
+				Field:    signal.field,
+				Modifier: &fieldvaluefactormodifier.Log1p,
+				// Books without the field, or indices created before it was mapped, score 0
+				Missing: ptr(types.Float64(0)),
+			},
+			Weight: ptr(types.Float64(signal.weight)),
+		})
+	}
+	if len(functions) == 0 {
+		return text
+	}
+
+	// The boost of function_score would scale the whole score, the weight goes on the text query only
+	if weight := float32(r.Text); weight > 0 && weight != 1 {
+		text = &types.Query{Bool: &types.BoolQuery{Must: []types.Query{*text}, Boost: &weight}}
+	}
+	return &types.Query{
+		FunctionScore: &types.FunctionScoreQuery{
+			Query:     text,
+			Functions: functions,
+			ScoreMode: &functionscoremode.Sum,
+			BoostMode: &functionboostmode.Sum,
+		},
+	}
+}
+
+// storedBook is a book as it is written, with the popularity UpdatePopularity sets, which Book leaves out.
+type storedBook struct {
+	Book
+	Popularity *float64 `json:"popularity,omitempty"`
+}
+
+// popularities returns the popularity of the given books that have one, so that the writes
+// replacing them whole can carry it over.
+func (es *ESClient) popularities(ctx context.Context, bookIDs []string) (map[string]float64, error) {
+	popularity := make(map[string]float64)
+	if len(bookIDs) == 0 {
+		return popularity, nil
+	}
+	res, err := es.client.Mget().
+		Index(es.index).
+		Ids(bookIDs...).
+		SourceIncludes_("popularity").
+		Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read popularity of books: %w", err)
+	}
+	for _, doc := range res.Docs {
+		switch doc := doc.(type) {
+		case *types.MultiGetError:
+			return nil, fmt.Errorf("cannot read popularity of book %s: %s", doc.Id_, doc.Error.Type)
+		case *types.GetResult:
+			if !doc.Found {
+				continue
+			}
+			var source struct {
+				Popularity *float64 `json:"popularity"`
+			}
+			if err := json.Unmarshal(doc.Source_, &source); err != nil {
+				return nil, fmt.Errorf("cannot decode popularity of book %s: %w", doc.Id_, err)
+			}
+			if source.Popularity != nil {
+				popularity[doc.Id_] = *source.Popularity
+			}
+		}
+	}
+	return popularity, nil
+}
+
+// UpdatePopularity sets the popularity of the given books, and removes it from the books not given,
+// so that books nobody clicks any more lose their boost. Books that no longer exist are reported
+// as failed items of the response, not as an error.
+func (es *ESClient) UpdatePopularity(ctx context.Context, popularity map[string]float64) (*bulk.Response, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	res := &bulk.Response{}
+	ids := slices.Sorted(maps.Keys(popularity))
+	if len(ids) > 0 {
+		req := es.client.Bulk().Index(es.index)
+		for _, id := range ids {
+			doc, err := json.Marshal(map[string]float64{"popularity": popularity[id]})
+			if err != nil {
+				return nil, fmt.Errorf("cannot encode popularity of book %s: %w", id, err)
+			}
+			if err := req.UpdateOp(types.UpdateOperation{Id_: &id}, nil, &types.UpdateAction{Doc: doc}); err != nil {
+				return nil, fmt.Errorf("cannot add book %s to bulk request: %w", id, err)
+			}
+		}
+		var err error
+		if res, err = req.Do(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// Removing the stale scores after setting the new ones never leaves a popular book without its boost
+	_, err := es.client.UpdateByQuery(es.index).
+		Query(&types.Query{
+			Bool: &types.BoolQuery{
+				Filter:  []types.Query{{Exists: &types.ExistsQuery{Field: "popularity"}}},
+				MustNot: []types.Query{{Ids: &types.IdsQuery{Values: ids}}},
+			},
+		}).
+		Script(&types.Script{Source: ptr("ctx._source.remove('popularity')")}).
+		Conflicts(conflicts.Proceed).
+		Do(ctx)
+	if err != nil {
+		return res, fmt.Errorf("cannot remove stale popularity: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,107 @@
+package es
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRankingQuery(t *testing.T) {
+	text := &types.Query{QueryString: &types.QueryStringQuery{Query: "dune"}}
+
+	// Text relevance only
+	require.Same(t, text, Ranking{}.query(text))
+	require.Same(t, text, Ranking{Text: 2}.query(text))
+
+	b, err := json.Marshal(Ranking{Text: 2, Popularity: 1, ReviewCount: 0.5}.query(text))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"function_score": {
+		"query": {"bool": {"must": [{"query_string": {"query": "dune"}}], "boost": 2}},
+		"functions": [
+			{"field_value_factor": {"field": "popularity", "modifier": "log1p", "missing": 0}, "weight": 1},
+			{"field_value_factor": {"field": "review_count", "modifier": "log1p", "missing": 0}, "weight": 0.5}
+		],
+		"score_mode": "sum",
+		"boost_mode": "sum"
+	}}`, string(b))
+
+	// The default text weight leaves the text query as is
+	b, err = json.Marshal(Ranking{Text: 1, Popularity: 1}.query(text))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"function_score": {
+		"query": {"query_string": {"query": "dune"}},
+		"functions": [{"field_value_factor": {"field": "popularity", "modifier": "log1p", "missing": 0}, "weight": 1}],
+		"score_mode": "sum",
+		"boost_mode": "sum"
+	}}`, string(b))
+}
+
+func TestUpdatePopularity(t *testing.T) {
+	popular, forgotten := createRandomBook(), createRandomBook()
+	for _, book := range []Book{popular, forgotten} {
+		_, err := testClient.AddBook(context.Background(), book)
+		require.NoError(t, err)
+		defer testClient.DeleteBook(context.Background(), book.ID)
+	}
+	popularity := func(id string) any {
+		res, err := testClient.GetBook(context.Background(), id)
+		require.NoError(t, err)
+		require.Len(t, res.Hits.Hits, 1)
+		var source map[string]any
+		require.NoError(t, json.Unmarshal(res.Hits.Hits[0].Source_, &source))
+		return source["popularity"]
+	}
+
+	res, err := testClient.UpdatePopularity(context.Background(), map[string]float64{popular.ID: 5, forgotten.ID: 2, "missing-book": 1})
+	require.NoError(t, err)
+	require.True(t, res.Errors)
+	time.Sleep(1 * time.Second)
+	require.Equal(t, 5.0, popularity(popular.ID))
+	require.Equal(t, 2.0, popularity(forgotten.ID))
+
+	// Books left out lose their popularity
+	_, err = testClient.UpdatePopularity(context.Background(), map[string]float64{popular.ID: 6})
+	require.NoError(t, err)
+	time.Sleep(1 * time.Second)
+	require.Equal(t, 6.0, popularity(popular.ID))
+	require.Nil(t, popularity(forgotten.ID))
+
+	// Replacing a book, or updating its embedding, keeps its popularity
+	popular.Name = "Replaced"
+	_, err = testClient.UpdateBook(context.Background(), popular)
+	require.NoError(t, err)
+	_, err = testClient.UpdateEmbeddings(context.Background(), []Book{popular})
+	require.NoError(t, err)
+	time.Sleep(1 * time.Second)
+	require.Equal(t, 6.0, popularity(popular.ID))
+
+	// So does adding it again, or importing it
+	_, err = testClient.AddBook(context.Background(), popular)
+	require.NoError(t, err)
+	time.Sleep(1 * time.Second)
+	require.Equal(t, 6.0, popularity(popular.ID))
+
+	imported, err := testClient.BulkAddBooks(context.Background(), []Book{popular, forgotten})
+	require.NoError(t, err)
+	require.False(t, imported.Errors)
+	time.Sleep(1 * time.Second)
+	require.Equal(t, 6.0, popularity(popular.ID))
+	require.Nil(t, popularity(forgotten.ID))
+}
+
+func TestStoredBook(t *testing.T) {
+	b, err := json.Marshal(storedBook{Book: Book{ID: "1", Name: "Dune"}, Popularity: ptr(3.0)})
+	require.NoError(t, err)
+	var source map[string]any
+	require.NoError(t, json.Unmarshal(b, &source))
+	require.Equal(t, "Dune", source["name"])
+	require.Equal(t, 3.0, source["popularity"])
+
+	b, err = json.Marshal(storedBook{Book: Book{ID: "1"}})
+	require.NoError(t, err)
+	require.NotContains(t, string(b), "popularity")
+}
@@ -0,0 +1,226 @@
+package es
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/synonyms/putsynonym"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/textquerytype"
+)
+
+// BooksSynonymsSet is the synonyms set of the book_search analyzer of a books index.
+// Elasticsearch reloads the analyzer whenever the set changes, without a reindex.
+const BooksSynonymsSet = "books-synonyms"
+
+// maxSynonymRules bounds the rules read from a synonyms set.
+const maxSynonymRules = 10000
+
+// defaultRelevance holds the relevance settings used without a RELEVANCE_FILE.
+//
+//go:embed relevance.json
+var defaultRelevance []byte
+
+// Relevance tunes how FullTextSearch matches a query string, and how a books index analyzes it.
+type Relevance struct {
+	// Fields maps the fields searched to their boost
+	Fields map[string]float64 `json:"fields"`
+	// PhraseFields maps fields to the boost of the books where the query appears as a phrase in them,
+	// PhraseSlop words apart at most
+	PhraseFields map[string]float64 `json:"phrase_fields,omitempty"`
+	PhraseSlop   int                `json:"phrase_slop,omitempty"`
+	// TieBreaker is how much the fields other than the best matching one add to the score, from 0 to 1
+	TieBreaker float64 `json:"tie_breaker"`
+	// Stopwords are dropped from queries, for the indices created with them
+	Stopwords []string `json:"stopwords,omitempty"`
+	// Synonyms are the rules, in Solr format, the synonyms set starts with when an index is created
+	// before the set exists. The set is managed with PutSynonyms afterwards.
+	Synonyms []string `json:"synonyms,omitempty"`
+}
+
+// DefaultRelevance returns the relevance settings used without a relevance file.
+func DefaultRelevance() Relevance {
+	relevance, err := parseRelevance(defaultRelevance)
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded relevance settings: %s", err))
+	}
+	return relevance
+}
+
+// LoadRelevance reads relevance settings from a JSON file with the format of DefaultRelevance,
+// or returns the default ones for an empty path.
+func LoadRelevance(path string) (Relevance, error) {
+	if path == "" {
+		return DefaultRelevance(), nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Relevance{}, fmt.Errorf("cannot read relevance file: %w", err)
+	}
+	relevance, err := parseRelevance(b)
+	if err != nil {
+		return Relevance{}, fmt.Errorf("invalid relevance file %s: %w", path, err)
+	}
+	return relevance, nil
+}
+
+func parseRelevance(b []byte) (Relevance, error) {
+	var relevance Relevance
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&relevance); err != nil {
+		return Relevance{}, err
+	}
+
+	var errs []error
+	if len(relevance.Fields) == 0 {
+		errs = append(errs, errors.New("fields must not be empty"))
+	}
+	for field, boost := range relevance.Fields {
+		if boost <= 0 {
+			errs = append(errs, fmt.Errorf("boost of field %s must be positive, got %g", field, boost))
+		}
+	}
+	for field, boost := range relevance.PhraseFields {
+		if boost <= 0 {
+			errs = append(errs, fmt.Errorf("boost of phrase field %s must be positive, got %g", field, boost))
+		}
+	}
+	if relevance.PhraseSlop < 0 {
+		errs = append(errs, fmt.Errorf("phrase_slop must not be negative, got %d", relevance.PhraseSlop))
+	}
+	if relevance.TieBreaker < 0 || relevance.TieBreaker > 1 {
+		errs = append(errs, fmt.Errorf("tie_breaker must be between 0 and 1, got %g", relevance.TieBreaker))
+	}
+	return relevance, errors.Join(errs...)
+}
+
+// WithRelevance sets how FullTextSearch matches query strings, and the stopwords and first synonyms
+// of the indices CreateIndex creates.
+func WithRelevance(relevance Relevance) Option {
+	return func(es *ESClient) {
+		es.relevance = relevance
+	}
+}
+
+// query matches a query string against the boosted fields, and boosts the books where it appears as a phrase.
+func (r Relevance) query(query string) *types.Query {
+	tieBreaker := types.Float64(r.TieBreaker)
+	text := &types.Query{
+		QueryString: &types.QueryStringQuery{
+			Query:      query,
+			Fields:     boostedFields(r.Fields),
+			TieBreaker: &tieBreaker,
+		},
+	}
+	if len(r.PhraseFields) == 0 {
+		return text
+	}
+
+	slop := r.PhraseSlop
+	return &types.Query{
+		Bool: &types.BoolQuery{
+			Must: []types.Query{*text},
+			Should: []types.Query{{
+				MultiMatch: &types.MultiMatchQuery{
+					Query:  query,
+					Type:   &textquerytype.Phrase,
+					Fields: boostedFields(r.PhraseFields),
+					Slop:   &slop,
+				},
+			}},
+		},
+	}
+}
+
+// boostedFields returns fields as field^boost, sorted by field.
+func boostedFields(fields map[string]float64) []string {
+	boosted := make([]string, 0, len(fields))
+	for _, field := range slices.Sorted(maps.Keys(fields)) {
+		boosted = append(boosted, field+"^"+strconv.FormatFloat(fields[field], 'f', -1, 64))
+	}
+	return boosted
+}
+
+// indexDefinition returns the books settings and mappings with the stopwords of the relevance settings.
+func (r Relevance) indexDefinition() ([]byte, error) {
+	if len(r.Stopwords) == 0 {
+		return booksIndex, nil
+	}
+	var definition map[string]any
+	if err := json.Unmarshal(booksIndex, &definition); err != nil {
+		return nil, fmt.Errorf("cannot decode books index definition: %w", err)
+	}
+	stop, ok := lookup(definition, "settings", "analysis", "filter", "book_stop").(map[string]any)
+	if !ok {
+		return nil, errors.New("books index definition has no book_stop filter")
+	}
+	stop["stopwords"] = r.Stopwords
+	return json.Marshal(definition)
+}
+
+// lookup returns the value at a path of nested JSON objects, nil if there is none.
+func lookup(v any, path ...string) any {
+	for _, key := range path {
+		object, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = object[key]
+	}
+	return v
+}
+
+// Synonyms returns the rules of a synonyms set.
+func (es *ESClient) Synonyms(ctx context.Context, set string) ([]types.SynonymRuleRead, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	res, err := es.client.Synonyms.GetSynonym(set).Size(maxSynonymRules).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return res.SynonymsSet, nil
+}
+
+// PutSynonyms replaces the rules of a synonyms set, creating it if needed. Elasticsearch reloads
+// the search analyzers using the set, so searches use the new rules right away, without a reindex.
+func (es *ESClient) PutSynonyms(ctx context.Context, set string, rules []string) (*putsynonym.Response, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	synonyms := make([]types.SynonymRule, 0, len(rules))
+	for _, rule := range rules {
+		synonyms = append(synonyms, types.SynonymRule{Synonyms: strings.TrimSpace(rule)})
+	}
+	return es.client.Synonyms.PutSynonym(set).Request(&putsynonym.Request{SynonymsSet: synonyms}).Do(ctx)
+}
+
+// ensureSynonyms creates the books synonyms set with the synonyms of the relevance settings,
+// unless it exists. An index cannot use a synonyms set that does not exist.
+func (es *ESClient) ensureSynonyms(ctx context.Context) error {
+	_, err := es.client.Synonyms.GetSynonym(BooksSynonymsSet).Size(1).Do(ctx)
+	var esErr *types.ElasticsearchError
+	if !errors.As(err, &esErr) || esErr.Status != http.StatusNotFound {
+		return err
+	}
+	synonyms := make([]types.SynonymRule, 0, len(es.relevance.Synonyms))
+	for _, rule := range es.relevance.Synonyms {
+		synonyms = append(synonyms, types.SynonymRule{Synonyms: rule})
+	}
+	_, err = es.client.Synonyms.PutSynonym(BooksSynonymsSet).Request(&putsynonym.Request{SynonymsSet: synonyms}).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot create synonyms set %s: %w", BooksSynonymsSet, err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package es
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadRelevance(t *testing.T) {
+	relevance, err := LoadRelevance("")
+	require.NoError(t, err)
+	require.Equal(t, 3.0, relevance.Fields["name"])
+	require.Contains(t, relevance.Synonyms, "sci-fi, science fiction")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "relevance.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"fields": {"name": 2, "author": 0}, "tie_breaker": 1.5}`), 0o600))
+	_, err = LoadRelevance(path)
+	require.ErrorContains(t, err, "boost of field author must be positive, got 0")
+	require.ErrorContains(t, err, "tie_breaker must be between 0 and 1, got 1.5")
+
+	// Misspelled settings are rejected rather than ignored
+	require.NoError(t, os.WriteFile(path, []byte(`{"fields": {"name": 2}, "tiebreaker": 0.5}`), 0o600))
+	_, err = LoadRelevance(path)
+	require.ErrorContains(t, err, `unknown field "tiebreaker"`)
+}
+
+func TestRelevanceQuery(t *testing.T) {
+	b, err := json.Marshal(Relevance{Fields: map[string]float64{"name": 3, "content": 0.5}, TieBreaker: 0.3}.query("dune"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"query_string": {"query": "dune", "fields": ["content^0.5", "name^3"], "tie_breaker": 0.3}}`, string(b))
+
+	b, err = json.Marshal(Relevance{Fields: map[string]float64{"name": 3}, PhraseFields: map[string]float64{"name": 4}, PhraseSlop: 2}.query("snow crash"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"bool": {
+		"must": [{"query_string": {"query": "snow crash", "fields": ["name^3"], "tie_breaker": 0}}],
+		"should": [{"multi_match": {"query": "snow crash", "type": "phrase", "fields": ["name^4"], "slop": 2}}]
+	}}`, string(b))
+}
+
+func TestRelevanceIndexDefinition(t *testing.T) {
+	b, err := Relevance{Stopwords: []string{"the", "of"}}.indexDefinition()
+	require.NoError(t, err)
+
+	var definition map[string]any
+	require.NoError(t, json.Unmarshal(b, &definition))
+	require.Equal(t, []any{"the", "of"}, lookup(definition, "settings", "analysis", "filter", "book_stop", "stopwords"))
+	require.Equal(t, BooksSynonymsSet, lookup(definition, "settings", "analysis", "filter", "book_synonyms", "synonyms_set"))
+	require.Equal(t, "book_search", lookup(definition, "mappings", "properties", "name", "search_analyzer"))
+
+	// Without stopwords, the built-in ones are kept
+	b, err = Relevance{}.indexDefinition()
+	require.NoError(t, err)
+	require.JSONEq(t, string(booksIndex), string(b))
+}
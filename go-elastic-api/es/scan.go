@@ -0,0 +1,92 @@
+package es
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+)
+
+// scanKeepAlive is how long the point in time of ScanBooks is kept between two pages.
+const scanKeepAlive = "1m"
+
+// ScanBooks calls fn with every book matching filter, in pages of batchSize books.
+// An empty filter matches all books. The books are read from a point in time, so
+// writes made while scanning are not seen, and pages follow each other with search_after,
+// which has no limit on the number of books unlike from and size.
+// Scanning stops at the first error returned by fn.
+func (es *ESClient) ScanBooks(ctx context.Context, filter map[string]any, batchSize int, fn func([]Book) error) error {
+	query := &types.Query{MatchAll: &types.MatchAllQuery{}}
+	if q := filterQuery(filter); len(q.Bool.Must) > 0 {
+		query = q
+	}
+
+	pit, err := es.openPointInTime(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot open point in time: %w", err)
+	}
+	defer func() {
+		// The scan may have ended because ctx is done, closing is still worth a try
+		ctx, cancel := es.withTimeout(context.WithoutCancel(ctx))
+		defer cancel()
+		_, _ = es.client.ClosePointInTime().Id(pit).Do(ctx)
+	}()
+
+	var after []types.FieldValue
+	for {
+		res, err := es.scanPage(ctx, query, pit, after, batchSize)
+		if err != nil {
+			return err
+		}
+		if res.PitId != nil {
+			pit = *res.PitId
+		}
+		if len(res.Hits.Hits) == 0 {
+			return nil
+		}
+
+		books := make([]Book, 0, len(res.Hits.Hits))
+		for _, hit := range res.Hits.Hits {
+			var book Book
+			if err := json.Unmarshal(hit.Source_, &book); err != nil {
+				return fmt.Errorf("cannot decode book %s: %w", *hit.Id_, err)
+			}
+			book.ID = *hit.Id_
+			books = append(books, book)
+		}
+		if err := fn(books); err != nil {
+			return err
+		}
+		after = res.Hits.Hits[len(res.Hits.Hits)-1].Sort
+	}
+}
+
+func (es *ESClient) openPointInTime(ctx context.Context) (string, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	res, err := es.client.OpenPointInTime(es.index).KeepAlive(scanKeepAlive).Do(ctx)
+	if err != nil {
+		return "", err
+	}
+	return res.Id, nil
+}
+
+func (es *ESClient) scanPage(ctx context.Context, query *types.Query, pit string, after []types.FieldValue, size int) (*search.Response, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	// _shard_doc is the cheapest total order of a point in time
+	return es.client.Search().
+		Request(&search.Request{
+			Query:       query,
+			Source_:     bookSource,
+			Pit:         &types.PointInTimeReference{Id: pit, KeepAlive: scanKeepAlive},
+			Sort:        []types.SortCombinations{"_shard_doc"},
+			SearchAfter: after,
+			Size:        &size,
+		}).
+		Do(ctx)
+}
@@ -0,0 +1,51 @@
+package es
+
+import (
+	"context"
+	"errors"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+)
+
+var (
+	// ErrBookNotFound is returned when a book does not exist, or is outside the publisher scope of the caller.
+	ErrBookNotFound = errors.New("book not found")
+	// ErrOutOfScope is returned when a write would put a book outside the publisher scope of the caller.
+	ErrOutOfScope = errors.New("book publisher is outside the allowed scope")
+)
+
+type publisherScopeKey struct{}
+
+// WithPublisherScope returns a copy of ctx that restricts writes made with it
+// to the books of publisher. Reads are not restricted.
+func WithPublisherScope(ctx context.Context, publisher string) context.Context {
+	return context.WithValue(ctx, publisherScopeKey{}, publisher)
+}
+
+// PublisherScope returns the publisher the writes made with ctx are restricted to, if any.
+func PublisherScope(ctx context.Context) (string, bool) {
+	publisher, ok := ctx.Value(publisherScopeKey{}).(string)
+	return publisher, ok && publisher != ""
+}
+
+// scopeFilter returns the filter clauses selecting the books writable with ctx:
+// none without a scope, a term query on the publisher otherwise.
+func scopeFilter(ctx context.Context) []types.Query {
+	publisher, ok := PublisherScope(ctx)
+	if !ok {
+		return nil
+	}
+	return []types.Query{{
+		Term: map[string]types.TermQuery{
+			"publisher.keyword": {Value: publisher},
+		},
+	}}
+}
+
+// checkScope reports ErrOutOfScope when book does not belong to the publisher scope of ctx.
+func checkScope(ctx context.Context, book Book) error {
+	if publisher, ok := PublisherScope(ctx); ok && book.Publisher != publisher {
+		return ErrOutOfScope
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package es
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublisherScope(t *testing.T) {
+	ctx := context.Background()
+	require.Nil(t, scopeFilter(ctx))
+	require.NoError(t, checkScope(ctx, Book{Publisher: "Other"}))
+
+	scoped := WithPublisherScope(ctx, "Acme")
+	publisher, ok := PublisherScope(scoped)
+	require.True(t, ok)
+	require.Equal(t, "Acme", publisher)
+
+	require.Len(t, scopeFilter(scoped), 1)
+	require.Equal(t, "Acme", scopeFilter(scoped)[0].Term["publisher.keyword"].Value)
+	require.NoError(t, checkScope(scoped, Book{Publisher: "Acme"}))
+	require.ErrorIs(t, checkScope(scoped, Book{Publisher: "Other"}), ErrOutOfScope)
+
+	// Every write checks the scope before reaching Elasticsearch
+	_, err := (&ESClient{}).AddBook(scoped, Book{ID: "42", Publisher: "Other"})
+	require.ErrorIs(t, err, ErrOutOfScope)
+
+	_, ok = PublisherScope(WithPublisherScope(ctx, ""))
+	require.False(t, ok)
+}
+
+func TestFilterQueryCategories(t *testing.T) {
+	// Categories decoded from a JSON body are a []any
+	query := filterQuery(map[string]any{"categories": []any{"Classics", "Fantasy"}})
+	require.Len(t, query.Bool.Must, 1)
+	require.Equal(t, []string{"Classics", "Fantasy"}, query.Bool.Must[0].Terms.TermsQuery["categories.keyword"])
+
+	query = filterQuery(map[string]any{"categories": []any{}})
+	require.Empty(t, query.Bool.Must)
+}
+
+func TestFilterQueryLanguage(t *testing.T) {
+	query := filterQuery(map[string]any{"author": "Nguyen Nhat Anh", "publisher": "Nhà Xuất Bản Trẻ", "language": "vi"})
+	require.Len(t, query.Bool.Must, 3)
+	require.Equal(t, []string{"author", "author.folded"}, query.Bool.Must[0].MultiMatch.Fields)
+	require.Equal(t, "Nhà Xuất Bản Trẻ", query.Bool.Must[1].Bool.Should[1].Term["publisher.normalized"].Value)
+	require.Equal(t, "vi", query.Bool.Must[2].Term["language"].Value)
+}
+
+func TestFilterQueryTrimsCriteria(t *testing.T) {
+	padded := filterQuery(map[string]any{"publisher": " Ace ", "author": "Frank Herbert ", "categories": []any{"Fantasy", "Classics"}})
+	trimmed := filterQuery(map[string]any{"publisher": "Ace", "author": "Frank Herbert", "categories": []any{"Classics", "Fantasy"}})
+	require.Equal(t, trimmed, padded, "filters sharing a cache key must find the same books")
+}
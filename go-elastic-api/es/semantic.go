@@ -0,0 +1,214 @@
+package es
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go-elastic-api/embedding"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/bulk"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/totalhitsrelation"
+)
+
+// rrfRankConstant is the k of reciprocal rank fusion, which keeps the first ranks of one search
+// from outweighing a book found by both. 60 is the value of the original paper and of Elasticsearch.
+const rrfRankConstant = 60
+
+// maxKnnCandidates bounds the candidates of a kNN search, Elasticsearch rejects more.
+const maxKnnCandidates = 10000
+
+// WithEmbedder sets the embedder of the books written without an embedding and of the queries
+// of SemanticSearch and HybridSearch. The default is the offline embedding.Local.
+// Changing embedders requires embedding the books again, their vectors are not comparable.
+func WithEmbedder(embedder embedding.Embedder) Option {
+	return func(es *ESClient) {
+		es.embedder = embedder
+	}
+}
+
+// embeddingText is the text of a book that is embedded, what it is about rather than its details.
+func embeddingText(book Book) string {
+	parts := append([]string{book.Name, book.Description}, book.Categories...)
+	parts = append(parts, book.Tags...)
+	return strings.Join(slices.DeleteFunc(parts, func(s string) bool { return s == "" }), "\n")
+}
+
+func (es *ESClient) embedBook(ctx context.Context, book Book) (Book, error) {
+	books, err := es.embedBooks(ctx, []Book{book})
+	if err != nil {
+		return book, err
+	}
+	return books[0], nil
+}
+
+// embedBooks returns books with an embedding computed for the ones without. books is left unchanged.
+func (es *ESClient) embedBooks(ctx context.Context, books []Book) ([]Book, error) {
+	var texts []string
+	var missing []int
+	for i, book := range books {
+		if len(book.Embedding) == 0 {
+			texts = append(texts, embeddingText(book))
+			missing = append(missing, i)
+		}
+	}
+	if len(texts) == 0 {
+		return books, nil
+	}
+
+	vectors, err := es.embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("cannot embed books: %w", err)
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("cannot embed books: got %d vectors for %d books", len(vectors), len(texts))
+	}
+	books = slices.Clone(books)
+	for j, i := range missing {
+		books[i].Embedding = vectors[j]
+	}
+	return books, nil
+}
+
+// UpdateEmbeddings embeds books again, ignoring the embeddings they have, and writes only their
+// embedding, so that the popularity and the rest of the stored books are left as they are.
+// Failures of individual items are reported in the response, not as an error.
+func (es *ESClient) UpdateEmbeddings(ctx context.Context, books []Book) (*bulk.Response, error) {
+	if len(books) == 0 {
+		return &bulk.Response{}, nil
+	}
+	books = slices.Clone(books)
+	for i := range books {
+		books[i].Embedding = nil
+	}
+	books, err := es.embedBooks(ctx, books)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	req := es.client.Bulk().Index(es.index)
+	for _, book := range books {
+		id := book.ID
+		doc, err := json.Marshal(map[string][]float32{"embedding": book.Embedding})
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode embedding of book %s: %w", id, err)
+		}
+		if err := req.UpdateOp(types.UpdateOperation{Id_: &id}, nil, &types.UpdateAction{Doc: doc}); err != nil {
+			return nil, fmt.Errorf("cannot add book %s to bulk request: %w", id, err)
+		}
+	}
+	return req.Do(ctx)
+}
+
+// window returns the number of hits up to the end of the page.
+func (p Page) window() int {
+	size := p.Size
+	if size <= 0 {
+		size = 10
+	}
+	return p.From + size
+}
+
+// SemanticSearch finds the books whose embedding is the nearest to the one of query,
+// with an approximate kNN search. A query without anything to embed finds no books.
+func (es *ESClient) SemanticSearch(ctx context.Context, query string, page Page) (*search.Response, error) {
+	vectors, err := es.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("cannot embed query: %w", err)
+	}
+	if len(vectors) != 1 || vectors[0] == nil {
+		return &search.Response{
+			Hits: types.HitsMetadata{Hits: []types.Hit{}, Total: &types.TotalHits{Relation: totalhitsrelation.Eq}},
+		}, nil
+	}
+
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	k := min(page.window(), maxKnnCandidates)
+	candidates := min(max(10*k, 100), maxKnnCandidates)
+	req := &search.Request{
+		Knn: []types.KnnSearch{{
+			Field:         "embedding",
+			QueryVector:   vectors[0],
+			K:             &k,
+			NumCandidates: &candidates,
+		}},
+		Source_: bookSource,
+	}
+	page.apply(req)
+
+	return es.client.Search().
+		Index(es.index).
+		Request(req).
+		Do(ctx)
+}
+
+// HybridSearch fuses the books found by FullTextSearch and SemanticSearch with reciprocal rank fusion:
+// a book scores 1 / (60 + rank) in each search it is found by, so that books found by both come first.
+// Both searches read the books up to the end of page, which is then cut from the fused list,
+// and the total is the number of books fused.
+func (es *ESClient) HybridSearch(ctx context.Context, query string, page Page) (*search.Response, error) {
+	window := Page{Size: page.window()}
+
+	var semantic *search.Response
+	var semanticErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		semantic, semanticErr = es.SemanticSearch(ctx, query, window)
+	}()
+	text, err := es.FullTextSearch(ctx, query, window)
+	<-done
+	if err != nil {
+		return nil, err
+	}
+	if semanticErr != nil {
+		return nil, semanticErr
+	}
+
+	hits := reciprocalRankFusion(text.Hits.Hits, semantic.Hits.Hits)
+	res := *text
+	res.Took += semantic.Took
+	res.Hits = types.HitsMetadata{
+		Hits:  hits[min(page.From, len(hits)):min(page.window(), len(hits))],
+		Total: &types.TotalHits{Value: int64(len(hits)), Relation: totalhitsrelation.Gte},
+	}
+	if len(hits) > 0 {
+		res.Hits.MaxScore = hits[0].Score_
+	}
+	return &res, nil
+}
+
+// reciprocalRankFusion merges ranked lists of hits into one, ranked by the sum of 1 / (rrfRankConstant + rank)
+// over the lists a hit is in, rank starting at 1. Ties keep the order of the first lists.
+func reciprocalRankFusion(lists ...[]types.Hit) []types.Hit {
+	scores := make(map[string]float64)
+	var hits []types.Hit
+	for _, list := range lists {
+		for rank, hit := range list {
+			if hit.Id_ == nil {
+				continue
+			}
+			if _, ok := scores[*hit.Id_]; !ok {
+				hits = append(hits, hit)
+			}
+			scores[*hit.Id_] += 1 / float64(rrfRankConstant+rank+1)
+		}
+	}
+	for i := range hits {
+		hits[i].Score_ = ptr(types.Float64(scores[*hits[i].Id_]))
+	}
+	slices.SortStableFunc(hits, func(a, b types.Hit) int {
+		return cmp.Compare(*b.Score_, *a.Score_)
+	})
+	return hits
+}
@@ -0,0 +1,75 @@
+package es
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go-elastic-api/embedding"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReciprocalRankFusion(t *testing.T) {
+	hits := func(ids ...string) []types.Hit {
+		list := make([]types.Hit, len(ids))
+		for i, id := range ids {
+			list[i] = types.Hit{Id_: ptr(id)}
+		}
+		return list
+	}
+
+	fused := reciprocalRankFusion(hits("a", "b", "c"), hits("c", "d", "a"))
+	var ids []string
+	for _, hit := range fused {
+		ids = append(ids, *hit.Id_)
+	}
+	// a and c are found by both, a ranks higher overall; b and d tie, the first list wins
+	require.Equal(t, []string{"a", "c", "b", "d"}, ids)
+	require.InDelta(t, 1.0/61+1.0/63, float64(*fused[0].Score_), 1e-9)
+
+	require.Empty(t, reciprocalRankFusion(nil, nil))
+}
+
+func TestEmbedBooks(t *testing.T) {
+	client := &ESClient{embedder: embedding.Local{}}
+	given := make([]float32, embedding.Dims)
+	books := []Book{{ID: "1", Name: "Dune", Tags: []string{"desert"}}, {ID: "2", Name: "Snow Crash", Embedding: given}}
+
+	embedded, err := client.embedBooks(context.Background(), books)
+	require.NoError(t, err)
+	require.Len(t, embedded[0].Embedding, embedding.Dims)
+	require.Equal(t, given, embedded[1].Embedding, "given embeddings are kept")
+	require.Nil(t, books[0].Embedding, "books are left unchanged")
+
+	require.Equal(t, "Dune\nA desert planet\nScience fiction\ndesert",
+		embeddingText(Book{Name: "Dune", Description: "A desert planet", Categories: []string{"Science fiction"}, Tags: []string{"desert"}}))
+}
+
+func TestEmbeddingMapping(t *testing.T) {
+	var definition map[string]any
+	require.NoError(t, json.Unmarshal(booksIndex, &definition))
+	require.Equal(t, "dense_vector", lookup(definition, "mappings", "properties", "embedding", "type"))
+	require.Equal(t, float64(embedding.Dims), lookup(definition, "mappings", "properties", "embedding", "dims"))
+}
+
+func TestSemanticSearch(t *testing.T) {
+	book := createRandomBook()
+	book.Description = "A young wizard raised by dragons in a mountain kingdom"
+	_, err := testClient.AddBook(context.Background(), book)
+	require.NoError(t, err)
+	defer testClient.DeleteBook(context.Background(), book.ID)
+
+	time.Sleep(1 * time.Second)
+
+	res, err := testClient.SemanticSearch(context.Background(), "wizard raised by dragons", Page{Size: 100})
+	require.NoError(t, err)
+	checkResult(t, res, book)
+	require.NotContains(t, string(res.Hits.Hits[0].Source_), "embedding")
+
+	res, err = testClient.HybridSearch(context.Background(), "wizard raised by dragons", Page{Size: 100})
+	require.NoError(t, err)
+	checkResult(t, res, book)
+}
@@ -0,0 +1,129 @@
+package es
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go-elastic-api/util"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+)
+
+// similarFields are the fields SimilarBooks compares, what a book is about rather than who made it.
+var similarFields = []string{"description", "content", "categories", "tags"}
+
+// Similarity weighs the score SimilarBooks adds to the books by the same author or publisher
+// as the book they are similar to. A zero weight ignores the author or publisher.
+type Similarity struct {
+	Author    float64
+	Publisher float64
+}
+
+// BooksSimilarity returns the weights of similar books configured by cfg.
+func BooksSimilarity(cfg util.Config) Similarity {
+	return Similarity{
+		Author:    cfg.SimilarAuthorWeight,
+		Publisher: cfg.SimilarPublisherWeight,
+	}
+}
+
+// WithSimilarity sets how SimilarBooks boosts the books by the same author or publisher.
+func WithSimilarity(similarity Similarity) Option {
+	return func(es *ESClient) {
+		es.similarity = similarity
+	}
+}
+
+// SimilarFilter narrows the books recommended by SimilarBooks.
+type SimilarFilter struct {
+	// SameLanguage keeps the books in the language of the book, when it has one
+	SameLanguage bool
+	// ReleaseAfter keeps the books released on or after this date, YYYY-MM-DD
+	ReleaseAfter string
+}
+
+// SimilarBooks recommends the books whose description, content, categories and tags are the most
+// like the ones of a book, with a more_like_this query, boosted as set with WithSimilarity.
+// The book itself is left out. It returns ErrBookNotFound if the book does not exist.
+func (es *ESClient) SimilarBooks(ctx context.Context, bookID string, filter SimilarFilter, page Page) (*search.Response, error) {
+	res, err := es.GetBook(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Hits.Hits) == 0 {
+		return nil, ErrBookNotFound
+	}
+	hit := res.Hits.Hits[0]
+	var book Book
+	if err := json.Unmarshal(hit.Source_, &book); err != nil {
+		return nil, fmt.Errorf("cannot decode book %s: %w", bookID, err)
+	}
+	book.ID = bookID
+
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	// The book is liked in its index, an alias may point to several
+	req := &search.Request{
+		Query:   es.similarity.query(book, hit.Index_, filter),
+		Source_: bookSource,
+	}
+	page.apply(req)
+
+	return es.client.Search().
+		Index(es.index).
+		Request(req).
+		Do(ctx)
+}
+
+// query matches the books like book in index, the index it is stored in, and adds the weights
+// of the similarity to the ones by the same author or publisher.
+func (s Similarity) query(book Book, index string, filter SimilarFilter) *types.Query {
+	query := &types.BoolQuery{
+		Must: []types.Query{{
+			MoreLikeThis: &types.MoreLikeThisQuery{
+				Fields: similarFields,
+				Like:   []types.Like{types.LikeDocument{Index_: &index, Id_: &book.ID}},
+				// A catalog has few books per term, the defaults of 2 and 5 would leave most books without terms
+				MinTermFreq: ptr(1),
+				MinDocFreq:  ptr(1),
+				Include:     ptr(false),
+			},
+		}},
+	}
+
+	for _, boost := range []struct {
+		field  string
+		value  string
+		weight float64
+	}{
+		{"author.keyword", book.Author, s.Author},
+		{"publisher.keyword", book.Publisher, s.Publisher},
+	} {
+		if boost.weight <= 0 || boost.value == "" {
+			continue
+		}
+		query.Should = append(query.Should, types.Query{
+			ConstantScore: &types.ConstantScoreQuery{
+				Filter: types.Query{Term: map[string]types.TermQuery{boost.field: {Value: boost.value}}},
+				Boost:  ptr(float32(boost.weight)),
+			},
+		})
+	}
+
+	if filter.SameLanguage && book.Language != "" {
+		query.Filter = append(query.Filter, types.Query{
+			Term: map[string]types.TermQuery{"language": {Value: book.Language}},
+		})
+	}
+	if filter.ReleaseAfter != "" {
+		query.Filter = append(query.Filter, types.Query{
+			Range: map[string]types.RangeQuery{
+				"release_date": &types.DateRangeQuery{Gte: &filter.ReleaseAfter},
+			},
+		})
+	}
+	return &types.Query{Bool: query}
+}
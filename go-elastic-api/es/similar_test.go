@@ -0,0 +1,58 @@
+package es
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimilarQuery(t *testing.T) {
+	book := Book{ID: "1", Author: "Frank Herbert", Publisher: "Ace", Language: "en"}
+	query := Similarity{Author: 2, Publisher: 0.5}.query(book, "books-v2", SimilarFilter{SameLanguage: true, ReleaseAfter: "1960-01-01"})
+
+	mlt := query.Bool.Must[0].MoreLikeThis
+	require.Equal(t, []string{"description", "content", "categories", "tags"}, mlt.Fields)
+	require.Equal(t, []types.Like{types.LikeDocument{Index_: ptr("books-v2"), Id_: ptr("1")}}, mlt.Like)
+	require.False(t, *mlt.Include, "the book is left out")
+
+	require.Len(t, query.Bool.Should, 2)
+	require.Equal(t, "Frank Herbert", query.Bool.Should[0].ConstantScore.Filter.Term["author.keyword"].Value)
+	require.Equal(t, float32(2), *query.Bool.Should[0].ConstantScore.Boost)
+	require.Equal(t, float32(0.5), *query.Bool.Should[1].ConstantScore.Boost)
+
+	require.Len(t, query.Bool.Filter, 2)
+	require.Equal(t, "en", query.Bool.Filter[0].Term["language"].Value)
+	require.Equal(t, "1960-01-01", *query.Bool.Filter[1].Range["release_date"].(*types.DateRangeQuery).Gte)
+
+	// A zero weight, or a book without a language, adds nothing
+	query = Similarity{Author: 1}.query(Book{ID: "1", Author: "Frank Herbert", Publisher: "Ace"}, "books-v2", SimilarFilter{SameLanguage: true})
+	require.Len(t, query.Bool.Should, 1)
+	require.Empty(t, query.Bool.Filter)
+}
+
+func TestSimilarBooks(t *testing.T) {
+	book := createRandomBook()
+	book.Description = "A lighthouse keeper befriends a whale during a storm at sea"
+	similar := createRandomBook()
+	similar.Description = "A lighthouse keeper and a whale ride out a storm at sea"
+	for _, b := range []Book{book, similar} {
+		_, err := testClient.AddBook(context.Background(), b)
+		require.NoError(t, err)
+		defer testClient.DeleteBook(context.Background(), b.ID)
+	}
+
+	time.Sleep(1 * time.Second)
+
+	res, err := testClient.SimilarBooks(context.Background(), book.ID, SimilarFilter{}, Page{Size: 100})
+	require.NoError(t, err)
+	checkResult(t, res, similar)
+	for _, hit := range res.Hits.Hits {
+		require.NotEqual(t, book.ID, *hit.Id_, "the book is not similar to itself")
+	}
+
+	_, err = testClient.SimilarBooks(context.Background(), "does-not-exist", SimilarFilter{}, Page{})
+	require.ErrorIs(t, err, ErrBookNotFound)
+}
@@ -0,0 +1,62 @@
+package es
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go-elastic-api/logging"
+)
+
+// SlowLog is an Observer that logs calls for which Elasticsearch reported
+// a took time at or above a threshold.
+type SlowLog struct {
+	logger    *slog.Logger
+	threshold time.Duration
+}
+
+// NewSlowLog creates a slow query Observer. A zero threshold disables it.
+func NewSlowLog(logger *slog.Logger, threshold time.Duration) *SlowLog {
+	return &SlowLog{
+		logger:    logger,
+		threshold: threshold,
+	}
+}
+
+func (s *SlowLog) Begin(ctx context.Context, call Call) (context.Context, func(Outcome)) {
+	start := time.Now()
+	return ctx, func(out Outcome) {
+		if s.threshold <= 0 || out.Err != nil || out.Took < s.threshold {
+			return
+		}
+		s.logger.WarnContext(ctx, "slow elasticsearch query",
+			slog.String("method", call.Method),
+			slog.String("index", call.Index),
+			slog.String("query_type", call.QueryType),
+			slog.Duration("took", out.Took),
+			slog.Duration("duration", time.Since(start)),
+			slog.Int64("hits", out.Hits),
+			slog.Duration("threshold", s.threshold),
+		)
+	}
+}
+
+// requestIDTransport sends the request ID of the request context as X-Opaque-Id,
+// which Elasticsearch records in its slow logs, tasks and deprecation logs.
+type requestIDTransport struct {
+	next http.RoundTripper
+}
+
+// RequestIDTransport wraps next so that every request carries the request ID of its context.
+func RequestIDTransport(next http.RoundTripper) http.RoundTripper {
+	return &requestIDTransport{next: next}
+}
+
+func (t *requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if id := logging.RequestID(req.Context()); id != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("X-Opaque-Id", id)
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,39 @@
+package es
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go-elastic-api/logging"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "info", "json")
+	require.NoError(t, err)
+
+	fake := &fakeClient{res: newSearchResponse(10, 1)}
+	client := Observe(fake, NewSlowLog(logger, 100*time.Millisecond))
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+
+	// Below the threshold nothing is logged
+	_, err = client.FullTextSearch(ctx, "name:dune", Page{})
+	require.NoError(t, err)
+	require.Zero(t, buf.Len())
+
+	// At or above the threshold the call is logged with the request ID
+	fake.res = newSearchResponse(250, 1)
+	_, err = client.FullTextSearch(ctx, "name:dune", Page{})
+	require.NoError(t, err)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "slow elasticsearch query", record["msg"])
+	require.Equal(t, "FullTextSearch", record["method"])
+	require.Equal(t, "req-1", record["request_id"])
+}
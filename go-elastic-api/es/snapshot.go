@@ -0,0 +1,170 @@
+package es
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	snapshotcreate "github.com/elastic/go-elasticsearch/v8/typedapi/snapshot/create"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/snapshot/createrepository"
+	snapshotdelete "github.com/elastic/go-elasticsearch/v8/typedapi/snapshot/delete"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/snapshot/restore"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+)
+
+// Snapshot states reported by Elasticsearch
+const (
+	SnapshotInProgress = "IN_PROGRESS"
+	SnapshotSuccess    = "SUCCESS"
+	SnapshotPartial    = "PARTIAL"
+	SnapshotFailed     = "FAILED"
+)
+
+// snapshotTimeLayout is the time part of snapshot names; snapshot names must be lowercase.
+const snapshotTimeLayout = "2006.01.02-15.04.05"
+
+// RegisterSnapshotRepository registers a shared file system repository at location,
+// which must be listed in the path.repo setting of every node.
+// Registering a repository again with the same location is harmless.
+func (es *ESClient) RegisterSnapshotRepository(ctx context.Context, repository, location string) (*createrepository.Response, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	compress := true
+	var req createrepository.Request = types.SharedFileSystemRepository{
+		Type:     "fs",
+		Settings: types.SharedFileSystemRepositorySettings{Location: location, Compress: &compress},
+	}
+	return es.client.Snapshot.CreateRepository(repository).Request(&req).Do(ctx)
+}
+
+// CreateSnapshot starts a snapshot of indices, without the cluster state, and returns without waiting;
+// follow it with Snapshots until its state is no longer IN_PROGRESS.
+// An alias among indices stands for the indices it points to.
+func (es *ESClient) CreateSnapshot(ctx context.Context, repository, snapshot string, indices []string) (*snapshotcreate.Response, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	return es.client.Snapshot.Create(repository, snapshot).
+		Indices(strings.Join(indices, ",")).
+		IncludeGlobalState(false).
+		WaitForCompletion(false).
+		Do(ctx)
+}
+
+// Snapshots returns the snapshots of repository, the ones named by names or all of them,
+// oldest first.
+func (es *ESClient) Snapshots(ctx context.Context, repository string, names ...string) ([]types.SnapshotInfo, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	if len(names) == 0 {
+		names = []string{"_all"}
+	}
+	res, err := es.client.Snapshot.Get(repository, strings.Join(names, ",")).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := res.Snapshots
+	slices.SortFunc(snapshots, func(a, b types.SnapshotInfo) int {
+		return snapshotStart(a).Compare(snapshotStart(b))
+	})
+	return snapshots, nil
+}
+
+// DeleteSnapshot deletes a snapshot. The files it shares with other snapshots are kept.
+func (es *ESClient) DeleteSnapshot(ctx context.Context, repository, snapshot string) (*snapshotdelete.Response, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	return es.client.Snapshot.Delete(repository, snapshot).Do(ctx)
+}
+
+// RestoreSnapshot starts restoring index from a snapshot as target, a new index, and returns without
+// waiting; target is usable once its health is no longer red. Aliases and the cluster state are not
+// restored, so the restored index serves nothing until an alias is pointed to it with SwapAlias.
+func (es *ESClient) RestoreSnapshot(ctx context.Context, repository, snapshot, index, target string) (*restore.Response, error) {
+	ctx, cancel := es.withTimeout(ctx)
+	defer cancel()
+
+	return es.client.Snapshot.Restore(repository, snapshot).
+		Indices(index).
+		RenamePattern("^" + regexp.QuoteMeta(index) + "$").
+		RenameReplacement(target).
+		IncludeAliases(false).
+		IncludeGlobalState(false).
+		WaitForCompletion(false).
+		Do(ctx)
+}
+
+// SnapshotName returns the name of a snapshot taken at t, such as books-2026.10.19-02.00.00.
+// Names sort by time, and their prefix tells which snapshots retention applies to.
+// The prefix is lowercased, Elasticsearch rejects snapshot names with uppercase letters.
+func SnapshotName(prefix string, t time.Time) string {
+	return strings.ToLower(prefix) + "-" + t.UTC().Format(snapshotTimeLayout)
+}
+
+// isSnapshotName reports whether name was made by SnapshotName with prefix.
+func isSnapshotName(name, prefix string) bool {
+	t, ok := strings.CutPrefix(name, strings.ToLower(prefix)+"-")
+	if !ok {
+		return false
+	}
+	_, err := time.Parse(snapshotTimeLayout, t)
+	return err == nil
+}
+
+// SnapshotRetention tells which snapshots to keep. Zero values disable a rule.
+type SnapshotRetention struct {
+	// MaxCount is the number of most recent snapshots kept
+	MaxCount int
+	// MaxAge is how long snapshots are kept
+	MaxAge time.Duration
+}
+
+// SnapshotsToPrune returns the names of the snapshots named by SnapshotName with prefix that retention
+// does not keep; other snapshots of the repository are never pruned.
+// The most recent successful snapshot is always kept, so that pruning never leaves nothing to restore,
+// and snapshots in progress are left alone.
+func SnapshotsToPrune(snapshots []types.SnapshotInfo, prefix string, retention SnapshotRetention, now time.Time) []string {
+	var candidates []types.SnapshotInfo
+	for _, s := range snapshots {
+		if isSnapshotName(s.Snapshot, prefix) && state(s) != SnapshotInProgress {
+			candidates = append(candidates, s)
+		}
+	}
+	// Most recent first
+	slices.SortFunc(candidates, func(a, b types.SnapshotInfo) int {
+		return snapshotStart(b).Compare(snapshotStart(a))
+	})
+
+	lastSuccess := slices.IndexFunc(candidates, func(s types.SnapshotInfo) bool { return state(s) == SnapshotSuccess })
+	var prune []string
+	for i, s := range candidates {
+		if i == lastSuccess {
+			continue
+		}
+		tooMany := retention.MaxCount > 0 && i >= retention.MaxCount
+		tooOld := retention.MaxAge > 0 && now.Sub(snapshotStart(s)) > retention.MaxAge
+		if tooMany || tooOld {
+			prune = append(prune, s.Snapshot)
+		}
+	}
+	return prune
+}
+
+func state(s types.SnapshotInfo) string {
+	if s.State == nil {
+		return ""
+	}
+	return *s.State
+}
+
+func snapshotStart(s types.SnapshotInfo) time.Time {
+	if s.StartTimeInMillis == nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(*s.StartTimeInMillis)
+}
@@ -0,0 +1,122 @@
+package es
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go-elastic-api/util"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotName(t *testing.T) {
+	at := time.Date(2026, time.October, 19, 2, 30, 0, 0, time.FixedZone("ICT", 7*3600))
+	require.Equal(t, "books-2026.10.18-19.30.00", SnapshotName("books", at))
+	require.True(t, isSnapshotName("books-2026.10.18-19.30.00", "books"))
+	require.False(t, isSnapshotName("books-v2-2026.10.18-19.30.00", "books"))
+	require.False(t, isSnapshotName("books-before-migration", "books"))
+
+	require.Equal(t, "books-2026.10.18-19.30.00", SnapshotName("Books", at))
+	require.True(t, isSnapshotName("books-2026.10.18-19.30.00", "Books"))
+}
+
+func TestSnapshotsToPrune(t *testing.T) {
+	now := time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+	snapshot := func(daysAgo int, state string) types.SnapshotInfo {
+		start := now.Add(-time.Duration(daysAgo) * 24 * time.Hour)
+		millis := start.UnixMilli()
+		return types.SnapshotInfo{Snapshot: SnapshotName("books", start), State: &state, StartTimeInMillis: &millis}
+	}
+	name := func(daysAgo int) string {
+		return SnapshotName("books", now.Add(-time.Duration(daysAgo)*24*time.Hour))
+	}
+	manual := namedSnapshot("books-before-migration", 90)
+
+	snapshots := []types.SnapshotInfo{
+		snapshot(0, SnapshotInProgress),
+		snapshot(1, SnapshotFailed),
+		snapshot(2, SnapshotSuccess),
+		snapshot(3, SnapshotPartial),
+		snapshot(4, SnapshotSuccess),
+		snapshot(10, SnapshotSuccess),
+		snapshot(40, SnapshotSuccess),
+		manual,
+	}
+
+	testCases := []struct {
+		name      string
+		retention SnapshotRetention
+		want      []string
+	}{
+		{name: "KeepAll", retention: SnapshotRetention{}},
+		// The snapshot in progress is not counted
+		{name: "MaxCount", retention: SnapshotRetention{MaxCount: 3}, want: []string{name(4), name(10), name(40)}},
+		{name: "MaxAge", retention: SnapshotRetention{MaxAge: 7 * 24 * time.Hour}, want: []string{name(10), name(40)}},
+		{name: "Both", retention: SnapshotRetention{MaxCount: 5, MaxAge: 30 * 24 * time.Hour}, want: []string{name(40)}},
+		// The most recent successful snapshot is kept whatever the retention
+		{name: "KeepLastSuccess", retention: SnapshotRetention{MaxCount: 1}, want: []string{name(3), name(4), name(10), name(40)}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, SnapshotsToPrune(snapshots, "books", tc.retention, now))
+		})
+	}
+
+	// A failed snapshot never counts as the one to keep
+	onlyOld := []types.SnapshotInfo{snapshot(1, SnapshotFailed), snapshot(60, SnapshotSuccess)}
+	require.Equal(t, []string{name(1)}, SnapshotsToPrune(onlyOld, "books", SnapshotRetention{MaxAge: time.Hour}, now))
+}
+
+// namedSnapshot returns a successful snapshot with a name not made by SnapshotName.
+func namedSnapshot(name string, daysAgo int) types.SnapshotInfo {
+	state := SnapshotSuccess
+	millis := time.Now().Add(-time.Duration(daysAgo) * 24 * time.Hour).UnixMilli()
+	return types.SnapshotInfo{Snapshot: name, State: &state, StartTimeInMillis: &millis}
+}
+
+// TestSnapshotRestore needs path.repo to list SNAPSHOT_REPOSITORY_PATH, as in docker-compose.yml.
+func TestSnapshotRestore(t *testing.T) {
+	ctx := context.Background()
+	cfg, err := util.LoadConfig("..")
+	require.NoError(t, err)
+	repository := cfg.SnapshotRepository
+
+	_, err = testClient.RegisterSnapshotRepository(ctx, repository, cfg.SnapshotRepositoryPath)
+	require.NoError(t, err)
+
+	book := createRandomBook()
+	_, err = testClient.AddBook(ctx, book)
+	require.NoError(t, err)
+	defer testClient.DeleteBook(ctx, book.ID)
+	time.Sleep(1 * time.Second)
+
+	name := SnapshotName("test-"+strings.ToLower(util.RandomString(8)), time.Now())
+	_, err = testClient.CreateSnapshot(ctx, repository, name, []string{testClient.Index()})
+	require.NoError(t, err)
+	defer testClient.DeleteSnapshot(ctx, repository, name)
+
+	var snapshot types.SnapshotInfo
+	require.Eventually(t, func() bool {
+		snapshots, err := testClient.Snapshots(ctx, repository, name)
+		require.NoError(t, err)
+		require.Len(t, snapshots, 1)
+		snapshot = snapshots[0]
+		return *snapshot.State != SnapshotInProgress
+	}, time.Minute, time.Second)
+	require.Equal(t, SnapshotSuccess, *snapshot.State)
+	require.Len(t, snapshot.Indices, 1)
+
+	target := "restored-" + strings.ToLower(util.RandomString(8))
+	_, err = testClient.RestoreSnapshot(ctx, repository, name, snapshot.Indices[0], target)
+	require.NoError(t, err)
+	defer testClient.DeleteIndex(ctx, target)
+
+	restored := NewClient(testClient.(*ESClient).client, WithIndex(target))
+	require.Eventually(t, func() bool {
+		res, err := restored.GetBook(ctx, book.ID)
+		return err == nil && len(res.Hits.Hits) == 1
+	}, time.Minute, time.Second)
+}
@@ -0,0 +1,84 @@
+package es
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "go-elastic-api/es"
+
+// Tracing is an Observer that starts a client span for every Client call.
+// Spans are children of the span found in the call context, usually the HTTP request span.
+type Tracing struct {
+	tracer trace.Tracer
+}
+
+// NewTracing creates a tracing Observer using tp.
+func NewTracing(tp trace.TracerProvider) *Tracing {
+	return &Tracing{
+		tracer: tp.Tracer(tracerName),
+	}
+}
+
+func (t *Tracing) Begin(ctx context.Context, call Call) (context.Context, func(Outcome)) {
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", "elasticsearch"),
+		attribute.String("db.operation", call.Method),
+	}
+	if call.Index != "" {
+		attrs = append(attrs, attribute.String("elasticsearch.index", call.Index))
+	}
+	if call.QueryType != "" {
+		attrs = append(attrs, attribute.String("elasticsearch.query_type", call.QueryType))
+	}
+
+	ctx, span := t.tracer.Start(ctx, "es."+call.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx, func(out Outcome) {
+		defer span.End()
+
+		if out.Err != nil {
+			span.RecordError(out.Err)
+			span.SetStatus(codes.Error, ErrorType(out.Err))
+			return
+		}
+		if out.Took > 0 {
+			span.SetAttributes(attribute.Int64("elasticsearch.took_ms", out.Took.Milliseconds()))
+		}
+		if call.QueryType != "" {
+			span.SetAttributes(attribute.Int64("elasticsearch.hits", out.Hits))
+		}
+		if out.Docs > 0 {
+			span.SetAttributes(
+				attribute.Int("elasticsearch.bulk.docs", out.Docs),
+				attribute.Int("elasticsearch.bulk.failed", out.Failed),
+			)
+		}
+	}
+}
+
+// traceContextTransport injects the W3C traceparent of the request context into
+// outgoing Elasticsearch requests, so the cluster can correlate its own traces and slow logs.
+type traceContextTransport struct {
+	next http.RoundTripper
+}
+
+// TraceContextTransport wraps next so that every request carries the trace context
+// of its context through the globally configured propagator.
+func TraceContextTransport(next http.RoundTripper) http.RoundTripper {
+	return &traceContextTransport{next: next}
+}
+
+func (t *traceContextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	otel.GetTextMapPropagator().Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+	return t.next.RoundTrip(req)
+}
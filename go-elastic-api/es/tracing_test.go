@@ -0,0 +1,93 @@
+package es
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestTracerProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	return tp, exporter
+}
+
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTracingObserver(t *testing.T) {
+	tp, exporter := newTestTracerProvider()
+	client := Observe(&fakeClient{res: newSearchResponse(7, 42)}, NewTracing(tp))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "GET /search/full_text_search")
+	_, err := client.FullTextSearch(ctx, "name:dune", Page{})
+	require.NoError(t, err)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+
+	span := spans[0]
+	require.Equal(t, "es.FullTextSearch", span.Name)
+	require.Equal(t, trace.SpanKindClient, span.SpanKind)
+	require.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+
+	attrs := spanAttributes(span)
+	require.Equal(t, "elasticsearch", attrs["db.system"].AsString())
+	require.Equal(t, DefaultBooksIndex, attrs["elasticsearch.index"].AsString())
+	require.Equal(t, "query_string", attrs["elasticsearch.query_type"].AsString())
+	require.Equal(t, int64(42), attrs["elasticsearch.hits"].AsInt64())
+	require.Equal(t, int64(7), attrs["elasticsearch.took_ms"].AsInt64())
+}
+
+func TestTracingObserverRecordsErrors(t *testing.T) {
+	tp, exporter := newTestTracerProvider()
+	client := Observe(&fakeClient{err: context.DeadlineExceeded}, NewTracing(tp))
+
+	_, err := client.FullTextSearch(context.Background(), "name:dune", Page{})
+	require.Error(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, codes.Error, spans[0].Status.Code)
+	require.Equal(t, "timeout", spans[0].Status.Description)
+}
+
+func TestTraceContextTransport(t *testing.T) {
+	prev := otel.GetTextMapPropagator()
+	t.Cleanup(func() { otel.SetTextMapPropagator(prev) })
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	tp, _ := newTestTracerProvider()
+
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer srv.Close()
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "parent")
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	res, err := TraceContextTransport(http.DefaultTransport).RoundTrip(req)
+	require.NoError(t, err)
+	res.Body.Close()
+
+	require.Contains(t, traceparent, span.SpanContext().TraceID().String())
+}
@@ -0,0 +1,213 @@
+// Package exporter writes books as CSV or NDJSON, with a choice of columns,
+// one book at a time so that exports of any size stream without being held in memory.
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go-elastic-api/es"
+)
+
+// Format is the format of an export.
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+// Formats lists the supported formats.
+var Formats = []Format{FormatCSV, FormatNDJSON}
+
+// ContentType returns the media type of format.
+func (f Format) ContentType() string {
+	if f == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// Columns lists the fields of a book by their JSON name, in the order of es.Book.
+var Columns = []string{
+	"id", "name", "author", "edition", "publisher", "release_date", "language", "description",
+	"page_count", "content", "categories", "tags", "rating", "review_count",
+}
+
+// ListSeparator separates the values of categories and tags in CSV cells,
+// the separator the importer splits them on.
+const ListSeparator = ";"
+
+// DefaultColumns returns the columns exported when none are chosen: every field in NDJSON,
+// which import reads back, and every field but the content in CSV, as spreadsheets
+// do not take cells of a whole book.
+func DefaultColumns(format Format) []string {
+	if format == FormatCSV {
+		return slices.DeleteFunc(slices.Clone(Columns), func(c string) bool { return c == "content" })
+	}
+	return Columns
+}
+
+// ParseColumns parses a comma separated list of columns, e.g. "id,name,author".
+// An empty list returns the default columns of format.
+func ParseColumns(s string, format Format) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return DefaultColumns(format), nil
+	}
+	var columns []string
+	for _, column := range strings.Split(s, ",") {
+		column = strings.TrimSpace(column)
+		if !slices.Contains(Columns, column) {
+			return nil, fmt.Errorf("unknown column %q, expected some of %v", column, Columns)
+		}
+		if slices.Contains(columns, column) {
+			return nil, fmt.Errorf("column %q is listed twice", column)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// Writer writes books one at a time. Flush must be called after the last one.
+type Writer interface {
+	Write(book es.Book) error
+	Flush() error
+}
+
+// NewWriter returns the Writer of format, writing the given columns to w.
+// The CSV header row is written right away, so an export without books still has it.
+func NewWriter(format Format, w io.Writer, columns []string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatNDJSON:
+		return newNDJSONWriter(w, columns), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q, expected one of %v", format, Formats)
+	}
+}
+
+type csvWriter struct {
+	w       *csv.Writer
+	columns []string
+	row     []string
+}
+
+func newCSVWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w), columns: columns, row: make([]string, len(columns))}
+	if err := cw.w.Write(columns); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) Write(book es.Book) error {
+	for i, column := range cw.columns {
+		cw.row[i] = escapeFormula(cell(book, column))
+	}
+	return cw.w.Write(cw.row)
+}
+
+// formulaPrefixes start the cells spreadsheets evaluate as formulas.
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeFormula prefixes a cell spreadsheets would evaluate as a formula with ', which they show as text.
+// The importer removes the prefix again.
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune(formulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func (cw *csvWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// cell returns the value of column in a CSV row. Like in JSON, a zero rating
+// and review count are left empty as they mean the book has no reviews.
+func cell(book es.Book, column string) string {
+	switch column {
+	case "id":
+		return book.ID
+	case "name":
+		return book.Name
+	case "author":
+		return book.Author
+	case "edition":
+		return book.Edition
+	case "publisher":
+		return book.Publisher
+	case "release_date":
+		return book.ReleaseDate
+	case "language":
+		return book.Language
+	case "description":
+		return book.Description
+	case "page_count":
+		return strconv.Itoa(book.PageCount)
+	case "content":
+		return book.Content
+	case "categories":
+		return strings.Join(book.Categories, ListSeparator)
+	case "tags":
+		return strings.Join(book.Tags, ListSeparator)
+	case "rating":
+		if book.Rating == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(float64(book.Rating), 'f', -1, 32)
+	case "review_count":
+		if book.ReviewCount == 0 {
+			return ""
+		}
+		return strconv.Itoa(book.ReviewCount)
+	default:
+		return ""
+	}
+}
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+	// columns is nil when every column is written
+	columns []string
+}
+
+func newNDJSONWriter(w io.Writer, columns []string) *ndjsonWriter {
+	nw := &ndjsonWriter{enc: json.NewEncoder(w)}
+	if !slices.Equal(columns, Columns) {
+		nw.columns = columns
+	}
+	return nw
+}
+
+func (nw *ndjsonWriter) Write(book es.Book) error {
+	if nw.columns == nil {
+		return nw.enc.Encode(book)
+	}
+	b, err := json.Marshal(book)
+	if err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	for field := range fields {
+		if !slices.Contains(nw.columns, field) {
+			delete(fields, field)
+		}
+	}
+	return nw.enc.Encode(fields)
+}
+
+// Flush does nothing, every book is written to the underlying writer as it comes.
+func (nw *ndjsonWriter) Flush() error {
+	return nil
+}
@@ -0,0 +1,93 @@
+package exporter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go-elastic-api/es"
+
+	"github.com/stretchr/testify/require"
+)
+
+var books = []es.Book{
+	{
+		ID: "9780553380958", Name: "Snow Crash", Author: "Neal Stephenson", Publisher: "Bantam",
+		ReleaseDate: "1992-06-01", Language: "en", PageCount: 480, Content: "The Deliverator belongs to an elite order",
+		Categories: []string{"Science fiction", "Cyberpunk"}, Rating: 4.5, ReviewCount: 12,
+	},
+	{ID: "9780441013593", Name: "Dune, 40th anniversary", Author: `Frank "Frank" Herbert`, ReleaseDate: "2005-08-02"},
+}
+
+func write(t *testing.T, format Format, columns []string) string {
+	t.Helper()
+	var b bytes.Buffer
+	w, err := NewWriter(format, &b, columns)
+	require.NoError(t, err)
+	for _, book := range books {
+		require.NoError(t, w.Write(book))
+	}
+	require.NoError(t, w.Flush())
+	return b.String()
+}
+
+func TestCSVWriter(t *testing.T) {
+	require.Equal(t, `id,name,author,edition,publisher,release_date,language,description,page_count,categories,tags,rating,review_count
+9780553380958,Snow Crash,Neal Stephenson,,Bantam,1992-06-01,en,,480,Science fiction;Cyberpunk,,4.5,12
+9780441013593,"Dune, 40th anniversary","Frank ""Frank"" Herbert",,,2005-08-02,,,0,,,,
+`, write(t, FormatCSV, DefaultColumns(FormatCSV)))
+
+	require.Equal(t, "name,id\nSnow Crash,9780553380958\n\"Dune, 40th anniversary\",9780441013593\n",
+		write(t, FormatCSV, []string{"name", "id"}))
+
+	// Cells spreadsheets would evaluate as formulas are written as text
+	var escaped bytes.Buffer
+	w, err := NewWriter(FormatCSV, &escaped, []string{"name", "author", "publisher", "description"})
+	require.NoError(t, err)
+	require.NoError(t, w.Write(es.Book{Name: `=HYPERLINK("http://evil")`, Author: "+1", Publisher: "@Ace", Description: "-2"}))
+	require.NoError(t, w.Flush())
+	require.Equal(t, `name,author,publisher,description
+"'=HYPERLINK(""http://evil"")",'+1,'@Ace,'-2
+`, escaped.String())
+
+	// The header is written without books
+	var b bytes.Buffer
+	w, err = NewWriter(FormatCSV, &b, []string{"id"})
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+	require.Equal(t, "id\n", b.String())
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(write(t, FormatNDJSON, DefaultColumns(FormatNDJSON))), "\n")
+	require.Len(t, lines, 2)
+	require.JSONEq(t, `{"id": "9780553380958", "name": "Snow Crash", "author": "Neal Stephenson", "edition": "",
+		"publisher": "Bantam", "release_date": "1992-06-01", "language": "en", "page_count": 480,
+		"content": "The Deliverator belongs to an elite order", "categories": ["Science fiction", "Cyberpunk"],
+		"rating": 4.5, "review_count": 12}`, lines[0])
+
+	lines = strings.Split(strings.TrimSpace(write(t, FormatNDJSON, []string{"id", "categories", "rating"})), "\n")
+	require.JSONEq(t, `{"id": "9780553380958", "categories": ["Science fiction", "Cyberpunk"], "rating": 4.5}`, lines[0])
+	require.JSONEq(t, `{"id": "9780441013593"}`, lines[1], "empty fields are left out as in the API")
+}
+
+func TestParseColumns(t *testing.T) {
+	columns, err := ParseColumns("", FormatCSV)
+	require.NoError(t, err)
+	require.NotContains(t, columns, "content")
+	columns, err = ParseColumns("", FormatNDJSON)
+	require.NoError(t, err)
+	require.Equal(t, Columns, columns)
+
+	columns, err = ParseColumns(" name, author ,content", FormatCSV)
+	require.NoError(t, err)
+	require.Equal(t, []string{"name", "author", "content"}, columns)
+
+	for _, invalid := range []string{"name,price", "name,,id", "id,id"} {
+		_, err := ParseColumns(invalid, FormatCSV)
+		require.Error(t, err, invalid)
+	}
+
+	_, err = NewWriter("xlsx", &bytes.Buffer{}, Columns)
+	require.Error(t, err)
+}
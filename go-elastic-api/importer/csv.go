@@ -0,0 +1,61 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvReader reads a CSV file with a header row; the header names the fields of every record.
+type csvReader struct {
+	r      *csv.Reader
+	header []string
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read CSV header: %w", err)
+	}
+	// The header is reused by the next Read, copy it. Excel adds a byte order mark.
+	names := make([]string, len(header))
+	for i, name := range header {
+		names[i] = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+	}
+	return &csvReader{r: cr, header: names}, nil
+}
+
+func (c *csvReader) Read() (Record, error) {
+	row, err := c.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(err, csv.ErrFieldCount) {
+			return Record{}, &RecordError{Number: parseErr.StartLine, Err: fmt.Errorf("expected %d columns, got %d", len(c.header), len(row))}
+		}
+		return Record{}, err
+	}
+
+	line, _ := c.r.FieldPos(0)
+	record := Record{Number: line, Fields: make(map[string][]string, len(row))}
+	for i, value := range row {
+		record.add(c.header[i], unescapeFormula(value))
+	}
+	return record, nil
+}
+
+// formulaPrefixes start the cells spreadsheets evaluate as formulas, as escaped by the exporter.
+const formulaPrefixes = "=+-@\t\r"
+
+// unescapeFormula removes the ' that export writes before cells spreadsheets would evaluate as formulas.
+// Files do not tell whether they were exported, so a value starting with ' and one of formulaPrefixes
+// loses its ' even when it was part of the data: the round trip of such values is lossy.
+func unescapeFormula(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
@@ -0,0 +1,186 @@
+// Package importer loads books from catalog files into Elasticsearch: CSV, JSON Lines,
+// ONIX for Books and MARCXML. Records are mapped to books, normalized and checked,
+// and the ones that cannot be imported are reported with the reason instead of stopping the import.
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"go-elastic-api/es"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/bulk"
+)
+
+// BookWriter indexes books in bulk, as es.Client does.
+type BookWriter interface {
+	BulkAddBooks(ctx context.Context, books []es.Book) (*bulk.Response, error)
+}
+
+// Importer maps the records of a Reader to books and indexes them in batches.
+type Importer struct {
+	store     BookWriter
+	mapping   Mapping
+	batchSize int
+	rejects   *json.Encoder
+}
+
+// Option configures an Importer.
+type Option func(*Importer)
+
+// WithBatchSize sets the number of books per bulk request, 500 by default.
+func WithBatchSize(n int) Option {
+	return func(im *Importer) {
+		im.batchSize = n
+	}
+}
+
+// WithRejects writes the rejected records to w as JSON lines, with the reason they were rejected.
+// Without it, rejected records are logged.
+func WithRejects(w io.Writer) Option {
+	return func(im *Importer) {
+		im.rejects = json.NewEncoder(w)
+	}
+}
+
+// New creates an Importer that indexes books with store.
+func New(store BookWriter, mapping Mapping, opts ...Option) (*Importer, error) {
+	im := &Importer{store: store, mapping: mapping, batchSize: 500}
+	for _, opt := range opts {
+		opt(im)
+	}
+	if im.batchSize < 1 {
+		return nil, fmt.Errorf("batch size must be positive, got %d", im.batchSize)
+	}
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+	return im, nil
+}
+
+// Stats counts the records of an import.
+type Stats struct {
+	Read     int `json:"read"`
+	Indexed  int `json:"indexed"`
+	Rejected int `json:"rejected"`
+}
+
+// Reject is a record that was not imported, as written to the rejects file.
+type Reject struct {
+	Record int                 `json:"record"`
+	Reason string              `json:"reason"`
+	Fields map[string][]string `json:"fields,omitempty"`
+}
+
+// Run imports the records of r until its end. Records that cannot be imported are rejected
+// and the import goes on; it stops on errors of the reader, of a bulk request or of ctx.
+// Books mapped from the records read before such an error are indexed when possible.
+func (im *Importer) Run(ctx context.Context, r Reader) (Stats, error) {
+	var stats Stats
+	// seen maps the ISBN, or the ID of books without one, to the record it was first seen in
+	seen := make(map[string]int)
+	books := make([]es.Book, 0, im.batchSize)
+	records := make([]Record, 0, im.batchSize)
+
+	flush := func() error {
+		if len(books) == 0 {
+			return nil
+		}
+		err := im.index(ctx, books, records, &stats)
+		books, records = books[:0], records[:0]
+		return err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		record, err := r.Read()
+		if err == io.EOF {
+			return stats, flush()
+		}
+		var recordErr *RecordError
+		if errors.As(err, &recordErr) {
+			stats.Read++
+			if err := im.reject(ctx, &stats, Reject{Record: recordErr.Number, Reason: recordErr.Err.Error()}); err != nil {
+				return stats, err
+			}
+			continue
+		}
+		if err != nil {
+			return stats, errors.Join(err, flush())
+		}
+		stats.Read++
+
+		book, isbn, err := im.mapping.book(record)
+		if err == nil {
+			key := isbn
+			if key == "" {
+				key = "id:" + book.ID
+			}
+			if first, ok := seen[key]; ok {
+				err = fmt.Errorf("duplicate of record %d", first)
+			} else {
+				seen[key] = record.Number
+			}
+		}
+		if err != nil {
+			if err := im.reject(ctx, &stats, Reject{Record: record.Number, Reason: err.Error(), Fields: record.Fields}); err != nil {
+				return stats, err
+			}
+			continue
+		}
+
+		books = append(books, book)
+		records = append(records, record)
+		if len(books) == im.batchSize {
+			if err := flush(); err != nil {
+				return stats, err
+			}
+		}
+	}
+}
+
+// index sends one bulk request and rejects the books Elasticsearch refused.
+func (im *Importer) index(ctx context.Context, books []es.Book, records []Record, stats *Stats) error {
+	res, err := im.store.BulkAddBooks(ctx, books)
+	if err != nil {
+		return fmt.Errorf("bulk request failed: %w", err)
+	}
+	for i, item := range res.Items {
+		for _, result := range item {
+			if result.Error == nil {
+				stats.Indexed++
+				continue
+			}
+			reason := result.Error.Type
+			if result.Error.Reason != nil {
+				reason += ": " + *result.Error.Reason
+			}
+			reject := Reject{Reason: reason}
+			if i < len(records) {
+				reject.Record, reject.Fields = records[i].Number, records[i].Fields
+			}
+			if err := im.reject(ctx, stats, reject); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (im *Importer) reject(ctx context.Context, stats *Stats, reject Reject) error {
+	stats.Rejected++
+	if im.rejects == nil {
+		slog.WarnContext(ctx, "record rejected", slog.Int("record", reject.Record), slog.String("reason", reject.Reason))
+		return nil
+	}
+	if err := im.rejects.Encode(reject); err != nil {
+		return fmt.Errorf("cannot write rejected record: %w", err)
+	}
+	return nil
+}
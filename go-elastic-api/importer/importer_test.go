@@ -0,0 +1,244 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"go-elastic-api/es"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/bulk"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/operationtype"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWriter records the bulk requests and refuses the books in refuse.
+type fakeWriter struct {
+	batches [][]es.Book
+	refuse  map[string]string
+	err     error
+}
+
+func (w *fakeWriter) BulkAddBooks(_ context.Context, books []es.Book) (*bulk.Response, error) {
+	if w.err != nil {
+		return nil, w.err
+	}
+	w.batches = append(w.batches, append([]es.Book(nil), books...))
+	res := &bulk.Response{}
+	for _, book := range books {
+		item := types.ResponseItem{Id_: &book.ID, Status: 201}
+		if reason, ok := w.refuse[book.ID]; ok {
+			item.Status = 400
+			item.Error = &types.ErrorCause{Type: "document_parsing_exception", Reason: &reason}
+		}
+		res.Items = append(res.Items, map[operationtype.OperationType]types.ResponseItem{operationtype.Index: item})
+	}
+	return res, nil
+}
+
+func (w *fakeWriter) books() map[string]es.Book {
+	books := make(map[string]es.Book)
+	for _, batch := range w.batches {
+		for _, book := range batch {
+			books[book.ID] = book
+		}
+	}
+	return books
+}
+
+func readRejects(t *testing.T, b *bytes.Buffer) []Reject {
+	t.Helper()
+	var rejects []Reject
+	scanner := bufio.NewScanner(b)
+	for scanner.Scan() {
+		var reject Reject
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &reject))
+		rejects = append(rejects, reject)
+	}
+	return rejects
+}
+
+func TestImportCSV(t *testing.T) {
+	reader, err := NewReader(FormatCSV, openTestdata(t, "books.csv"))
+	require.NoError(t, err)
+	writer := &fakeWriter{}
+	var rejects bytes.Buffer
+	im, err := New(writer, DefaultMapping(FormatCSV), WithBatchSize(1), WithRejects(&rejects))
+	require.NoError(t, err)
+
+	stats, err := im.Run(context.Background(), reader)
+	require.NoError(t, err)
+	require.Equal(t, Stats{Read: 6, Indexed: 2, Rejected: 4}, stats)
+	require.Len(t, writer.batches, 2)
+
+	// ISBNs become the ID, as ISBN-13
+	books := writer.books()
+	require.Equal(t, es.Book{
+		ID:          "9780553380958",
+		Name:        "Snow Crash",
+		Author:      "Neal Stephenson",
+		Publisher:   "Bantam",
+		ReleaseDate: "1992-06-01",
+		PageCount:   480,
+		Categories:  []string{"Science fiction", "Cyberpunk"},
+	}, books["9780553380958"])
+	require.Equal(t, "1984-07-01", books["9780441569595"].ReleaseDate)
+	require.Equal(t, 271, books["9780441569595"].PageCount)
+
+	got := readRejects(t, &rejects)
+	require.Len(t, got, 4)
+	require.Equal(t, 4, got[0].Record)
+	require.Equal(t, "duplicate of record 2", got[0].Reason)
+	require.Equal(t, []string{"Snow Crash"}, got[0].Fields["name"])
+	require.Equal(t, 5, got[1].Record)
+	require.Contains(t, got[1].Reason, "check digit")
+	require.Equal(t, 6, got[2].Record)
+	require.Equal(t, "expected 7 columns, got 6", got[2].Reason)
+	require.Equal(t, 7, got[3].Record)
+	require.Contains(t, got[3].Reason, "release_date")
+}
+
+func TestImportONIX(t *testing.T) {
+	writer := &fakeWriter{}
+	var rejects bytes.Buffer
+	for _, name := range []string{"books.onix.xml", "books.onix21.xml"} {
+		reader, err := NewReader(FormatONIX, openTestdata(t, name))
+		require.NoError(t, err)
+		im, err := New(writer, DefaultMapping(FormatONIX), WithRejects(&rejects))
+		require.NoError(t, err)
+		_, err = im.Run(context.Background(), reader)
+		require.NoError(t, err)
+	}
+
+	books := writer.books()
+	require.Equal(t, es.Book{
+		ID:          "9780441013593",
+		Name:        "Dune",
+		Author:      "Frank Herbert",
+		Edition:     "40",
+		Publisher:   "Ace",
+		ReleaseDate: "2005-08-02",
+		Language:    "en",
+		Description: "Set on the desert planet Arrakis, Dune is the story of Paul Atreides.",
+		PageCount:   617,
+		Categories:  []string{"Science fiction"},
+		Tags:        []string{"desert", "ecology"},
+	}, books["9780441013593"])
+	require.Equal(t, "George Orwell", books["9780141187761"].Author)
+	require.Equal(t, "2000-01-27", books["9780141187761"].ReleaseDate)
+
+	got := readRejects(t, &rejects)
+	require.Len(t, got, 1)
+	require.Equal(t, "id or isbn is required", got[0].Reason)
+}
+
+func TestImportMARCXML(t *testing.T) {
+	reader, err := NewReader(FormatMARCXML, openTestdata(t, "books.marcxml"))
+	require.NoError(t, err)
+	writer := &fakeWriter{}
+	var rejects bytes.Buffer
+	im, err := New(writer, DefaultMapping(FormatMARCXML), WithRejects(&rejects))
+	require.NoError(t, err)
+
+	stats, err := im.Run(context.Background(), reader)
+	require.NoError(t, err)
+	require.Equal(t, Stats{Read: 2, Indexed: 1, Rejected: 1}, stats)
+	require.Equal(t, es.Book{
+		ID:          "9780261103252",
+		Name:        "The lord of the rings",
+		Author:      "Tolkien, J. R. R.",
+		Edition:     "50th anniversary ed.",
+		Publisher:   "HarperCollins",
+		ReleaseDate: "2005-01-01",
+		PageCount:   1178,
+		Categories:  []string{"Fantasy fiction", "Middle Earth (Imaginary place)"},
+	}, writer.books()["9780261103252"])
+}
+
+func TestImportMapping(t *testing.T) {
+	var m Mapping
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"fields": {
+			"id": "Ref",
+			"name": "Title",
+			"author": ["Author", "Editor"],
+			"release_date": "Published",
+			"rating": "Stars",
+			"tags": {"from": ["Keywords", "Genre"], "split": "|"}
+		},
+		"date_layouts": ["02/01/2006"]
+	}`), &m))
+	require.NoError(t, m.Validate())
+
+	input := `Ref,Title,Author,Editor,Published,Stars,Keywords,Genre
+B1,Snow Crash,,Jane Doe,01/06/1992,4.5,cyberpunk|satire,sf
+B2,Dune,Frank Herbert,,1965,five,,
+B1,Snow Crash again,,,1992,,,
+`
+	reader, err := NewReader(FormatCSV, strings.NewReader(input))
+	require.NoError(t, err)
+	writer := &fakeWriter{refuse: map[string]string{"B3": "failed to parse"}}
+	var rejects bytes.Buffer
+	im, err := New(writer, m, WithRejects(&rejects))
+	require.NoError(t, err)
+
+	stats, err := im.Run(context.Background(), reader)
+	require.NoError(t, err)
+	require.Equal(t, Stats{Read: 3, Indexed: 1, Rejected: 2}, stats)
+	require.Equal(t, es.Book{
+		ID:          "B1",
+		Name:        "Snow Crash",
+		Author:      "Jane Doe",
+		ReleaseDate: "1992-06-01",
+		Rating:      4.5,
+		Tags:        []string{"cyberpunk", "satire", "sf"},
+	}, writer.books()["B1"])
+
+	got := readRejects(t, &rejects)
+	require.Equal(t, `rating: "five" is not a number`, got[0].Reason)
+	require.Equal(t, "duplicate of record 2", got[1].Reason)
+
+	for _, invalid := range []string{
+		`{"fields": {"id": "Ref"}}`,
+		`{"fields": {"name": "Title"}}`,
+		`{"fields": {"id": "Ref", "name": "Title", "price": "Price"}}`,
+	} {
+		var m Mapping
+		require.NoError(t, json.Unmarshal([]byte(invalid), &m))
+		require.Error(t, m.Validate(), invalid)
+	}
+	require.Error(t, json.Unmarshal([]byte(`{"fields": {"name": 1}}`), &m))
+}
+
+func TestImportErrors(t *testing.T) {
+	input := "{\"id\": \"1\", \"name\": \"Snow Crash\", \"release_date\": \"1992\"}\n" +
+		"{\"id\": \"2\", \"name\": \"Dune\", \"release_date\": \"1965\"}\n"
+
+	// Books refused by Elasticsearch are rejected with its reason
+	reader, _ := NewReader(FormatJSONL, strings.NewReader(input))
+	writer := &fakeWriter{refuse: map[string]string{"2": "failed to parse field [page_count]"}}
+	var rejects bytes.Buffer
+	im, err := New(writer, DefaultMapping(FormatJSONL), WithRejects(&rejects))
+	require.NoError(t, err)
+	stats, err := im.Run(context.Background(), reader)
+	require.NoError(t, err)
+	require.Equal(t, Stats{Read: 2, Indexed: 1, Rejected: 1}, stats)
+	got := readRejects(t, &rejects)
+	require.Equal(t, 2, got[0].Record)
+	require.Equal(t, "document_parsing_exception: failed to parse field [page_count]", got[0].Reason)
+
+	// A failed bulk request stops the import
+	reader, _ = NewReader(FormatJSONL, strings.NewReader(input))
+	im, err = New(&fakeWriter{err: errors.New("connection refused")}, DefaultMapping(FormatJSONL))
+	require.NoError(t, err)
+	_, err = im.Run(context.Background(), reader)
+	require.ErrorContains(t, err, "connection refused")
+
+	_, err = New(writer, DefaultMapping(FormatJSONL), WithBatchSize(0))
+	require.Error(t, err)
+}
@@ -0,0 +1,78 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// maxLineSize bounds a single JSON line, books with a long content are large.
+const maxLineSize = 16 << 20
+
+// jsonlReader reads one JSON object per line. Strings, numbers and booleans become single values,
+// arrays of them repeated values; nested objects are not supported.
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newJSONLReader(r io.Reader) *jsonlReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), maxLineSize)
+	return &jsonlReader{scanner: scanner}
+}
+
+func (j *jsonlReader) Read() (Record, error) {
+	for j.scanner.Scan() {
+		j.line++
+		line := bytes.TrimSpace(j.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var object map[string]any
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+		if err := dec.Decode(&object); err != nil {
+			return Record{}, &RecordError{Number: j.line, Err: fmt.Errorf("invalid JSON: %w", err)}
+		}
+
+		record := Record{Number: j.line, Fields: make(map[string][]string, len(object))}
+		for field, value := range object {
+			values, ok := value.([]any)
+			if !ok {
+				values = []any{value}
+			}
+			for _, v := range values {
+				s, err := scalar(v)
+				if err != nil {
+					return Record{}, &RecordError{Number: j.line, Err: fmt.Errorf("field %s: %w", field, err)}
+				}
+				record.add(field, s)
+			}
+		}
+		return record, nil
+	}
+	if err := j.scanner.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
+
+func scalar(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		return "", fmt.Errorf("nested objects are not supported")
+	}
+}
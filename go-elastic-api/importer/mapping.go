@@ -0,0 +1,256 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go-elastic-api/es"
+)
+
+// Mapping tells which fields of the source records the fields of a book are read from.
+//
+// A mapping file is JSON. Fields are keyed by the JSON name of the Book field, plus "isbn",
+// and name one source field, a list of source fields of which the first with a value is used,
+// or an object with the source fields in "from" and a "split" separator for lists:
+//
+//	{
+//	  "fields": {
+//	    "isbn": "ISBN",
+//	    "name": "Title",
+//	    "author": ["Author", "Editor"],
+//	    "release_date": "Published",
+//	    "categories": {"from": "Subjects", "split": ";"}
+//	  },
+//	  "date_layouts": ["02/01/2006"]
+//	}
+type Mapping struct {
+	Fields map[string]Field `json:"fields"`
+	// DateLayouts are the Go time layouts of release dates, tried before the common ones.
+	DateLayouts []string `json:"date_layouts,omitempty"`
+}
+
+// Field lists the source fields a book field is read from.
+type Field struct {
+	From  []string `json:"from"`
+	Split string   `json:"split,omitempty"`
+}
+
+func (f *Field) UnmarshalJSON(b []byte) error {
+	var from string
+	if err := json.Unmarshal(b, &from); err == nil {
+		f.From = []string{from}
+		return nil
+	}
+	if err := json.Unmarshal(b, &f.From); err == nil {
+		return nil
+	}
+	var field struct {
+		From  json.RawMessage `json:"from"`
+		Split string          `json:"split"`
+	}
+	if err := json.Unmarshal(b, &field); err != nil || field.From == nil {
+		return errors.New(`must be a source field, a list of source fields or an object with "from" and "split"`)
+	}
+	if err := f.UnmarshalJSON(field.From); err != nil {
+		return err
+	}
+	f.Split = field.Split
+	return nil
+}
+
+// bookFields are the fields a mapping can set, by their JSON name.
+var bookFields = []string{
+	"id", "isbn", "name", "author", "edition", "publisher", "release_date", "language",
+	"description", "page_count", "content", "categories", "tags", "rating", "review_count",
+}
+
+// LoadMapping reads and checks a mapping file.
+func LoadMapping(path string) (Mapping, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Mapping{}, err
+	}
+	var m Mapping
+	if err := json.Unmarshal(b, &m); err != nil {
+		return Mapping{}, fmt.Errorf("invalid mapping %s: %w", path, err)
+	}
+	if err := m.Validate(); err != nil {
+		return Mapping{}, fmt.Errorf("invalid mapping %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Validate checks that the mapping only sets known fields, and can set an ID and a name.
+func (m Mapping) Validate() error {
+	var errs []error
+	for name, field := range m.Fields {
+		if !slices.Contains(bookFields, name) {
+			errs = append(errs, fmt.Errorf("unknown book field %q, expected one of %v", name, bookFields))
+		}
+		if len(field.From) == 0 {
+			errs = append(errs, fmt.Errorf("field %q has no source field", name))
+		}
+	}
+	if _, ok := m.Fields["name"]; !ok {
+		errs = append(errs, errors.New(`field "name" is required`))
+	}
+	_, hasID := m.Fields["id"]
+	_, hasISBN := m.Fields["isbn"]
+	if !hasID && !hasISBN {
+		errs = append(errs, errors.New(`field "id" or "isbn" is required`))
+	}
+	return errors.Join(errs...)
+}
+
+// DefaultMapping returns the mapping used for format without a mapping file.
+// CSV and JSON Lines files are expected to use the JSON names of the Book fields,
+// as written by the export command.
+func DefaultMapping(format Format) Mapping {
+	switch format {
+	case FormatONIX:
+		return Mapping{Fields: map[string]Field{
+			"isbn":         {From: []string{"isbn13", "isbn10"}},
+			"name":         {From: []string{"title"}},
+			"author":       {From: []string{"author", "contributor"}},
+			"edition":      {From: []string{"edition_number", "edition_statement"}},
+			"publisher":    {From: []string{"publisher"}},
+			"release_date": {From: []string{"publishing_date"}},
+			"language":     {From: []string{"language"}},
+			"description":  {From: []string{"description"}},
+			"page_count":   {From: []string{"page_count"}},
+			"categories":   {From: []string{"subject"}},
+			"tags":         {From: []string{"keyword"}},
+		}}
+	case FormatMARCXML:
+		return Mapping{Fields: map[string]Field{
+			"isbn":         {From: []string{"020a"}},
+			"name":         {From: []string{"245a"}},
+			"author":       {From: []string{"100a", "110a", "700a"}},
+			"edition":      {From: []string{"250a"}},
+			"publisher":    {From: []string{"264b", "260b"}},
+			"release_date": {From: []string{"264c", "260c"}},
+			"language":     {From: []string{"041a"}},
+			"description":  {From: []string{"520a"}},
+			"page_count":   {From: []string{"300a"}},
+			"categories":   {From: []string{"650a", "655a"}},
+			"tags":         {From: []string{"653a"}},
+		}}
+	default:
+		m := Mapping{Fields: make(map[string]Field, len(bookFields))}
+		for _, name := range bookFields {
+			m.Fields[name] = Field{From: []string{name}}
+		}
+		if format == FormatCSV {
+			m.Fields["categories"] = Field{From: []string{"categories"}, Split: ";"}
+			m.Fields["tags"] = Field{From: []string{"tags"}, Split: ";"}
+		}
+		return m
+	}
+}
+
+// numberPattern finds the number in values such as "470 p." or "xii, 470 pages".
+var numberPattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// book maps a record to a book and returns it with its normalized ISBN, which is empty
+// when the record has none. The error tells why the record cannot be imported.
+func (m Mapping) book(r Record) (es.Book, string, error) {
+	var book es.Book
+	book.ID = m.value(r, "id")
+	book.Name = m.value(r, "name")
+	book.Author = strings.Join(m.values(r, "author", true), ", ")
+	book.Edition = m.value(r, "edition")
+	book.Publisher = m.value(r, "publisher")
+	book.Description = m.value(r, "description")
+	book.Content = m.value(r, "content")
+	book.Categories = m.values(r, "categories", false)
+	book.Tags = m.values(r, "tags", false)
+
+	var isbn string
+	if v := m.value(r, "isbn"); v != "" {
+		var err error
+		if isbn, err = NormalizeISBN(v); err != nil {
+			return book, "", err
+		}
+		if book.ID == "" {
+			book.ID = isbn
+		}
+	}
+	if book.ID == "" {
+		return book, "", errors.New("id or isbn is required")
+	}
+	if book.Name == "" {
+		return book, "", errors.New("name is required")
+	}
+
+	date := m.value(r, "release_date")
+	if date == "" {
+		return book, "", errors.New("release_date is required")
+	}
+	var err error
+	if book.ReleaseDate, err = NormalizeDate(date, m.DateLayouts); err != nil {
+		return book, "", fmt.Errorf("release_date: %w", err)
+	}
+	if v := m.value(r, "language"); v != "" {
+		if book.Language, err = NormalizeLanguage(v); err != nil {
+			return book, "", fmt.Errorf("language: %w", err)
+		}
+	}
+
+	for name, target := range map[string]*int{"page_count": &book.PageCount, "review_count": &book.ReviewCount} {
+		if v := m.value(r, name); v != "" {
+			n, err := strconv.Atoi(numberPattern.FindString(v))
+			if err != nil {
+				return book, "", fmt.Errorf("%s: %q is not a number", name, v)
+			}
+			*target = n
+		}
+	}
+	if v := m.value(r, "rating"); v != "" {
+		rating, err := strconv.ParseFloat(numberPattern.FindString(v), 32)
+		if err != nil {
+			return book, "", fmt.Errorf("rating: %q is not a number", v)
+		}
+		book.Rating = float32(rating)
+	}
+	return book, isbn, nil
+}
+
+// value returns the first value of the first source field of name that has one.
+func (m Mapping) value(r Record, name string) string {
+	for _, from := range m.Fields[name].From {
+		if v := r.Value(from); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// values returns the values of name, split by the separator of the field. With first,
+// only the values of the first source field that has any are returned, otherwise those of all.
+func (m Mapping) values(r Record, name string, first bool) []string {
+	field := m.Fields[name]
+	var values []string
+	for _, from := range field.From {
+		for _, v := range r.Fields[from] {
+			parts := []string{v}
+			if field.Split != "" {
+				parts = strings.Split(v, field.Split)
+			}
+			for _, part := range parts {
+				if part = strings.TrimSpace(part); part != "" && !slices.Contains(values, part) {
+					values = append(values, part)
+				}
+			}
+		}
+		if first && len(values) > 0 {
+			break
+		}
+	}
+	return values
+}
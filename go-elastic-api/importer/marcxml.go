@@ -0,0 +1,78 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// marcRecord is a MARC 21 record in the MARCXML schema.
+type marcRecord struct {
+	ControlFields []struct {
+		Tag   string `xml:"tag,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"controlfield"`
+	DataFields []struct {
+		Tag       string `xml:"tag,attr"`
+		Subfields []struct {
+			Code  string `xml:"code,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"subfield"`
+	} `xml:"datafield"`
+}
+
+// marcxmlReader reads the <record> elements of a MARCXML file into records with a field per
+// control field tag, e.g. "001", and per data field tag and subfield code, e.g. "245a" for the title.
+// The ISBD punctuation that separates subfields, as in "Snow crash /", is removed.
+type marcxmlReader struct {
+	dec    *xml.Decoder
+	number int
+}
+
+func newMARCXMLReader(r io.Reader) *marcxmlReader {
+	return &marcxmlReader{dec: xml.NewDecoder(r)}
+}
+
+func (m *marcxmlReader) Read() (Record, error) {
+	for {
+		tok, err := m.dec.Token()
+		if err == io.EOF {
+			return Record{}, io.EOF
+		}
+		if err != nil {
+			return Record{}, fmt.Errorf("invalid MARCXML after record %d: %w", m.number, err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+
+		m.number++
+		var mr marcRecord
+		if err := m.dec.DecodeElement(&mr, &start); err != nil {
+			return Record{}, fmt.Errorf("invalid MARCXML record %d: %w", m.number, err)
+		}
+
+		r := Record{Number: m.number, Fields: make(map[string][]string)}
+		for _, f := range mr.ControlFields {
+			r.add(f.Tag, f.Value)
+		}
+		for _, f := range mr.DataFields {
+			for _, sf := range f.Subfields {
+				r.add(f.Tag+sf.Code, trimISBD(sf.Value))
+			}
+		}
+		return r, nil
+	}
+}
+
+// trimISBD removes the trailing punctuation catalogers put before the next subfield.
+// A final period is kept after an initial or an abbreviation, as in "Tolkien, J. R. R." or "2nd ed.".
+func trimISBD(s string) string {
+	s = strings.TrimRight(strings.TrimSpace(s), " /:;,=")
+	if words := strings.Fields(s); len(words) > 0 && len(strings.TrimSuffix(words[len(words)-1], ".")) > 2 {
+		s = strings.TrimSuffix(s, ".")
+	}
+	return s
+}
@@ -0,0 +1,132 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"go-elastic-api/val"
+)
+
+// defaultDateLayouts are the date layouts tried after the ones of the mapping,
+// covering ISO dates, ONIX dates and common spreadsheet exports.
+var defaultDateLayouts = []string{
+	time.DateOnly,
+	"20060102",
+	"2006/01/02",
+	"01/02/2006",
+	"2 January 2006",
+	"January 2, 2006",
+	"2 Jan 2006",
+	"Jan 2, 2006",
+	"2006-01",
+	"200601",
+	"January 2006",
+	"2006",
+}
+
+// yearPattern matches catalog dates made of a year and punctuation, such as "c1992." or "[1953?]".
+var yearPattern = regexp.MustCompile(`^\D*((?:1[5-9]|20)\d{2})\D*$`)
+
+// NormalizeDate converts a date to the YYYY-MM-DD format accepted by val.IsDateValid.
+// layouts are tried first, then common layouts. Dates without a day or month
+// are set to the first day of the month or year. As a last resort, the year
+// is taken from a value made of a year and punctuation, as catalogs often write "c1992." or "[1953?]".
+func NormalizeDate(s string, layouts []string) (string, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range slices.Concat(layouts, defaultDateLayouts) {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(time.DateOnly), nil
+		}
+	}
+	if match := yearPattern.FindStringSubmatch(s); match != nil {
+		date := match[1] + "-01-01"
+		if val.IsDateValid(date) {
+			return date, nil
+		}
+	}
+	return "", fmt.Errorf("unknown date format %q", s)
+}
+
+// isbnPattern finds an ISBN at the start of a value, catalogs add qualifiers
+// after it, e.g. "0-553-35192-X (pbk.)".
+var isbnPattern = regexp.MustCompile(`^[0-9][0-9\- ]{8,16}[0-9Xx]`)
+
+// NormalizeISBN validates an ISBN-10 or ISBN-13, with or without hyphens and spaces,
+// and returns it as ISBN-13 digits, so both forms of a book dedupe to the same value.
+func NormalizeISBN(s string) (string, error) {
+	match := isbnPattern.FindString(strings.TrimSpace(s))
+	digits := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(match))
+
+	switch len(digits) {
+	case 10:
+		sum := 0
+		for i, c := range digits {
+			var d int
+			switch {
+			case c == 'X' && i == 9:
+				d = 10
+			case c >= '0' && c <= '9':
+				d = int(c - '0')
+			default:
+				return "", fmt.Errorf("invalid ISBN %q", s)
+			}
+			sum += (10 - i) * d
+		}
+		if sum%11 != 0 {
+			return "", fmt.Errorf("invalid ISBN %q: wrong check digit", s)
+		}
+		isbn13 := "978" + digits[:9]
+		return isbn13 + isbn13CheckDigit(isbn13), nil
+	case 13:
+		if strings.ContainsRune(digits, 'X') {
+			return "", fmt.Errorf("invalid ISBN %q", s)
+		}
+		if isbn13CheckDigit(digits[:12]) != digits[12:] {
+			return "", fmt.Errorf("invalid ISBN %q: wrong check digit", s)
+		}
+		return digits, nil
+	default:
+		return "", fmt.Errorf("invalid ISBN %q: must have 10 or 13 digits", s)
+	}
+}
+
+// isbn13CheckDigit computes the check digit of the first 12 digits of an ISBN-13.
+func isbn13CheckDigit(digits string) string {
+	sum := 0
+	for i, c := range digits {
+		d := int(c - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return fmt.Sprint((10 - sum%10) % 10)
+}
+
+// languageCodes maps the ISO 639-2 codes catalogs use, bibliographic and terminologic,
+// to the ISO 639-1 code of the language.
+var languageCodes = map[string]string{
+	"ara": "ar", "chi": "zh", "zho": "zh", "dut": "nl", "nld": "nl", "eng": "en",
+	"fre": "fr", "fra": "fr", "ger": "de", "deu": "de", "ita": "it", "jpn": "ja",
+	"kor": "ko", "por": "pt", "rus": "ru", "spa": "es", "tha": "th", "vie": "vi",
+}
+
+// NormalizeLanguage converts a language code to the lowercase ISO 639-1 code accepted by val.IsLanguageValid.
+// It accepts ISO 639-1 codes, with or without a region as in "en-US", and the ISO 639-2 codes
+// of common languages, as ONIX and MARC records write "eng" or "vie".
+func NormalizeLanguage(s string) (string, error) {
+	code := strings.ToLower(strings.TrimSpace(s))
+	if i := strings.IndexAny(code, "-_"); i > 0 {
+		code = code[:i]
+	}
+	if alpha2, ok := languageCodes[code]; ok {
+		code = alpha2
+	}
+	if !val.IsLanguageValid(code) {
+		return "", fmt.Errorf("unknown language %q", s)
+	}
+	return code, nil
+}
@@ -0,0 +1,85 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeDate(t *testing.T) {
+	for input, want := range map[string]string{
+		"1992-06-01":     "1992-06-01",
+		"19920601":       "1992-06-01",
+		"06/01/1992":     "1992-06-01",
+		"1 June 1992":    "1992-06-01",
+		"June 1, 1992":   "1992-06-01",
+		"1992-06":        "1992-06-01",
+		"1992":           "1992-01-01",
+		"c1992.":         "1992-01-01",
+		"[1953?]":        "1953-01-01",
+		" 2000-03-15 \t": "2000-03-15",
+	} {
+		got, err := NormalizeDate(input, nil)
+		require.NoError(t, err, input)
+		require.Equal(t, want, got, input)
+	}
+
+	// The layouts of the mapping come first: 01/06/1992 is the 1st of June in Europe
+	got, err := NormalizeDate("01/06/1992", []string{"02/01/2006"})
+	require.NoError(t, err)
+	require.Equal(t, "1992-06-01", got)
+
+	// The layouts of the mapping are left unchanged, even with room to spare
+	layouts := make([]string, 1, 10)
+	layouts[0] = "02/01/2006"
+	_, err = NormalizeDate("1992", layouts)
+	require.NoError(t, err)
+	require.Empty(t, layouts[1:cap(layouts)][0], "the backing array is not written")
+
+	for _, input := range []string{"", "soon", "1992-13-01", "31/02/1992", "1953-1960"} {
+		_, err := NormalizeDate(input, nil)
+		require.Error(t, err, input)
+	}
+}
+
+func TestNormalizeISBN(t *testing.T) {
+	for input, want := range map[string]string{
+		"9780553351927":       "9780553351927",
+		"978-0-553-35192-7":   "9780553351927",
+		"0553351923":          "9780553351927",
+		"0-553-35192-3 (pbk)": "9780553351927",
+		"0-8044-2957-X":       "9780804429573",
+		"080442957x":          "9780804429573",
+	} {
+		got, err := NormalizeISBN(input)
+		require.NoError(t, err, input)
+		require.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{"", "9780553351928", "0553351924", "12345", "978055335192X"} {
+		_, err := NormalizeISBN(input)
+		require.Error(t, err, input)
+	}
+}
+
+func TestNormalizeLanguage(t *testing.T) {
+	for input, want := range map[string]string{
+		"en":    "en",
+		" VI ":  "vi",
+		"en-US": "en",
+		"pt_BR": "pt",
+		"eng":   "en",
+		"vie":   "vi",
+		"fre":   "fr",
+		"deu":   "de",
+	} {
+		got, err := NormalizeLanguage(input)
+		require.NoError(t, err, input)
+		require.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{"", "english", "xyz", "e1"} {
+		_, err := NormalizeLanguage(input)
+		require.Error(t, err, input)
+	}
+}
@@ -0,0 +1,254 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// onixProduct holds the parts of an ONIX for Books <Product> that map to a book,
+// in reference tag names. Both ONIX 3.0 and the older 2.1 layouts are read.
+type onixProduct struct {
+	RecordReference    string `xml:"RecordReference"`
+	ProductIdentifiers []struct {
+		Type  string `xml:"ProductIDType"`
+		Value string `xml:"IDValue"`
+	} `xml:"ProductIdentifier"`
+
+	// ONIX 3.0
+	DescriptiveDetail struct {
+		TitleDetails []struct {
+			Type     string `xml:"TitleType"`
+			Elements []struct {
+				Level    string `xml:"TitleElementLevel"`
+				Text     string `xml:"TitleText"`
+				Prefix   string `xml:"TitlePrefix"`
+				Without  string `xml:"TitleWithoutPrefix"`
+				Subtitle string `xml:"Subtitle"`
+			} `xml:"TitleElement"`
+		} `xml:"TitleDetail"`
+		Contributors     []onixContributor `xml:"Contributor"`
+		EditionNumber    string            `xml:"EditionNumber"`
+		EditionStatement string            `xml:"EditionStatement"`
+		Extents          []struct {
+			Type  string `xml:"ExtentType"`
+			Value string `xml:"ExtentValue"`
+			Unit  string `xml:"ExtentUnit"`
+		} `xml:"Extent"`
+		Subjects []onixSubject `xml:"Subject"`
+		Language []struct {
+			Role string `xml:"LanguageRole"`
+			Code string `xml:"LanguageCode"`
+		} `xml:"Language"`
+	} `xml:"DescriptiveDetail"`
+	CollateralDetail struct {
+		TextContents []struct {
+			Type string `xml:"TextType"`
+			Text string `xml:"Text"`
+		} `xml:"TextContent"`
+	} `xml:"CollateralDetail"`
+	PublishingDetail struct {
+		Publishers      []onixPublisher `xml:"Publisher"`
+		PublishingDates []struct {
+			Role string `xml:"PublishingDateRole"`
+			Date string `xml:"Date"`
+		} `xml:"PublishingDate"`
+	} `xml:"PublishingDetail"`
+
+	// ONIX 2.1
+	Title struct {
+		Text     string `xml:"TitleText"`
+		Subtitle string `xml:"Subtitle"`
+	} `xml:"Title"`
+	Contributors     []onixContributor `xml:"Contributor"`
+	EditionNumber    string            `xml:"EditionNumber"`
+	EditionStatement string            `xml:"EditionStatement"`
+	NumberOfPages    string            `xml:"NumberOfPages"`
+	Subjects         []onixSubject     `xml:"Subject"`
+	MainSubjects     []onixSubject     `xml:"MainSubject"`
+	OtherTexts       []struct {
+		Type string `xml:"TextTypeCode"`
+		Text string `xml:"Text"`
+	} `xml:"OtherText"`
+	Publishers      []onixPublisher `xml:"Publisher"`
+	PublicationDate string          `xml:"PublicationDate"`
+}
+
+type onixContributor struct {
+	Role           string `xml:"ContributorRole"`
+	PersonName     string `xml:"PersonName"`
+	PersonInverted string `xml:"PersonNameInverted"`
+	CorporateName  string `xml:"CorporateName"`
+	NamesBeforeKey string `xml:"NamesBeforeKey"`
+	KeyNames       string `xml:"KeyNames"`
+}
+
+func (c onixContributor) name() string {
+	switch {
+	case c.PersonName != "":
+		return c.PersonName
+	case c.KeyNames != "":
+		return strings.TrimSpace(c.NamesBeforeKey + " " + c.KeyNames)
+	case c.CorporateName != "":
+		return c.CorporateName
+	default:
+		return c.PersonInverted
+	}
+}
+
+type onixSubject struct {
+	Scheme  string `xml:"SubjectSchemeIdentifier"`
+	Code    string `xml:"SubjectCode"`
+	Heading string `xml:"SubjectHeadingText"`
+}
+
+type onixPublisher struct {
+	Role string `xml:"PublishingRole"`
+	Name string `xml:"PublisherName"`
+}
+
+// ONIX code list values used below
+const (
+	onixISBN10         = "02"
+	onixISBN13         = "15"
+	onixDistinctive    = "01" // TitleType: distinctive title
+	onixAuthor         = "A01"
+	onixMainContent    = "00" // ExtentType: main content page count
+	onixTotalPages     = "07" // ExtentType: total numbered pages
+	onixPages          = "03" // ExtentUnit
+	onixKeywords       = "20" // SubjectSchemeIdentifier
+	onixShortDesc      = "02" // TextType
+	onixDescription    = "03" // TextType
+	onixPublicationDay = "01" // PublishingDateRole
+)
+
+// onixReader reads the <Product> elements of an ONIX for Books message into records with the fields
+// record_reference, isbn10, isbn13, title, subtitle, author, contributor, edition_number,
+// edition_statement, page_count, subject, keyword, language, description, publisher and publishing_date.
+// Messages with short tags are not supported.
+type onixReader struct {
+	dec    *xml.Decoder
+	number int
+}
+
+func newONIXReader(r io.Reader) *onixReader {
+	return &onixReader{dec: xml.NewDecoder(r)}
+}
+
+func (o *onixReader) Read() (Record, error) {
+	for {
+		tok, err := o.dec.Token()
+		if err == io.EOF {
+			return Record{}, io.EOF
+		}
+		if err != nil {
+			return Record{}, fmt.Errorf("invalid ONIX after product %d: %w", o.number, err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local == "product" {
+			return Record{}, fmt.Errorf("ONIX with short tags is not supported")
+		}
+		if start.Name.Local != "Product" {
+			continue
+		}
+
+		o.number++
+		var p onixProduct
+		if err := o.dec.DecodeElement(&p, &start); err != nil {
+			return Record{}, fmt.Errorf("invalid ONIX product %d: %w", o.number, err)
+		}
+		return p.record(o.number), nil
+	}
+}
+
+func (p *onixProduct) record(number int) Record {
+	r := Record{Number: number, Fields: make(map[string][]string)}
+	r.add("record_reference", p.RecordReference)
+
+	for _, id := range p.ProductIdentifiers {
+		switch id.Type {
+		case onixISBN10:
+			r.add("isbn10", id.Value)
+		case onixISBN13:
+			r.add("isbn13", id.Value)
+		}
+	}
+
+	d := &p.DescriptiveDetail
+	for _, title := range d.TitleDetails {
+		if title.Type != onixDistinctive {
+			continue
+		}
+		for _, e := range title.Elements {
+			text := e.Text
+			if text == "" {
+				text = strings.TrimSpace(e.Prefix + " " + e.Without)
+			}
+			r.add("title", text)
+			r.add("subtitle", e.Subtitle)
+		}
+	}
+	r.add("title", p.Title.Text)
+	r.add("subtitle", p.Title.Subtitle)
+
+	for _, c := range append(d.Contributors, p.Contributors...) {
+		if c.Role == onixAuthor {
+			r.add("author", c.name())
+		}
+		r.add("contributor", c.name())
+	}
+
+	r.add("edition_number", d.EditionNumber)
+	r.add("edition_number", p.EditionNumber)
+	r.add("edition_statement", d.EditionStatement)
+	r.add("edition_statement", p.EditionStatement)
+
+	for _, e := range d.Extents {
+		if (e.Type == onixMainContent || e.Type == onixTotalPages) && e.Unit == onixPages {
+			r.add("page_count", e.Value)
+		}
+	}
+	r.add("page_count", p.NumberOfPages)
+
+	for _, s := range append(append(d.Subjects, p.MainSubjects...), p.Subjects...) {
+		if s.Scheme == onixKeywords {
+			for _, keyword := range strings.Split(s.Heading, ";") {
+				r.add("keyword", keyword)
+			}
+			continue
+		}
+		r.add("subject", s.Heading)
+	}
+	for _, l := range d.Language {
+		r.add("language", l.Code)
+	}
+
+	// The long description comes first, the short one is the fallback
+	for _, textType := range []string{onixDescription, onixShortDesc} {
+		for _, t := range p.CollateralDetail.TextContents {
+			if t.Type == textType {
+				r.add("description", t.Text)
+			}
+		}
+	}
+	for _, t := range p.OtherTexts {
+		r.add("description", t.Text)
+	}
+
+	for _, pub := range append(p.PublishingDetail.Publishers, p.Publishers...) {
+		if pub.Role == "" || pub.Role == "01" {
+			r.add("publisher", pub.Name)
+		}
+	}
+	for _, date := range p.PublishingDetail.PublishingDates {
+		if date.Role == onixPublicationDay {
+			r.add("publishing_date", date.Date)
+		}
+	}
+	r.add("publishing_date", p.PublicationDate)
+	return r
+}
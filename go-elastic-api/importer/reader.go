@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Format is the format of a source file.
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatJSONL   Format = "jsonl"
+	FormatONIX    Format = "onix"
+	FormatMARCXML Format = "marcxml"
+)
+
+// Formats lists the supported formats.
+var Formats = []Format{FormatCSV, FormatJSONL, FormatONIX, FormatMARCXML}
+
+// Record is one row or product of a source file, with the values of each of its fields.
+// Fields of XML formats can repeat, e.g. the contributors of a book.
+type Record struct {
+	// Number is the position of the record in the file, starting at 1.
+	// It is the line number for CSV and JSON Lines files.
+	Number int
+	Fields map[string][]string
+}
+
+// Value returns the first non-empty value of field, or "".
+func (r Record) Value(field string) string {
+	for _, v := range r.Fields[field] {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func (r Record) add(field, value string) {
+	if value = strings.TrimSpace(value); value != "" {
+		r.Fields[field] = append(r.Fields[field], value)
+	}
+}
+
+// Reader reads the records of a source file one at a time, returning io.EOF after the last one.
+// A *RecordError means that a single record is unreadable and the next one can be read.
+type Reader interface {
+	Read() (Record, error)
+}
+
+// RecordError reports a record that cannot be read, such as a CSV row with a wrong number of columns.
+type RecordError struct {
+	Number int
+	Err    error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("record %d: %s", e.Number, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// NewReader returns the Reader of format for r.
+func NewReader(format Format, r io.Reader) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatJSONL:
+		return newJSONLReader(r), nil
+	case FormatONIX:
+		return newONIXReader(r), nil
+	case FormatMARCXML:
+		return newMARCXMLReader(r), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q, expected one of %v", format, Formats)
+	}
+}
+
+// DetectFormat tells the format of a file from its extension, or from the root element of .xml files,
+// which it peeks at in r. The returned reader must be used instead of r.
+func DetectFormat(path string, r io.Reader) (Format, io.Reader, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV, r, nil
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, r, nil
+	case ".onix":
+		return FormatONIX, r, nil
+	case ".mrc", ".marcxml":
+		return FormatMARCXML, r, nil
+	case ".xml":
+		br := bufio.NewReaderSize(r, 4096)
+		head, _ := br.Peek(4096)
+		switch {
+		case bytes.Contains(head, []byte("<ONIXMessage")):
+			return FormatONIX, br, nil
+		case bytes.Contains(head, []byte("www.loc.gov/MARC21/slim")), bytes.Contains(head, []byte("<collection")):
+			return FormatMARCXML, br, nil
+		}
+		return "", br, fmt.Errorf("cannot tell whether %s is ONIX or MARCXML, set the format", path)
+	default:
+		return "", r, fmt.Errorf("cannot tell the format of %s from its extension, set the format", path)
+	}
+}
@@ -0,0 +1,178 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readAll reads the records of path, with the numbers of the records that could not be read.
+func readAll(t *testing.T, format Format, r io.Reader) ([]Record, []int) {
+	t.Helper()
+	reader, err := NewReader(format, r)
+	require.NoError(t, err)
+
+	var records []Record
+	var bad []int
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return records, bad
+		}
+		var recordErr *RecordError
+		if errors.As(err, &recordErr) {
+			bad = append(bad, recordErr.Number)
+			continue
+		}
+		require.NoError(t, err)
+		records = append(records, record)
+	}
+}
+
+func openTestdata(t *testing.T, name string) *os.File {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestCSVReader(t *testing.T) {
+	records, bad := readAll(t, FormatCSV, openTestdata(t, "books.csv"))
+	require.Equal(t, []int{6}, bad)
+	require.Len(t, records, 5)
+
+	// The byte order mark is not part of the first column name
+	require.Equal(t, 2, records[0].Number)
+	require.Equal(t, "978-0-553-38095-8", records[0].Value("isbn"))
+	require.Equal(t, "Science fiction;Cyberpunk", records[0].Value("categories"))
+	require.NotContains(t, records[2].Fields, "categories", "empty values are left out")
+
+	input := "id,name\n1,Snow Crash\n2,Dune,extra\n\"3\",\"Revelation\nSpace\"\n4,1984\n"
+	records, bad = readAll(t, FormatCSV, strings.NewReader(input))
+	require.Equal(t, []int{3}, bad)
+	require.Len(t, records, 3)
+	require.Equal(t, []int{2, 4, 6}, []int{records[0].Number, records[1].Number, records[2].Number})
+	require.Equal(t, "Revelation\nSpace", records[1].Value("name"))
+
+	// The prefix export writes before formulas is removed, other quotes are kept
+	records, _ = readAll(t, FormatCSV, strings.NewReader("name,author\n'=1+1,'Tis Pity\n"))
+	require.Equal(t, "=1+1", records[0].Value("name"))
+	require.Equal(t, "'Tis Pity", records[0].Value("author"))
+
+	// Files from other sources are unescaped too, a ' of the data before a formula prefix is lost
+	records, _ = readAll(t, FormatCSV, strings.NewReader("name,tags\nThe '-ism' of it,'-ism'\n"))
+	require.Equal(t, "The '-ism' of it", records[0].Value("name"))
+	require.Equal(t, "-ism'", records[0].Value("tags"))
+}
+
+func TestJSONLReader(t *testing.T) {
+	input := `{"id": "1", "name": "Snow Crash", "page_count": 480, "tags": ["cyberpunk", "satire"]}
+
+{"id": "2", "name":
+{"id": "3", "name": "Dune", "author": {"name": "Frank Herbert"}}
+{"id": "4", "name": "1984", "rating": 4.5, "available": true, "edition": null}
+`
+	records, bad := readAll(t, FormatJSONL, strings.NewReader(input))
+	require.Equal(t, []int{3, 4}, bad)
+	require.Len(t, records, 2)
+
+	require.Equal(t, 1, records[0].Number)
+	require.Equal(t, "480", records[0].Value("page_count"))
+	require.Equal(t, []string{"cyberpunk", "satire"}, records[0].Fields["tags"])
+
+	require.Equal(t, 5, records[1].Number)
+	require.Equal(t, "4.5", records[1].Value("rating"))
+	require.Equal(t, "true", records[1].Value("available"))
+	require.Empty(t, records[1].Value("edition"))
+}
+
+func TestONIXReader(t *testing.T) {
+	records, bad := readAll(t, FormatONIX, openTestdata(t, "books.onix.xml"))
+	require.Empty(t, bad)
+	require.Len(t, records, 2)
+
+	dune := records[0]
+	require.Equal(t, map[string][]string{
+		"record_reference": {"com.example.9780441013593"},
+		"isbn13":           {"9780441013593"},
+		"title":            {"Dune"},
+		"author":           {"Frank Herbert"},
+		"contributor":      {"Frank Herbert"},
+		"edition_number":   {"40"},
+		"language":         {"eng"},
+		"page_count":       {"617"},
+		"subject":          {"Science fiction"},
+		"keyword":          {"desert", "ecology"},
+		"description":      {"Set on the desert planet Arrakis, Dune is the story of Paul Atreides.", "A desert planet."},
+		"publisher":        {"Ace"},
+		"publishing_date":  {"20050802"},
+	}, dune.Fields)
+	require.Empty(t, records[1].Value("isbn13"))
+
+	// ONIX 2.1
+	records, bad = readAll(t, FormatONIX, openTestdata(t, "books.onix21.xml"))
+	require.Empty(t, bad)
+	require.Len(t, records, 1)
+	require.Equal(t, "014118776X", records[0].Value("isbn10"))
+	require.Equal(t, "Nineteen Eighty-Four", records[0].Value("title"))
+	require.Equal(t, "George Orwell", records[0].Value("author"))
+	require.Equal(t, "355", records[0].Value("page_count"))
+	require.Equal(t, "Fiction", records[0].Value("subject"))
+	require.Equal(t, "Penguin", records[0].Value("publisher"))
+	require.Equal(t, "20000127", records[0].Value("publishing_date"))
+
+	reader, err := NewReader(FormatONIX, strings.NewReader(`<ONIXMessage><product><a001>1</a001></product></ONIXMessage>`))
+	require.NoError(t, err)
+	_, err = reader.Read()
+	require.ErrorContains(t, err, "short tags")
+}
+
+func TestMARCXMLReader(t *testing.T) {
+	records, bad := readAll(t, FormatMARCXML, openTestdata(t, "books.marcxml"))
+	require.Empty(t, bad)
+	require.Len(t, records, 2)
+
+	lotr := records[0]
+	require.Equal(t, "12345", lotr.Value("001"))
+	require.Equal(t, "9780261103252 (paperback)", lotr.Value("020a"))
+	require.Equal(t, "Tolkien, J. R. R.", lotr.Value("100a"))
+	require.Equal(t, "The lord of the rings", lotr.Value("245a"))
+	require.Equal(t, "50th anniversary ed.", lotr.Value("250a"))
+	require.Equal(t, "HarperCollins", lotr.Value("264b"))
+	require.Equal(t, "c2005", lotr.Value("264c"))
+	require.Equal(t, "xviii, 1178 p.", lotr.Value("300a"))
+	require.Equal(t, []string{"Fantasy fiction", "Middle Earth (Imaginary place)"}, lotr.Fields["650a"])
+}
+
+func TestDetectFormat(t *testing.T) {
+	for path, want := range map[string]Format{
+		"books.csv":      FormatCSV,
+		"books.CSV":      FormatCSV,
+		"books.jsonl":    FormatJSONL,
+		"books.ndjson":   FormatJSONL,
+		"books.onix":     FormatONIX,
+		"books.marcxml":  FormatMARCXML,
+		"books.onix.xml": FormatONIX,
+	} {
+		format, _, err := DetectFormat(path, strings.NewReader(`<?xml version="1.0"?><ONIXMessage release="3.0">`))
+		require.NoError(t, err, path)
+		require.Equal(t, want, format, path)
+	}
+
+	// The peeked bytes are not lost
+	format, r, err := DetectFormat("export.xml", openTestdata(t, "books.marcxml"))
+	require.NoError(t, err)
+	require.Equal(t, FormatMARCXML, format)
+	records, _ := readAll(t, format, r)
+	require.Len(t, records, 2)
+
+	_, _, err = DetectFormat("books.xml", strings.NewReader(`<catalog/>`))
+	require.Error(t, err)
+	_, _, err = DetectFormat("books.txt", strings.NewReader(""))
+	require.Error(t, err)
+}
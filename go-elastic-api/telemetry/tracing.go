@@ -0,0 +1,58 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+
+	"go-elastic-api/util"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// ServiceName identifies this service in traces.
+const ServiceName = "go-elastic-api"
+
+// SetupTracing installs the global W3C trace context propagator and, when tracing is enabled,
+// a tracer provider exporting spans over OTLP/HTTP.
+// The exporter is configured through the standard OTEL_EXPORTER_OTLP_* environment variables,
+// e.g. OTEL_EXPORTER_OTLP_ENDPOINT=http://localhost:4318.
+// The returned function flushes pending spans and must be called before the process exits.
+func SetupTracing(ctx context.Context, cfg util.Config) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !cfg.TracingEnabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create OTLP trace exporter: %w", err)
+	}
+
+	tp := NewTracerProvider(exporter, cfg.TracingSampleRatio)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// NewTracerProvider creates a tracer provider that batches spans to exporter.
+// Root spans are sampled with the given ratio; child spans follow their parent's decision.
+func NewTracerProvider(exporter sdktrace.SpanExporter, sampleRatio float64) *sdktrace.TracerProvider {
+	res := resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(ServiceName),
+		semconv.ServiceVersion(util.Version),
+	)
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+}
@@ -1,27 +1,672 @@
 package util
 
 import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	HTTPServerAddress          string `mapstructure:"HTTP_SERVER_ADDRESS"`
-	ElasticsearchServerAddress string `mapstructure:"ELASTICSEARCH_SERVER_ADDRESS"`
+	// HTTP server
+	HTTPServerAddress string        `mapstructure:"HTTP_SERVER_ADDRESS"`
+	ShutdownTimeout   time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	// TrustedProxies are the proxies whose X-Forwarded-For header gives the client IP address
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES"`
+
+	// Elasticsearch connection.
+	// ElasticsearchServerAddress is the former single address setting, it is used
+	// when ElasticsearchAddresses is empty.
+	ElasticsearchServerAddress  string        `mapstructure:"ELASTICSEARCH_SERVER_ADDRESS"`
+	ElasticsearchAddresses      []string      `mapstructure:"ELASTICSEARCH_ADDRESSES"`
+	ElasticsearchUsername       string        `mapstructure:"ELASTICSEARCH_USERNAME"`
+	ElasticsearchPassword       string        `mapstructure:"ELASTICSEARCH_PASSWORD"`
+	ElasticsearchAPIKey         string        `mapstructure:"ELASTICSEARCH_API_KEY"`
+	ElasticsearchCloudID        string        `mapstructure:"ELASTICSEARCH_CLOUD_ID"`
+	ElasticsearchMaxRetries     int           `mapstructure:"ELASTICSEARCH_MAX_RETRIES"`
+	ElasticsearchRetryBackoff   time.Duration `mapstructure:"ELASTICSEARCH_RETRY_BACKOFF"`
+	ElasticsearchRequestTimeout time.Duration `mapstructure:"ELASTICSEARCH_REQUEST_TIMEOUT"`
+
+	// Elasticsearch resilience
+	ElasticsearchRetryMaxBackoff       time.Duration `mapstructure:"ELASTICSEARCH_RETRY_MAX_BACKOFF"`
+	ElasticsearchDiscoverNodesOnStart  bool          `mapstructure:"ELASTICSEARCH_DISCOVER_NODES_ON_START"`
+	ElasticsearchDiscoverNodesInterval time.Duration `mapstructure:"ELASTICSEARCH_DISCOVER_NODES_INTERVAL"`
+	ElasticsearchBreakerThreshold      int           `mapstructure:"ELASTICSEARCH_BREAKER_THRESHOLD"`
+	ElasticsearchBreakerCooldown       time.Duration `mapstructure:"ELASTICSEARCH_BREAKER_COOLDOWN"`
+
+	// Elasticsearch TLS
+	ElasticsearchCACert          string `mapstructure:"ELASTICSEARCH_CA_CERT"`
+	ElasticsearchCertFingerprint string `mapstructure:"ELASTICSEARCH_CERT_FINGERPRINT"`
+	ElasticsearchClientCert      string `mapstructure:"ELASTICSEARCH_CLIENT_CERT"`
+	ElasticsearchClientKey       string `mapstructure:"ELASTICSEARCH_CLIENT_KEY"`
+
+	// Index names. Books are read and written through BooksAlias when it is set,
+	// otherwise through BooksIndex directly.
+	BooksIndex string `mapstructure:"BOOKS_INDEX"`
+	BooksAlias string `mapstructure:"BOOKS_ALIAS"`
+
+	// Pagination limits for search endpoints
+	DefaultPageSize int `mapstructure:"DEFAULT_PAGE_SIZE"`
+	MaxPageSize     int `mapstructure:"MAX_PAGE_SIZE"`
+
+	// CORS
+	CORSAllowedOrigins   []string      `mapstructure:"CORS_ALLOWED_ORIGINS"`
+	CORSAllowedMethods   []string      `mapstructure:"CORS_ALLOWED_METHODS"`
+	CORSAllowedHeaders   []string      `mapstructure:"CORS_ALLOWED_HEADERS"`
+	CORSAllowCredentials bool          `mapstructure:"CORS_ALLOW_CREDENTIALS"`
+	CORSMaxAge           time.Duration `mapstructure:"CORS_MAX_AGE"`
+
+	// Response compression and security headers
+	CompressionEnabled     bool          `mapstructure:"COMPRESSION_ENABLED"`
+	CompressionMinSize     int           `mapstructure:"COMPRESSION_MIN_SIZE"`
+	SecurityHeadersEnabled bool          `mapstructure:"SECURITY_HEADERS_ENABLED"`
+	HSTSMaxAge             time.Duration `mapstructure:"HSTS_MAX_AGE"`
+
+	// Routes served without the /api/v1 prefix, answered with Deprecation and Sunset headers
+	LegacyRoutesEnabled bool   `mapstructure:"LEGACY_ROUTES_ENABLED"`
+	LegacyRoutesSunset  string `mapstructure:"LEGACY_ROUTES_SUNSET"`
+
+	// Authentication
+	AuthEnabled        bool     `mapstructure:"AUTH_ENABLED"`
+	AuthAPIKeys        []string `mapstructure:"AUTH_API_KEYS"`
+	AuthJWTHS256Secret string   `mapstructure:"AUTH_JWT_HS256_SECRET"`
+	AuthJWKSFile       string   `mapstructure:"AUTH_JWKS_FILE"`
+	AuthJWTIssuer      string   `mapstructure:"AUTH_JWT_ISSUER"`
+	AuthJWTAudience    string   `mapstructure:"AUTH_JWT_AUDIENCE"`
+
+	// Rate limiting, per API key or client IP and per route
+	RateLimitEnabled bool     `mapstructure:"RATE_LIMIT_ENABLED"`
+	RateLimitRPS     float64  `mapstructure:"RATE_LIMIT_RPS"`
+	RateLimitBurst   int      `mapstructure:"RATE_LIMIT_BURST"`
+	RateLimitRoutes  []string `mapstructure:"RATE_LIMIT_ROUTES"`
+	RateLimitIPRPS   float64  `mapstructure:"RATE_LIMIT_IP_RPS"`
+	RateLimitIPBurst int      `mapstructure:"RATE_LIMIT_IP_BURST"`
+
+	// Query cost limits, checked before a query is sent to Elasticsearch
+	QueryAllowLeadingWildcard bool `mapstructure:"QUERY_ALLOW_LEADING_WILDCARD"`
+	FilterMaxCategories       int  `mapstructure:"FILTER_MAX_CATEGORIES"`
+
+	// Search response cache, in process unless a Redis URL is set
+	CacheEnabled    bool          `mapstructure:"CACHE_ENABLED"`
+	CacheTTL        time.Duration `mapstructure:"CACHE_TTL"`
+	CacheMaxEntries int           `mapstructure:"CACHE_MAX_ENTRIES"`
+	CacheRedisURL   string        `mapstructure:"CACHE_REDIS_URL"`
+
+	// Snapshots of the books indices to a shared file system repository
+	SnapshotRepository      string        `mapstructure:"SNAPSHOT_REPOSITORY"`
+	SnapshotRepositoryPath  string        `mapstructure:"SNAPSHOT_REPOSITORY_PATH"`
+	SnapshotInterval        time.Duration `mapstructure:"SNAPSHOT_INTERVAL"`
+	SnapshotRetentionCount  int           `mapstructure:"SNAPSHOT_RETENTION_COUNT"`
+	SnapshotRetentionMaxAge time.Duration `mapstructure:"SNAPSHOT_RETENTION_MAX_AGE"`
+
+	// Events indices written through a rollover alias and aged by a lifecycle policy
+	EventsAlias              string        `mapstructure:"EVENTS_ALIAS"`
+	EventsRolloverMaxAge     time.Duration `mapstructure:"EVENTS_ROLLOVER_MAX_AGE"`
+	EventsRolloverMaxSize    string        `mapstructure:"EVENTS_ROLLOVER_MAX_SIZE"`
+	EventsWarmAfter          time.Duration `mapstructure:"EVENTS_WARM_AFTER"`
+	EventsShrinkShards       int           `mapstructure:"EVENTS_SHRINK_SHARDS"`
+	EventsForceMergeSegments int           `mapstructure:"EVENTS_FORCE_MERGE_SEGMENTS"`
+	EventsDeleteAfter        time.Duration `mapstructure:"EVENTS_DELETE_AFTER"`
+
+	// Search analytics, recorded to the events indices in the background
+	AnalyticsEnabled       bool          `mapstructure:"ANALYTICS_ENABLED"`
+	AnalyticsBufferSize    int           `mapstructure:"ANALYTICS_BUFFER_SIZE"`
+	AnalyticsFlushInterval time.Duration `mapstructure:"ANALYTICS_FLUSH_INTERVAL"`
+	// Popularity of the books, from their clicks in a window, updated by the server when analytics
+	// and the updater are enabled. With several replicas, one of them, or a scheduled job, is enough.
+	PopularityWindow         time.Duration `mapstructure:"POPULARITY_WINDOW"`
+	PopularityUpdaterEnabled bool          `mapstructure:"POPULARITY_UPDATER_ENABLED"`
+	PopularityUpdateInterval time.Duration `mapstructure:"POPULARITY_UPDATE_INTERVAL"`
+
+	// RelevanceFile holds the field boosts, phrase boosts, stopwords and first synonyms of full text search,
+	// the built-in ones are used when it is empty
+	RelevanceFile string `mapstructure:"RELEVANCE_FILE"`
+
+	// Ranking of full text search, text relevance blended with the log-scaled popularity, rating and review count
+	RankingTextWeight        float64 `mapstructure:"RANKING_TEXT_WEIGHT"`
+	RankingPopularityWeight  float64 `mapstructure:"RANKING_POPULARITY_WEIGHT"`
+	RankingRatingWeight      float64 `mapstructure:"RANKING_RATING_WEIGHT"`
+	RankingReviewCountWeight float64 `mapstructure:"RANKING_REVIEW_COUNT_WEIGHT"`
+
+	// Boosts of similar books by the same author or publisher as the book they are similar to
+	SimilarAuthorWeight    float64 `mapstructure:"SIMILAR_AUTHOR_WEIGHT"`
+	SimilarPublisherWeight float64 `mapstructure:"SIMILAR_PUBLISHER_WEIGHT"`
+
+	// Embedder turns books and queries into the vectors of semantic search: "local", computed in process,
+	// or "http", an OpenAI compatible embeddings service
+	Embedder        string        `mapstructure:"EMBEDDER"`
+	EmbedderURL     string        `mapstructure:"EMBEDDER_URL"`
+	EmbedderModel   string        `mapstructure:"EMBEDDER_MODEL"`
+	EmbedderAPIKey  string        `mapstructure:"EMBEDDER_API_KEY"`
+	EmbedderTimeout time.Duration `mapstructure:"EMBEDDER_TIMEOUT"`
+
+	// Observability
+	LogLevel           string        `mapstructure:"LOG_LEVEL"`
+	LogFormat          string        `mapstructure:"LOG_FORMAT"`
+	SlowQueryThreshold time.Duration `mapstructure:"SLOW_QUERY_THRESHOLD"`
+	TracingEnabled     bool          `mapstructure:"TRACING_ENABLED"`
+	TracingSampleRatio float64       `mapstructure:"TRACING_SAMPLE_RATIO"`
+	ReadinessCacheTTL  time.Duration `mapstructure:"READINESS_CACHE_TTL"`
+}
+
+// byteSizePattern matches an Elasticsearch byte size, such as 512mb or 10gb.
+var byteSizePattern = regexp.MustCompile(`^[0-9]+(b|kb|mb|gb|tb|pb)$`)
+
+// setting is a configuration key with its default value and flag usage.
+type setting struct {
+	key   string
+	value any
+	usage string
+}
+
+// settings lists every configuration key. Keys must be listed here to be read
+// from environment variables and flags.
+var settings = []setting{
+	{"HTTP_SERVER_ADDRESS", "0.0.0.0:8000", "address the HTTP server listens on"},
+	{"TRUSTED_PROXIES", []string{}, "comma separated IP addresses or CIDR ranges of the proxies trusted to set X-Forwarded-For"},
+	{"SHUTDOWN_TIMEOUT", 10 * time.Second, "time given to requests in flight and queued events to finish once the server is stopped"},
+
+	{"ELASTICSEARCH_SERVER_ADDRESS", "http://localhost:9200", "single Elasticsearch URL, deprecated in favor of ELASTICSEARCH_ADDRESSES"},
+	{"ELASTICSEARCH_ADDRESSES", []string{}, "comma separated Elasticsearch node URLs"},
+	{"ELASTICSEARCH_USERNAME", "", "Elasticsearch basic auth username"},
+	{"ELASTICSEARCH_PASSWORD", "", "Elasticsearch basic auth password"},
+	{"ELASTICSEARCH_API_KEY", "", "base64 encoded Elasticsearch API key"},
+	{"ELASTICSEARCH_CLOUD_ID", "", "Elastic Cloud deployment ID, replaces ELASTICSEARCH_ADDRESSES"},
+	{"ELASTICSEARCH_MAX_RETRIES", 3, "maximum number of retries of a failed Elasticsearch request"},
+	{"ELASTICSEARCH_RETRY_BACKOFF", 100 * time.Millisecond, "initial backoff between Elasticsearch retries"},
+	{"ELASTICSEARCH_REQUEST_TIMEOUT", 10 * time.Second, "timeout of a single es.Client call"},
+	{"ELASTICSEARCH_RETRY_MAX_BACKOFF", 5 * time.Second, "maximum backoff between Elasticsearch retries"},
+	{"ELASTICSEARCH_DISCOVER_NODES_ON_START", false, "sniff the cluster nodes when the client starts"},
+	{"ELASTICSEARCH_DISCOVER_NODES_INTERVAL", time.Duration(0), "sniff the cluster nodes periodically, 0 disables it"},
+	{"ELASTICSEARCH_BREAKER_THRESHOLD", 5, "consecutive failed attempts that open the circuit breaker, 0 disables it"},
+	{"ELASTICSEARCH_BREAKER_COOLDOWN", 30 * time.Second, "how long the circuit breaker stays open"},
+
+	{"ELASTICSEARCH_CA_CERT", "", "path to the PEM encoded CA certificate of the cluster"},
+	{"ELASTICSEARCH_CERT_FINGERPRINT", "", "SHA-256 fingerprint of the cluster certificate to pin"},
+	{"ELASTICSEARCH_CLIENT_CERT", "", "path to the PEM encoded client certificate"},
+	{"ELASTICSEARCH_CLIENT_KEY", "", "path to the PEM encoded client private key"},
+
+	{"BOOKS_INDEX", "books", "name of the books index"},
+	{"BOOKS_ALIAS", "", "alias used to read and write books, defaults to BOOKS_INDEX"},
+
+	{"DEFAULT_PAGE_SIZE", 10, "number of hits returned when size is not given"},
+	{"MAX_PAGE_SIZE", 100, "maximum number of hits a client can request"},
+
+	{"CORS_ALLOWED_ORIGINS", []string{}, "comma separated origins allowed to call the API, * for any"},
+	{"CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}, "comma separated methods allowed for cross-origin requests"},
+	{"CORS_ALLOWED_HEADERS", []string{"Authorization", "Content-Type", "X-Request-ID"}, "comma separated headers allowed for cross-origin requests"},
+	{"CORS_ALLOW_CREDENTIALS", false, "allow cross-origin requests with credentials"},
+	{"CORS_MAX_AGE", 12 * time.Hour, "how long browsers cache preflight responses"},
+
+	{"COMPRESSION_ENABLED", true, "compress responses with brotli or gzip"},
+	{"COMPRESSION_MIN_SIZE", 1024, "minimum size in bytes of a compressed response"},
+	{"SECURITY_HEADERS_ENABLED", true, "send X-Content-Type-Options, X-Frame-Options and similar headers"},
+	{"HSTS_MAX_AGE", time.Duration(0), "max-age of the Strict-Transport-Security header, 0 disables it"},
+
+	{"LEGACY_ROUTES_ENABLED", true, "serve the routes without the /api/v1 prefix as deprecated aliases"},
+	{"LEGACY_ROUTES_SUNSET", "2027-04-30", "date as YYYY-MM-DD after which the legacy routes are removed"},
+
+	{"AUTH_ENABLED", false, "require authentication on API routes"},
+	{"AUTH_API_KEYS", []string{}, "comma separated API keys as id:sha256-hex[:role[:publisher]]"},
+	{"AUTH_JWT_HS256_SECRET", "", "shared secret for HS256 signed JWTs"},
+	{"AUTH_JWKS_FILE", "", "path to a JWKS file with the RS256 public keys"},
+	{"AUTH_JWT_ISSUER", "", "expected iss claim of JWTs"},
+	{"AUTH_JWT_AUDIENCE", "", "expected aud claim of JWTs"},
+
+	{"RATE_LIMIT_ENABLED", false, "limit the request rate of every API key or client IP"},
+	{"RATE_LIMIT_RPS", 10.0, "requests per second allowed per client and route"},
+	{"RATE_LIMIT_BURST", 20, "requests a client can make at once before being limited"},
+	{"RATE_LIMIT_ROUTES", []string{}, "comma separated per route limits as METHOD /route=rps:burst"},
+	{"RATE_LIMIT_IP_RPS", 100.0, "requests per second allowed per client IP before authentication, for all routes"},
+	{"RATE_LIMIT_IP_BURST", 200, "requests a client IP can make at once before authentication"},
+
+	{"QUERY_ALLOW_LEADING_WILDCARD", false, "allow query_str terms starting with * or ?"},
+	{"FILTER_MAX_CATEGORIES", 20, "maximum number of categories in a filter"},
+
+	{"CACHE_ENABLED", false, "cache search responses"},
+	{"CACHE_TTL", 30 * time.Second, "how long search responses are cached, also sent as Cache-Control max-age"},
+	{"CACHE_MAX_ENTRIES", 1000, "maximum number of responses in the in-process cache"},
+	{"CACHE_REDIS_URL", "", "redis:// URL of a shared cache used instead of the in-process one"},
+
+	{"SNAPSHOT_REPOSITORY", "books_backup", "name of the snapshot repository"},
+	{"SNAPSHOT_REPOSITORY_PATH", "/usr/share/elasticsearch/snapshots", "location of the snapshot repository on the nodes, listed in path.repo"},
+	{"SNAPSHOT_INTERVAL", 24 * time.Hour, "time between two snapshots taken by snapshot schedule"},
+	{"SNAPSHOT_RETENTION_COUNT", 7, "number of most recent snapshots kept when pruning, 0 keeps all"},
+	{"SNAPSHOT_RETENTION_MAX_AGE", 30 * 24 * time.Hour, "age after which snapshots are pruned, 0 keeps all"},
+	{"EVENTS_ALIAS", "book-events", "write alias of the events indices"},
+	{"EVENTS_ROLLOVER_MAX_AGE", 24 * time.Hour, "age at which the events write index rolls over"},
+	{"EVENTS_ROLLOVER_MAX_SIZE", "10gb", "primary shard size at which the events write index rolls over, empty for no limit"},
+	{"EVENTS_WARM_AFTER", 7 * 24 * time.Hour, "time after rollover when events indices are shrunk and force merged, 0 skips the warm phase"},
+	{"EVENTS_SHRINK_SHARDS", 1, "primary shards of warm events indices, 0 does not shrink"},
+	{"EVENTS_FORCE_MERGE_SEGMENTS", 1, "segments per shard of warm events indices, 0 does not force merge"},
+	{"EVENTS_DELETE_AFTER", 90 * 24 * time.Hour, "time after rollover when events indices are deleted, 0 keeps them"},
+	{"ANALYTICS_ENABLED", false, "record searches to the events indices"},
+	{"ANALYTICS_BUFFER_SIZE", 10000, "events waiting to be written before new ones are dropped"},
+	{"ANALYTICS_FLUSH_INTERVAL", 5 * time.Second, "longest time an event waits to be written"},
+	{"POPULARITY_WINDOW", 30 * 24 * time.Hour, "time window of the clicks counted in the popularity of books"},
+	{"POPULARITY_UPDATER_ENABLED", true, "update the popularity of books in the server when analytics are enabled, false on all replicas but one"},
+	{"POPULARITY_UPDATE_INTERVAL", time.Hour, "time between updates of the popularity of books"},
+	{"RELEVANCE_FILE", "", "path to a JSON file with the field boosts, phrase boosts, stopwords and synonyms of full text search"},
+	{"RANKING_TEXT_WEIGHT", 1.0, "weight of text relevance against the other signals in full text search"},
+	{"RANKING_POPULARITY_WEIGHT", 1.0, "weight of the log-scaled popularity in full text search, 0 ignores it"},
+	{"RANKING_RATING_WEIGHT", 0.5, "weight of the log-scaled rating in full text search, 0 ignores it"},
+	{"RANKING_REVIEW_COUNT_WEIGHT", 0.5, "weight of the log-scaled review count in full text search, 0 ignores it"},
+	{"SIMILAR_AUTHOR_WEIGHT", 1.0, "score added to similar books by the same author, 0 ignores the author"},
+	{"SIMILAR_PUBLISHER_WEIGHT", 0.5, "score added to similar books by the same publisher, 0 ignores the publisher"},
+	{"EMBEDDER", "local", "embedder of semantic search: local or http"},
+	{"EMBEDDER_URL", "", "URL of the OpenAI compatible embeddings endpoint of the http embedder"},
+	{"EMBEDDER_MODEL", "", "model asked of the http embedder"},
+	{"EMBEDDER_API_KEY", "", "bearer token of the http embedder"},
+	{"EMBEDDER_TIMEOUT", 10 * time.Second, "timeout of a request to the http embedder"},
+
+	{"LOG_LEVEL", "info", "log level: debug, info, warn or error"},
+	{"LOG_FORMAT", "json", "log format: json or text"},
+	{"SLOW_QUERY_THRESHOLD", 500 * time.Millisecond, "log Elasticsearch calls taking at least this long, 0 disables it"},
+	{"TRACING_ENABLED", false, "export traces over OTLP"},
+	{"TRACING_SAMPLE_RATIO", 1.0, "ratio of sampled root spans"},
+	{"READINESS_CACHE_TTL", 5 * time.Second, "how long a readiness check result is reused"},
 }
 
-// LoadConfig reads configuration from file or environment variables.
+// RegisterFlags adds a flag for every configuration key to fs, e.g. --http-server-address
+// for HTTP_SERVER_ADDRESS. Flags that are set override all other sources.
+func RegisterFlags(fs *pflag.FlagSet) {
+	for _, s := range settings {
+		fs.String(flagName(s.key), "", s.usage)
+	}
+}
+
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
+
+// DefaultConfig returns the configuration made of default values only.
+func DefaultConfig() Config {
+	v := viper.New()
+	for _, s := range settings {
+		v.SetDefault(s.key, s.value)
+	}
+
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		panic(fmt.Sprintf("invalid default configuration: %s", err))
+	}
+	config.ElasticsearchAddresses = []string{config.ElasticsearchServerAddress}
+	return config
+}
+
+// LoadConfig reads configuration from defaults, an optional file and environment variables.
 func LoadConfig(path string) (config Config, err error) {
-	viper.AddConfigPath(path)
-	viper.SetConfigName("config")
-	viper.SetConfigType("env")
+	return LoadConfigWithFlags(path, nil)
+}
+
+// LoadConfigWithFlags reads configuration in layers, each one overriding the previous:
+// defaults, the optional config.env file in path, environment variables and flags
+// registered with RegisterFlags. The result is validated.
+func LoadConfigWithFlags(path string, flags *pflag.FlagSet) (config Config, err error) {
+	v := viper.New()
+	for _, s := range settings {
+		v.SetDefault(s.key, s.value)
+	}
+
+	v.AddConfigPath(path)
+	v.SetConfigName("config")
+	v.SetConfigType("env")
+	if err = v.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return config, fmt.Errorf("cannot read config file: %w", err)
+		}
+	}
+
+	v.AutomaticEnv()
+
+	if flags != nil {
+		for _, s := range settings {
+			if f := flags.Lookup(flagName(s.key)); f != nil && f.Changed {
+				v.Set(s.key, f.Value.String())
+			}
+		}
+	}
+
+	if err = v.Unmarshal(&config); err != nil {
+		return config, fmt.Errorf("cannot decode config: %w", err)
+	}
+
+	if len(config.ElasticsearchAddresses) == 0 && config.ElasticsearchServerAddress != "" && config.ElasticsearchCloudID == "" {
+		config.ElasticsearchAddresses = []string{config.ElasticsearchServerAddress}
+	}
+
+	if err = config.Validate(); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// BooksTarget returns the index or alias books are read from and written to.
+func (c Config) BooksTarget() string {
+	if c.BooksAlias != "" {
+		return c.BooksAlias
+	}
+	return c.BooksIndex
+}
+
+// LegacyRoutesSunsetDate returns the day the legacy routes are removed, at midnight UTC.
+func (c Config) LegacyRoutesSunsetDate() (time.Time, error) {
+	return time.Parse(time.DateOnly, c.LegacyRoutesSunset)
+}
+
+// Validate checks the configuration and reports every invalid setting at once.
+func (c Config) Validate() error {
+	var errs []error
+	invalid := func(key string, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if _, _, err := net.SplitHostPort(c.HTTPServerAddress); err != nil {
+		invalid("HTTP_SERVER_ADDRESS", "must be host:port, got %q", c.HTTPServerAddress)
+	}
+	for _, proxy := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			invalid("TRUSTED_PROXIES", "%q is not an IP address or CIDR range", proxy)
+		}
+	}
+	if c.ShutdownTimeout <= 0 {
+		invalid("SHUTDOWN_TIMEOUT", "must be positive, got %s", c.ShutdownTimeout)
+	}
+
+	switch {
+	case c.ElasticsearchCloudID != "" && len(c.ElasticsearchAddresses) > 0:
+		invalid("ELASTICSEARCH_CLOUD_ID", "cannot be combined with ELASTICSEARCH_ADDRESSES")
+	case c.ElasticsearchCloudID == "" && len(c.ElasticsearchAddresses) == 0:
+		invalid("ELASTICSEARCH_ADDRESSES", "at least one address or ELASTICSEARCH_CLOUD_ID is required")
+	}
+	for _, address := range c.ElasticsearchAddresses {
+		u, err := url.Parse(address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid("ELASTICSEARCH_ADDRESSES", "%q is not an http or https URL", address)
+		}
+	}
+	if c.ElasticsearchAPIKey != "" && c.ElasticsearchUsername != "" {
+		invalid("ELASTICSEARCH_API_KEY", "cannot be combined with ELASTICSEARCH_USERNAME")
+	}
+	if c.ElasticsearchPassword != "" && c.ElasticsearchUsername == "" {
+		invalid("ELASTICSEARCH_PASSWORD", "requires ELASTICSEARCH_USERNAME")
+	}
+	for key, path := range map[string]string{
+		"ELASTICSEARCH_CA_CERT":     c.ElasticsearchCACert,
+		"ELASTICSEARCH_CLIENT_CERT": c.ElasticsearchClientCert,
+		"ELASTICSEARCH_CLIENT_KEY":  c.ElasticsearchClientKey,
+	} {
+		if path != "" {
+			if _, err := os.Stat(path); err != nil {
+				invalid(key, "cannot read %q: %s", path, err)
+			}
+		}
+	}
+	if (c.ElasticsearchClientCert == "") != (c.ElasticsearchClientKey == "") {
+		invalid("ELASTICSEARCH_CLIENT_CERT", "ELASTICSEARCH_CLIENT_CERT and ELASTICSEARCH_CLIENT_KEY must be set together")
+	}
+	if c.ElasticsearchCertFingerprint != "" {
+		fingerprint := strings.ReplaceAll(c.ElasticsearchCertFingerprint, ":", "")
+		if _, err := hex.DecodeString(fingerprint); err != nil || len(fingerprint) != 64 {
+			invalid("ELASTICSEARCH_CERT_FINGERPRINT", "must be a hex encoded SHA-256 digest, got %q", c.ElasticsearchCertFingerprint)
+		}
+	}
+	if c.usesTLS() {
+		for _, address := range c.ElasticsearchAddresses {
+			if !strings.HasPrefix(address, "https://") {
+				invalid("ELASTICSEARCH_ADDRESSES", "%q must use https when TLS settings are configured", address)
+			}
+		}
+	}
+	if c.ElasticsearchMaxRetries < 0 {
+		invalid("ELASTICSEARCH_MAX_RETRIES", "must not be negative, got %d", c.ElasticsearchMaxRetries)
+	}
+	if c.ElasticsearchRetryBackoff < 0 {
+		invalid("ELASTICSEARCH_RETRY_BACKOFF", "must not be negative, got %s", c.ElasticsearchRetryBackoff)
+	}
+	if c.ElasticsearchRequestTimeout < 0 {
+		invalid("ELASTICSEARCH_REQUEST_TIMEOUT", "must not be negative, got %s", c.ElasticsearchRequestTimeout)
+	}
+	if c.ElasticsearchRetryMaxBackoff < c.ElasticsearchRetryBackoff {
+		invalid("ELASTICSEARCH_RETRY_MAX_BACKOFF", "must not be less than ELASTICSEARCH_RETRY_BACKOFF (%s), got %s", c.ElasticsearchRetryBackoff, c.ElasticsearchRetryMaxBackoff)
+	}
+	if c.ElasticsearchCloudID != "" && (c.ElasticsearchDiscoverNodesOnStart || c.ElasticsearchDiscoverNodesInterval > 0) {
+		invalid("ELASTICSEARCH_DISCOVER_NODES_ON_START", "node discovery cannot be used with ELASTICSEARCH_CLOUD_ID")
+	}
+	if c.ElasticsearchDiscoverNodesInterval < 0 {
+		invalid("ELASTICSEARCH_DISCOVER_NODES_INTERVAL", "must not be negative, got %s", c.ElasticsearchDiscoverNodesInterval)
+	}
+	if c.ElasticsearchBreakerThreshold < 0 {
+		invalid("ELASTICSEARCH_BREAKER_THRESHOLD", "must not be negative, got %d", c.ElasticsearchBreakerThreshold)
+	}
+	if c.ElasticsearchBreakerThreshold > 0 && c.ElasticsearchBreakerCooldown <= 0 {
+		invalid("ELASTICSEARCH_BREAKER_COOLDOWN", "must be positive when the circuit breaker is enabled, got %s", c.ElasticsearchBreakerCooldown)
+	}
+
+	if !isValidIndexName(c.BooksIndex) {
+		invalid("BOOKS_INDEX", "%q is not a valid index name", c.BooksIndex)
+	}
+	if c.BooksAlias != "" {
+		if !isValidIndexName(c.BooksAlias) {
+			invalid("BOOKS_ALIAS", "%q is not a valid alias name", c.BooksAlias)
+		}
+		if c.BooksAlias == c.BooksIndex {
+			invalid("BOOKS_ALIAS", "must differ from BOOKS_INDEX")
+		}
+	}
+
+	// 10000 is the default index.max_result_window of Elasticsearch
+	if c.MaxPageSize < 1 || c.MaxPageSize > 10000 {
+		invalid("MAX_PAGE_SIZE", "must be between 1 and 10000, got %d", c.MaxPageSize)
+	}
+	if c.DefaultPageSize < 1 || c.DefaultPageSize > c.MaxPageSize {
+		invalid("DEFAULT_PAGE_SIZE", "must be between 1 and MAX_PAGE_SIZE (%d), got %d", c.MaxPageSize, c.DefaultPageSize)
+	}
+
+	for _, origin := range c.CORSAllowedOrigins {
+		if origin == "*" {
+			if c.CORSAllowCredentials {
+				invalid("CORS_ALLOW_CREDENTIALS", "cannot be used with the * origin")
+			}
+			continue
+		}
+		// As checked by the CORS middleware, which panics on the origins it rejects
+		if strings.Count(origin, "*") > 1 {
+			invalid("CORS_ALLOWED_ORIGINS", "%q has more than one *", origin)
+		}
+		if !strings.Contains(origin, "*") && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			invalid("CORS_ALLOWED_ORIGINS", "%q is not an http or https origin", origin)
+		}
+	}
+
+	if c.CompressionMinSize < 0 {
+		invalid("COMPRESSION_MIN_SIZE", "must not be negative, got %d", c.CompressionMinSize)
+	}
+	if c.HSTSMaxAge < 0 {
+		invalid("HSTS_MAX_AGE", "must not be negative, got %s", c.HSTSMaxAge)
+	}
+
+	if _, err := c.LegacyRoutesSunsetDate(); c.LegacyRoutesEnabled && err != nil {
+		invalid("LEGACY_ROUTES_SUNSET", "must be a date as YYYY-MM-DD, got %q", c.LegacyRoutesSunset)
+	}
 
-	viper.AutomaticEnv()
+	if c.AuthEnabled && len(c.AuthAPIKeys) == 0 && c.AuthJWTHS256Secret == "" && c.AuthJWKSFile == "" {
+		invalid("AUTH_ENABLED", "requires AUTH_API_KEYS, AUTH_JWT_HS256_SECRET or AUTH_JWKS_FILE")
+	}
+	if c.AuthJWKSFile != "" {
+		if _, err := os.Stat(c.AuthJWKSFile); err != nil {
+			invalid("AUTH_JWKS_FILE", "cannot read %q: %s", c.AuthJWKSFile, err)
+		}
+	}
+
+	if c.RateLimitRPS <= 0 {
+		invalid("RATE_LIMIT_RPS", "must be positive, got %v", c.RateLimitRPS)
+	}
+	if c.RateLimitBurst < 1 {
+		invalid("RATE_LIMIT_BURST", "must be positive, got %d", c.RateLimitBurst)
+	}
+	if c.RateLimitIPRPS <= 0 {
+		invalid("RATE_LIMIT_IP_RPS", "must be positive, got %v", c.RateLimitIPRPS)
+	}
+	if c.RateLimitIPBurst < 1 {
+		invalid("RATE_LIMIT_IP_BURST", "must be positive, got %d", c.RateLimitIPBurst)
+	}
+	if _, err := c.RouteRateLimits(); err != nil {
+		invalid("RATE_LIMIT_ROUTES", "%s", err)
+	}
+	if c.FilterMaxCategories < 1 {
+		invalid("FILTER_MAX_CATEGORIES", "must be positive, got %d", c.FilterMaxCategories)
+	}
 
-	err = viper.ReadInConfig()
-	if err != nil {
-		return
+	if c.CacheEnabled && c.CacheTTL <= 0 {
+		invalid("CACHE_TTL", "must be positive when the cache is enabled, got %s", c.CacheTTL)
+	}
+	if c.CacheEnabled && c.CacheRedisURL == "" && c.CacheMaxEntries < 1 {
+		invalid("CACHE_MAX_ENTRIES", "must be positive, got %d", c.CacheMaxEntries)
+	}
+	if c.CacheRedisURL != "" {
+		if u, err := url.Parse(c.CacheRedisURL); err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") {
+			invalid("CACHE_REDIS_URL", "must be a redis:// or rediss:// URL, got %q", c.CacheRedisURL)
+		}
 	}
 
-	err = viper.Unmarshal(&config)
-	return
+	if c.SnapshotRepository == "" || strings.ToLower(c.SnapshotRepository) != c.SnapshotRepository || strings.ContainsAny(c.SnapshotRepository, `\/*?"<>| ,#`) {
+		invalid("SNAPSHOT_REPOSITORY", "%q is not a valid repository name", c.SnapshotRepository)
+	}
+	if !strings.HasPrefix(c.SnapshotRepositoryPath, "/") {
+		invalid("SNAPSHOT_REPOSITORY_PATH", "must be an absolute path, got %q", c.SnapshotRepositoryPath)
+	}
+	if c.SnapshotInterval <= 0 {
+		invalid("SNAPSHOT_INTERVAL", "must be positive, got %s", c.SnapshotInterval)
+	}
+	if c.SnapshotRetentionCount < 0 {
+		invalid("SNAPSHOT_RETENTION_COUNT", "must not be negative, got %d", c.SnapshotRetentionCount)
+	}
+	if c.SnapshotRetentionMaxAge < 0 {
+		invalid("SNAPSHOT_RETENTION_MAX_AGE", "must not be negative, got %s", c.SnapshotRetentionMaxAge)
+	}
+
+	if c.EventsAlias == "" || strings.ToLower(c.EventsAlias) != c.EventsAlias || strings.ContainsAny(c.EventsAlias, `\/*?"<>| ,#`) {
+		invalid("EVENTS_ALIAS", "%q is not a valid alias name", c.EventsAlias)
+	}
+	if c.EventsRolloverMaxAge <= 0 {
+		invalid("EVENTS_ROLLOVER_MAX_AGE", "must be positive, got %s", c.EventsRolloverMaxAge)
+	}
+	if c.EventsRolloverMaxSize != "" && !byteSizePattern.MatchString(c.EventsRolloverMaxSize) {
+		invalid("EVENTS_ROLLOVER_MAX_SIZE", "must be a size such as 10gb, got %q", c.EventsRolloverMaxSize)
+	}
+	if c.EventsWarmAfter < 0 {
+		invalid("EVENTS_WARM_AFTER", "must not be negative, got %s", c.EventsWarmAfter)
+	}
+	if c.EventsShrinkShards < 0 {
+		invalid("EVENTS_SHRINK_SHARDS", "must not be negative, got %d", c.EventsShrinkShards)
+	}
+	if c.EventsForceMergeSegments < 0 {
+		invalid("EVENTS_FORCE_MERGE_SEGMENTS", "must not be negative, got %d", c.EventsForceMergeSegments)
+	}
+	if c.EventsDeleteAfter < 0 {
+		invalid("EVENTS_DELETE_AFTER", "must not be negative, got %s", c.EventsDeleteAfter)
+	}
+	if c.EventsDeleteAfter > 0 && c.EventsDeleteAfter <= c.EventsWarmAfter {
+		invalid("EVENTS_DELETE_AFTER", "must be after EVENTS_WARM_AFTER (%s), got %s", c.EventsWarmAfter, c.EventsDeleteAfter)
+	}
+
+	if c.AnalyticsEnabled && c.AnalyticsBufferSize < 1 {
+		invalid("ANALYTICS_BUFFER_SIZE", "must be positive when analytics are enabled, got %d", c.AnalyticsBufferSize)
+	}
+	if c.AnalyticsEnabled && c.AnalyticsFlushInterval <= 0 {
+		invalid("ANALYTICS_FLUSH_INTERVAL", "must be positive when analytics are enabled, got %s", c.AnalyticsFlushInterval)
+	}
+	if c.PopularityWindow <= 0 {
+		invalid("POPULARITY_WINDOW", "must be positive, got %s", c.PopularityWindow)
+	}
+	if c.AnalyticsEnabled && c.PopularityUpdaterEnabled && c.PopularityUpdateInterval <= 0 {
+		invalid("POPULARITY_UPDATE_INTERVAL", "must be positive when the popularity updater is enabled, got %s", c.PopularityUpdateInterval)
+	}
+
+	if c.RelevanceFile != "" {
+		if _, err := os.Stat(c.RelevanceFile); err != nil {
+			invalid("RELEVANCE_FILE", "cannot read %q: %s", c.RelevanceFile, err)
+		}
+	}
+	if c.RankingTextWeight <= 0 {
+		invalid("RANKING_TEXT_WEIGHT", "must be positive, got %g", c.RankingTextWeight)
+	}
+	if c.RankingPopularityWeight < 0 {
+		invalid("RANKING_POPULARITY_WEIGHT", "must not be negative, got %g", c.RankingPopularityWeight)
+	}
+	if c.RankingRatingWeight < 0 {
+		invalid("RANKING_RATING_WEIGHT", "must not be negative, got %g", c.RankingRatingWeight)
+	}
+	if c.RankingReviewCountWeight < 0 {
+		invalid("RANKING_REVIEW_COUNT_WEIGHT", "must not be negative, got %g", c.RankingReviewCountWeight)
+	}
+	if c.SimilarAuthorWeight < 0 {
+		invalid("SIMILAR_AUTHOR_WEIGHT", "must not be negative, got %g", c.SimilarAuthorWeight)
+	}
+	if c.SimilarPublisherWeight < 0 {
+		invalid("SIMILAR_PUBLISHER_WEIGHT", "must not be negative, got %g", c.SimilarPublisherWeight)
+	}
+	switch c.Embedder {
+	case "local":
+	case "http":
+		if u, err := url.Parse(c.EmbedderURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid("EMBEDDER_URL", "must be an http or https URL with the http embedder, got %q", c.EmbedderURL)
+		}
+		if c.EmbedderTimeout <= 0 {
+			invalid("EMBEDDER_TIMEOUT", "must be positive, got %s", c.EmbedderTimeout)
+		}
+	default:
+		invalid("EMBEDDER", "must be local or http, got %q", c.Embedder)
+	}
+
+	switch strings.ToLower(c.LogLevel) {
+	case "debug", "info", "warn", "error":
+	default:
+		invalid("LOG_LEVEL", "must be debug, info, warn or error, got %q", c.LogLevel)
+	}
+	switch strings.ToLower(c.LogFormat) {
+	case "json", "text":
+	default:
+		invalid("LOG_FORMAT", "must be json or text, got %q", c.LogFormat)
+	}
+	if c.SlowQueryThreshold < 0 {
+		invalid("SLOW_QUERY_THRESHOLD", "must not be negative, got %s", c.SlowQueryThreshold)
+	}
+	if c.TracingSampleRatio < 0 || c.TracingSampleRatio > 1 {
+		invalid("TRACING_SAMPLE_RATIO", "must be between 0 and 1, got %v", c.TracingSampleRatio)
+	}
+	if c.ReadinessCacheTTL < 0 {
+		invalid("READINESS_CACHE_TTL", "must not be negative, got %s", c.ReadinessCacheTTL)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// usesTLS reports whether any Elasticsearch TLS setting is configured.
+func (c Config) usesTLS() bool {
+	return c.ElasticsearchCACert != "" || c.ElasticsearchCertFingerprint != "" || c.ElasticsearchClientCert != ""
+}
+
+// isValidIndexName checks the main Elasticsearch index naming rules.
+func isValidIndexName(name string) bool {
+	if name == "" || name == "." || name == ".." || len(name) > 255 {
+		return false
+	}
+	if strings.ToLower(name) != name || strings.ContainsAny(name, `\/*?"<>| ,#:`) {
+		return false
+	}
+	return !strings.HasPrefix(name, "-") && !strings.HasPrefix(name, "_") && !strings.HasPrefix(name, "+")
 }
@@ -0,0 +1,261 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigWithoutFile(t *testing.T) {
+	t.Setenv("HTTP_SERVER_ADDRESS", "127.0.0.1:9000")
+	t.Setenv("ELASTICSEARCH_ADDRESSES", "http://es1:9200,http://es2:9200")
+
+	cfg, err := LoadConfig(t.TempDir())
+	require.NoError(t, err)
+	require.Equal(t, "127.0.0.1:9000", cfg.HTTPServerAddress)
+	require.Equal(t, []string{"http://es1:9200", "http://es2:9200"}, cfg.ElasticsearchAddresses)
+
+	// Unset keys keep their defaults
+	require.Equal(t, "books", cfg.BooksTarget())
+	require.Equal(t, 10, cfg.DefaultPageSize)
+	require.Equal(t, 500*time.Millisecond, cfg.SlowQueryThreshold)
+}
+
+func TestLoadConfigLayers(t *testing.T) {
+	dir := t.TempDir()
+	content := "LOG_LEVEL=debug\nMAX_PAGE_SIZE=50\nELASTICSEARCH_SERVER_ADDRESS=http://legacy:9200\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.env"), []byte(content), 0o600))
+
+	// Environment overrides the file
+	t.Setenv("MAX_PAGE_SIZE", "60")
+
+	// Flags override the environment
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	RegisterFlags(flags)
+	require.NoError(t, flags.Parse([]string{"--log-level=warn", "--books-alias=books-read"}))
+
+	cfg, err := LoadConfigWithFlags(dir, flags)
+	require.NoError(t, err)
+	require.Equal(t, "warn", cfg.LogLevel)
+	require.Equal(t, 60, cfg.MaxPageSize)
+	require.Equal(t, "books-read", cfg.BooksTarget())
+
+	// The legacy single address is used when no address list is set
+	require.Equal(t, []string{"http://legacy:9200"}, cfg.ElasticsearchAddresses)
+}
+
+func TestConfigValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr string
+	}{
+		{
+			name:   "Defaults",
+			modify: func(cfg *Config) {},
+		},
+		{
+			name:    "InvalidAddress",
+			modify:  func(cfg *Config) { cfg.ElasticsearchAddresses = []string{"es1:9200"} },
+			wantErr: `ELASTICSEARCH_ADDRESSES: "es1:9200" is not an http or https URL`,
+		},
+		{
+			name: "CloudIDWithAddresses",
+			modify: func(cfg *Config) {
+				cfg.ElasticsearchCloudID = "deployment:abc"
+			},
+			wantErr: "ELASTICSEARCH_CLOUD_ID: cannot be combined with ELASTICSEARCH_ADDRESSES",
+		},
+		{
+			name: "APIKeyWithUsername",
+			modify: func(cfg *Config) {
+				cfg.ElasticsearchAPIKey = "key"
+				cfg.ElasticsearchUsername = "elastic"
+			},
+			wantErr: "ELASTICSEARCH_API_KEY: cannot be combined with ELASTICSEARCH_USERNAME",
+		},
+		{
+			name:    "MissingCACert",
+			modify:  func(cfg *Config) { cfg.ElasticsearchCACert = "/does/not/exist.pem" },
+			wantErr: "ELASTICSEARCH_CA_CERT: cannot read",
+		},
+		{
+			name:    "ClientCertWithoutKey",
+			modify:  func(cfg *Config) { cfg.ElasticsearchClientCert = "config.go" },
+			wantErr: "ELASTICSEARCH_CLIENT_CERT and ELASTICSEARCH_CLIENT_KEY must be set together",
+		},
+		{
+			name:    "InvalidFingerprint",
+			modify:  func(cfg *Config) { cfg.ElasticsearchCertFingerprint = "AB:CD" },
+			wantErr: `ELASTICSEARCH_CERT_FINGERPRINT: must be a hex encoded SHA-256 digest, got "AB:CD"`,
+		},
+		{
+			name: "TLSWithHTTPAddress",
+			modify: func(cfg *Config) {
+				cfg.ElasticsearchCertFingerprint = strings.Repeat("ab", 32)
+			},
+			wantErr: `ELASTICSEARCH_ADDRESSES: "http://localhost:9200" must use https when TLS settings are configured`,
+		},
+		{
+			name:    "UppercaseIndex",
+			modify:  func(cfg *Config) { cfg.BooksIndex = "Books" },
+			wantErr: `BOOKS_INDEX: "Books" is not a valid index name`,
+		},
+		{
+			name:    "DefaultPageSizeAboveMax",
+			modify:  func(cfg *Config) { cfg.DefaultPageSize = 500 },
+			wantErr: "DEFAULT_PAGE_SIZE: must be between 1 and MAX_PAGE_SIZE (100), got 500",
+		},
+		{
+			name: "CORSWildcardWithCredentials",
+			modify: func(cfg *Config) {
+				cfg.CORSAllowedOrigins = []string{"*"}
+				cfg.CORSAllowCredentials = true
+			},
+			wantErr: "CORS_ALLOW_CREDENTIALS: cannot be used with the * origin",
+		},
+		{
+			name:    "InvalidLegacyRoutesSunset",
+			modify:  func(cfg *Config) { cfg.LegacyRoutesSunset = "30/04/2027" },
+			wantErr: `LEGACY_ROUTES_SUNSET: must be a date as YYYY-MM-DD, got "30/04/2027"`,
+		},
+		{
+			name:    "AuthWithoutCredentials",
+			modify:  func(cfg *Config) { cfg.AuthEnabled = true },
+			wantErr: "AUTH_ENABLED: requires AUTH_API_KEYS, AUTH_JWT_HS256_SECRET or AUTH_JWKS_FILE",
+		},
+		{
+			name:    "InvalidRateLimitRoute",
+			modify:  func(cfg *Config) { cfg.RateLimitRoutes = []string{"/api/v1/books/full_text_search=5"} },
+			wantErr: `RATE_LIMIT_ROUTES: entry "/api/v1/books/full_text_search=5" must be METHOD /route=rps:burst`,
+		},
+		{
+			name:    "ZeroIPRateLimit",
+			modify:  func(cfg *Config) { cfg.RateLimitIPRPS = 0 },
+			wantErr: "RATE_LIMIT_IP_RPS: must be positive, got 0",
+		},
+		{
+			name:    "RelativeSnapshotRepositoryPath",
+			modify:  func(cfg *Config) { cfg.SnapshotRepositoryPath = "snapshots" },
+			wantErr: `SNAPSHOT_REPOSITORY_PATH: must be an absolute path, got "snapshots"`,
+		},
+		{
+			name:    "InvalidEventsRolloverMaxSize",
+			modify:  func(cfg *Config) { cfg.EventsRolloverMaxSize = "10 GB" },
+			wantErr: `EVENTS_ROLLOVER_MAX_SIZE: must be a size such as 10gb, got "10 GB"`,
+		},
+		{
+			name:    "EventsDeletedBeforeWarm",
+			modify:  func(cfg *Config) { cfg.EventsDeleteAfter = 24 * time.Hour },
+			wantErr: `EVENTS_DELETE_AFTER: must be after EVENTS_WARM_AFTER (168h0m0s), got 24h0m0s`,
+		},
+		{
+			name: "AnalyticsWithoutBuffer",
+			modify: func(cfg *Config) {
+				cfg.AnalyticsEnabled = true
+				cfg.AnalyticsBufferSize = 0
+			},
+			wantErr: `ANALYTICS_BUFFER_SIZE: must be positive when analytics are enabled, got 0`,
+		},
+		{
+			name:    "MissingRelevanceFile",
+			modify:  func(cfg *Config) { cfg.RelevanceFile = "/does/not/exist.json" },
+			wantErr: `RELEVANCE_FILE: cannot read "/does/not/exist.json"`,
+		},
+		{
+			name:    "NegativeRankingWeight",
+			modify:  func(cfg *Config) { cfg.RankingRatingWeight = -1 },
+			wantErr: "RANKING_RATING_WEIGHT: must not be negative, got -1",
+		},
+		{
+			name:    "CORSOriginWithoutScheme",
+			modify:  func(cfg *Config) { cfg.CORSAllowedOrigins = []string{"https://app.example.com", "example.com"} },
+			wantErr: `CORS_ALLOWED_ORIGINS: "example.com" is not an http or https origin`,
+		},
+		{
+			name:    "CORSOriginWithTwoWildcards",
+			modify:  func(cfg *Config) { cfg.CORSAllowedOrigins = []string{"https://*.*.example.com"} },
+			wantErr: `CORS_ALLOWED_ORIGINS: "https://*.*.example.com" has more than one *`,
+		},
+		{
+			name: "PopularityUpdaterDisabled",
+			modify: func(cfg *Config) {
+				cfg.AnalyticsEnabled = true
+				cfg.PopularityUpdaterEnabled = false
+				cfg.PopularityUpdateInterval = 0
+			},
+		},
+		{
+			name:    "InvalidTrustedProxy",
+			modify:  func(cfg *Config) { cfg.TrustedProxies = []string{"10.0.0.0/8", "proxy.internal"} },
+			wantErr: `TRUSTED_PROXIES: "proxy.internal" is not an IP address or CIDR range`,
+		},
+		{
+			name:    "ZeroShutdownTimeout",
+			modify:  func(cfg *Config) { cfg.ShutdownTimeout = 0 },
+			wantErr: "SHUTDOWN_TIMEOUT: must be positive, got 0s",
+		},
+		{
+			name:    "NegativeSimilarWeight",
+			modify:  func(cfg *Config) { cfg.SimilarPublisherWeight = -0.5 },
+			wantErr: "SIMILAR_PUBLISHER_WEIGHT: must not be negative, got -0.5",
+		},
+		{
+			name:    "HTTPEmbedderWithoutURL",
+			modify:  func(cfg *Config) { cfg.Embedder = "http" },
+			wantErr: `EMBEDDER_URL: must be an http or https URL with the http embedder, got ""`,
+		},
+		{
+			name:    "InvalidLogLevel",
+			modify:  func(cfg *Config) { cfg.LogLevel = "verbose" },
+			wantErr: `LOG_LEVEL: must be debug, info, warn or error, got "verbose"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tc.modify(&cfg)
+
+			err := cfg.Validate()
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestConfigValidateReportsAllErrors(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.HTTPServerAddress = "8000"
+	cfg.MaxPageSize = 0
+	cfg.LogFormat = "xml"
+
+	err := cfg.Validate()
+	require.ErrorContains(t, err, "HTTP_SERVER_ADDRESS")
+	require.ErrorContains(t, err, "MAX_PAGE_SIZE")
+	require.ErrorContains(t, err, "LOG_FORMAT")
+}
+
+func TestRouteRateLimits(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.RateLimitRoutes = []string{"get /api/v1/books/full_text_search=0.5:2", "POST /api/v1/books/filter=5:10"}
+
+	limits, err := cfg.RouteRateLimits()
+	require.NoError(t, err)
+	require.Equal(t, map[string]RateLimit{
+		"GET /api/v1/books/full_text_search": {RPS: 0.5, Burst: 2},
+		"POST /api/v1/books/filter":          {RPS: 5, Burst: 10},
+	}, limits)
+
+	cfg.RateLimitRoutes = []string{"PUT /api/v1/books/:id=5:0"}
+	_, err = cfg.RouteRateLimits()
+	require.ErrorContains(t, err, "burst must be a positive integer")
+}
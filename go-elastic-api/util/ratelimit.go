@@ -0,0 +1,39 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RateLimit is a token bucket: RPS tokens are added per second, up to Burst.
+type RateLimit struct {
+	RPS   float64
+	Burst int
+}
+
+// RouteRateLimits parses RATE_LIMIT_ROUTES entries of the form "METHOD /route=rps:burst",
+// e.g. "GET /api/v1/books/full_text_search=5:10". Routes are gin route templates of the versioned API
+// such as "/api/v1/books/:id", which legacy aliases share the bucket of. The result is keyed by "METHOD /route".
+func (c Config) RouteRateLimits() (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit, len(c.RateLimitRoutes))
+	for _, entry := range c.RateLimitRoutes {
+		route, spec, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		method, path, okRoute := strings.Cut(route, " ")
+		rps, burst, okSpec := strings.Cut(spec, ":")
+		if !ok || !okRoute || !okSpec || method == "" || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("entry %q must be METHOD /route=rps:burst", entry)
+		}
+
+		limit := RateLimit{}
+		var err error
+		if limit.RPS, err = strconv.ParseFloat(rps, 64); err != nil || limit.RPS <= 0 {
+			return nil, fmt.Errorf("entry %q: rps must be a positive number", entry)
+		}
+		if limit.Burst, err = strconv.Atoi(burst); err != nil || limit.Burst < 1 {
+			return nil, fmt.Errorf("entry %q: burst must be a positive integer", entry)
+		}
+		limits[strings.ToUpper(method)+" "+path] = limit
+	}
+	return limits, nil
+}
@@ -0,0 +1,49 @@
+package util
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Build information, overridden at link time with
+// -ldflags "-X go-elastic-api/util.Version=... -X go-elastic-api/util.Commit=... -X go-elastic-api/util.BuildDate=...".
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+// GetBuildInfo returns the build information of the running binary.
+// When the commit or build date were not set at link time, it falls back to
+// the VCS stamp embedded by the Go toolchain.
+func GetBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = s.Value
+				}
+			}
+		}
+	}
+	return info
+}
@@ -17,3 +17,9 @@ func IsDateValid(after string) bool {
 	_, err := time.Parse("2006-01-02", after)
 	return err == nil
 }
+
+// IsLanguageValid checks if language is a lowercase ISO 639-1 code, two letters like "en" or "vi".
+func IsLanguageValid(language string) bool {
+	match, _ := regexp.MatchString(`^[a-z]{2}$`, language)
+	return match
+}